@@ -8,6 +8,8 @@
 package backend
 
 import (
+	"github.com/cceckman/discoirc/backend/ignore"
+	"github.com/cceckman/discoirc/backend/outbox"
 	"github.com/cceckman/discoirc/data"
 )
 
@@ -32,13 +34,90 @@ type EventsArchive interface {
 }
 
 // Sender sends a message on the given network to the given target (channel or user).
+// A message of several lines separates them with '\n'. It's sent as one
+// draft/multiline message if the server supports it, or as separate lines,
+// at a limited rate, if it doesn't; see package multiline.
 type Sender interface {
+	// Send sends a line the user entered. A line starting with '/' is a
+	// command the view doesn't run itself, e.g. "/me waves", and one
+	// starting with "//" is a message starting with '/'.
 	Send(s data.Scope, message string)
 }
 
+// Outbox holds the messages sent while their network is disconnected. They're
+// sent, in order, once the network reconnects and the channel is rejoined.
+type Outbox interface {
+	// Pending returns the messages waiting to be sent to the scope, oldest
+	// first.
+	Pending(s data.Scope) []outbox.Message
+	// Cancel drops the waiting message with the ID, and reports whether it
+	// was waiting.
+	Cancel(s data.Scope, id int64) bool
+}
+
+// Notifications controls the notifications of highlights and private messages
+// in channels the user isn't looking at.
+type Notifications interface {
+	// SetDoNotDisturb turns notifications off, or back on.
+	SetDoNotDisturb(on bool)
+	// DoNotDisturb reports whether notifications are off.
+	DoNotDisturb() bool
+}
+
+// Searcher searches the messages in every scope.
+type Searcher interface {
+	// Search returns up to limit messages matching the Filter, newest
+	// first. A limit of 0 returns all of them. Unlike EventsBefore, it
+	// doesn't mark them read.
+	Search(f data.Filter, limit int) data.EventList
+	// MessagesAfter returns the messages in the scope after the Seq that
+	// match the Filter, oldest first. It doesn't mark them read either.
+	MessagesAfter(s data.Scope, after data.Seq, f data.Filter) data.EventList
+}
+
+// Ignorer keeps the rules for ignoring users added while discoirc runs, as well
+// as those from the configuration. Events from ignored users are still kept,
+// but flagged as ignored (see data.Ignored), from when the rule is added until
+// it expires.
+type Ignorer interface {
+	// Ignore adds the rule, replacing any for the same target in the same
+	// scope.
+	Ignore(r *ignore.Rule)
+	// Unignore removes the rule added for the target in the scope, and
+	// reports whether there was one.
+	Unignore(s data.Scope, target string) bool
+	// Ignores returns the rules that apply in the scope.
+	Ignores(s data.Scope) []*ignore.Rule
+}
+
+// Saver saves the backend's current configuration, i.e. the networks and
+// channels it is connected to, to the configuration file.
+type Saver interface {
+	Save() error
+}
+
+// Directory lists the networks and channels the backend knows about.
+type Directory interface {
+	// Networks returns the names of the known networks, sorted.
+	Networks() []string
+	// Channels returns the names of the known channels on the network,
+	// sorted.
+	Channels(network string) []string
+	// Members returns the nicks of the channel's members, most recently
+	// active first.
+	Members(s data.Scope) []string
+}
+
 // Backend supports the full set of backend functionality.
 type Backend interface {
 	DataPublisher
 	EventsArchive
 	Sender
+	Outbox
+	Notifications
+	Searcher
+	Ignorer
+	Manager
+	Saver
+	Directory
 }
@@ -4,24 +4,22 @@ package demo
 import (
 	"fmt"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/cceckman/discoirc/backend"
+	"github.com/cceckman/discoirc/backend/highlight"
+	"github.com/cceckman/discoirc/backend/ignore"
+	"github.com/cceckman/discoirc/backend/index"
+	"github.com/cceckman/discoirc/backend/notify"
+	"github.com/cceckman/discoirc/backend/outbox"
+	"github.com/cceckman/discoirc/config"
 	"github.com/cceckman/discoirc/data"
 )
 
 var _ backend.Backend = &Demo{}
 
-type message struct {
-	data.EventID
-	message string
-}
-
-var _ data.Event = &message{}
-
-func (m *message) String() string    { return m.message }
-func (m *message) ID() *data.EventID { return &m.EventID }
-
 // Demo provides data and updates to discoirc UI components.
 type Demo struct {
 	sync.RWMutex
@@ -31,6 +29,24 @@ type Demo struct {
 	nets     map[data.Scope]*data.NetworkState
 	chans    map[data.Scope]*data.ChannelState
 	contents map[data.Scope]data.EventList
+	// members are the nicks that have spoken in each channel, most recent
+	// first.
+	members map[data.Scope][]string
+	configs map[string]*config.Network
+	// order is the order in which networks were configured.
+	order      []string
+	configPath string
+	// outbox holds messages sent while their network is disconnected.
+	outbox *outbox.Store
+	// highlights decides which messages are highlighted.
+	highlights *highlight.Rules
+	notifier   *notify.Notifier
+	// ignores decides which users' messages are ignored.
+	ignores *ignore.List
+	// index is the full-text index of every channel's messages.
+	index *index.Index
+	// conns are the connections to each network's server.
+	conns map[string]*conn
 
 	seq int64
 }
@@ -41,41 +57,91 @@ func New() *Demo {
 		nets:     make(map[data.Scope]*data.NetworkState),
 		chans:    make(map[data.Scope]*data.ChannelState),
 		contents: make(map[data.Scope]data.EventList),
+		members:  make(map[data.Scope][]string),
+		configs:  make(map[string]*config.Network),
+		outbox:   outbox.New(),
+		notifier: notify.New(config.Notify{}, notify.Exec),
+		ignores:  &ignore.List{},
+		index:    index.New(),
+		conns:    make(map[string]*conn),
 	}
 	return d
 }
 
-// Send sends the given message to the target.
+// Send sends the given message to the target, through the connection's send
+// queue. If the network isn't connected, or earlier messages are still
+// waiting, the message waits in the outbox.
 func (d *Demo) Send(scope data.Scope, message string) {
+	if _, _, err := parseLine(message); err != nil {
+		go d.ReportError(err)
+		return
+	}
 	d.ensureChannel(scope)
 
 	d.Lock()
 	defer d.Unlock()
-	netScope := data.Scope{Net: scope.Net}
+	net := d.nets[data.Scope{Net: scope.Net}]
 
-	nick := d.nets[netScope].Nick
-	d.appendMessage(scope, nick, message)
+	if net.State != data.Connected || len(d.outbox.Pending(scope)) > 0 {
+		if _, err := d.outbox.Add(scope, message); err != nil {
+			go d.ReportError(fmt.Errorf("outbox not saved: %v", err))
+		}
+		d.chans[scope].Pending = len(d.outbox.Pending(scope))
+		go d.updateAll()
+		return
+	}
+	d.sendLine(scope, message)
+}
+
+// parseLine returns the command and text of a line the user sent: "/me
+// waves" is an action, "//etc" the message "/etc", and other commands aren't
+// known.
+func parseLine(line string) (data.Command, string, error) {
+	switch {
+	case strings.HasPrefix(line, "//"):
+		return data.Privmsg, line[1:], nil
+	case !strings.HasPrefix(line, "/"):
+		return data.Privmsg, line, nil
+	}
+	name, text := line[1:], ""
+	if i := strings.IndexAny(name, " \t"); i >= 0 {
+		name, text = name[:i], strings.TrimSpace(name[i:])
+	}
+	if strings.ToLower(name) != "me" {
+		return data.Privmsg, "", fmt.Errorf("unknown command /%s", name)
+	}
+	return data.Action, text, nil
 }
 
 // appendMessage must be called under the write lock.
-func (d *Demo) appendMessage(scope data.Scope, speaker, contents string) {
+func (d *Demo) appendMessage(scope data.Scope, speaker string, command data.Command, contents string) *data.MessageEvent {
 	last := d.chans[scope].LastMessage
-	next := &message{
+	own := d.nets[data.Scope{Net: scope.Net}].Nick
+	now := time.Now()
+	next := &data.MessageEvent{
 		EventID: data.EventID{
 			Scope: scope,
 			Seq:   last + 1,
 		},
-		message: fmt.Sprintf(
-			"<%s> %s",
-			speaker, contents,
-		),
+		Nick:    speaker,
+		Command: command,
+		Text:    contents,
+		Time:    now,
+		Own:     speaker == own,
 	}
+	// Our own messages are never ignored, nor ignored messages highlighted.
+	next.Ignored = !next.Own && d.ignores.Match(scope, ignore.Source{Nick: speaker}, contents, now)
+	next.Highlight = !next.Ignored && d.highlights.Match(scope, own, speaker, contents)
 
 	// Doesn't update unread; 'send' doesn't count as unread.
 	d.contents[scope] = append(d.contents[scope], next)
+	d.index.Add(next)
 	d.chans[scope].LastMessage = next.ID().Seq
+	d.touch(scope, speaker)
+	d.notify(next, own)
 
 	go d.updateAll()
+	return next
 }
 
 // EventsBefore returns N events preceding the given event in the given channel.
@@ -117,6 +183,15 @@ func (d *Demo) EventsBefore(id data.Scope, n int, last data.Seq) data.EventList
 		if unread < ch.Unread {
 			ch.Unread = unread
 		}
+		highlights := 0
+		for _, e := range d.contents[id][len(d.contents[id])-unread:] {
+			if m, ok := e.(*data.MessageEvent); ok && m.Highlight {
+				highlights++
+			}
+		}
+		if highlights < ch.Highlights {
+			ch.Highlights = highlights
+		}
 		go d.updateAll()
 	}()
 	// TODO: Handle num-unread better in a non-demo backend.
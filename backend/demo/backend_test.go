@@ -1,11 +1,22 @@
 package demo_test
 
 import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cceckman/discoirc/backend"
 	"github.com/cceckman/discoirc/backend/demo"
+	"github.com/cceckman/discoirc/backend/ignore"
+	"github.com/cceckman/discoirc/backend/multiline"
+	"github.com/cceckman/discoirc/backend/notify"
 	"github.com/cceckman/discoirc/backend/testhelper"
+	"github.com/cceckman/discoirc/config"
 	"github.com/cceckman/discoirc/data"
 )
 
@@ -19,6 +30,24 @@ func delay(n int) int {
 	return n + 1
 }
 
+// waitMessages waits for the scope's Events to be the wanted ones, which the
+// demo's send queue may take a moment to send.
+func waitMessages(t *testing.T, b *demo.Demo, s data.Scope, want []string) {
+	t.Helper()
+	attempts := 4
+	var diff string
+	for i := 0; i <= attempts; i = delay(i) {
+		var got []string
+		for _, e := range b.EventsBefore(s, 10, 10) {
+			got = append(got, e.String())
+		}
+		if diff = cmp.Diff(got, want); diff == "" {
+			return
+		}
+	}
+	t.Errorf("unexpected messages: (-got +want)\n%s", diff)
+}
+
 func TestSubscribeFiltered(t *testing.T) {
 	t.Parallel()
 	b := demo.New()
@@ -229,6 +258,7 @@ func TestSend(t *testing.T) {
 
 	c.Archive = b
 	b.Subscribe(c)
+	b.Connect(eighteen.Net)
 
 	// Send a message via the UI interface; assume it's from an arbitrary thread
 	go b.Send(eighteen, "hello!")
@@ -251,3 +281,619 @@ func TestSend(t *testing.T) {
 		})
 	}
 }
+
+func TestSend_Commands(t *testing.T) {
+	t.Parallel()
+	b := demo.New()
+	b.SetNick(eighteen.Net, "will")
+	b.Connect(eighteen.Net)
+	b.Send(eighteen, "/me compares thee")
+	b.Send(eighteen, "/dance")
+	b.Send(eighteen, "//etc is where the sonnets are")
+
+	waitMessages(t, b, eighteen, []string{"* will compares thee", "<will> /etc is where the sonnets are"})
+}
+
+func TestSend_Multiline(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name   string
+		limits *multiline.Limits
+		want   []string
+	}{
+		{
+			name:   "batch",
+			limits: &multiline.Limits{MaxBytes: 4096},
+			want:   []string{"<will> Shall I compare thee\n\nto a summer's day?"},
+		},
+		{
+			name: "fallback",
+			want: []string{"<will> Shall I compare thee", "<will> to a summer's day?"},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			b := demo.New()
+			b.SetNick(eighteen.Net, "will")
+			b.SetMultiline(eighteen.Net, tt.limits)
+			b.Connect(eighteen.Net)
+			b.Send(eighteen, "Shall I compare thee\n\nto a summer's day?")
+
+			waitMessages(t, b, eighteen, tt.want)
+		})
+	}
+}
+
+func TestSend_Flood(t *testing.T) {
+	t.Parallel()
+	b := demo.New()
+	cfg := &config.Network{Name: eighteen.Net, Nick: "will", FloodBurst: 1}
+	b.Configure(cfg)
+	b.Connect(eighteen.Net)
+	b.Send(eighteen, "Shall I compare thee")
+	b.Send(eighteen, "to a summer's day?")
+
+	// The second line waits two seconds, at the default rate.
+	waitMessages(t, b, eighteen, []string{"<will> Shall I compare thee"})
+	time.Sleep(200 * time.Millisecond)
+	waitMessages(t, b, eighteen, []string{"<will> Shall I compare thee"})
+
+	// Reconfiguring the network changes the limits at once.
+	cfg.FloodRate = 100
+	b.Configure(cfg)
+	waitMessages(t, b, eighteen, []string{"<will> Shall I compare thee", "<will> to a summer's day?"})
+}
+
+func TestRemove(t *testing.T) {
+	t.Parallel()
+	attempts := 4
+	b := demo.New()
+	c := testhelper.NewClient()
+	b.Subscribe(c)
+	b.Connect(sonnet.Net)
+
+	for i, done := 0, false; !(done || i > attempts); i = delay(i) {
+		c.Join(func() {
+			done = c.Nets[sonnet].State == data.Connected
+			if !done && i == attempts {
+				t.Errorf("unexpected network state: got: %+v want: connected", c.Nets[sonnet])
+			}
+		})
+	}
+	c.Join(func() {
+		delete(c.Nets, sonnet)
+	})
+
+	// No update for the network may follow its removal.
+	b.Remove(sonnet.Net)
+	b.TickNetwork("sonata")
+	for i, done := 0, false; !(done || i > attempts); i = delay(i) {
+		c.Join(func() {
+			_, done = c.Nets[data.Scope{Net: "sonata"}]
+		})
+	}
+	c.Join(func() {
+		if got, ok := c.Nets[sonnet]; ok {
+			t.Errorf("unexpected update of removed network: %+v", got)
+		}
+	})
+}
+
+func TestRemoveChannel(t *testing.T) {
+	t.Parallel()
+	attempts := 4
+	b := demo.New()
+	c := testhelper.NewClient()
+	b.Subscribe(c)
+	b.Configure(&config.Network{
+		Name:     sonnet.Net,
+		Channels: []*config.Channel{{Name: eighteen.Name, AutoJoin: true}},
+	})
+	b.Connect(sonnet.Net)
+
+	for i, done := 0, false; !(done || i > attempts); i = delay(i) {
+		c.Join(func() {
+			done = c.Chans[eighteen].Presence == data.Joined
+			if !done && i == attempts {
+				t.Errorf("unexpected channel state: got: %+v want: joined", c.Chans[eighteen])
+			}
+		})
+	}
+	c.Join(func() {
+		delete(c.Chans, eighteen)
+	})
+
+	// No update for the channel may follow its removal.
+	b.RemoveChannel(eighteen)
+	b.SetNick(sonnet.Net, "shakespeare")
+	for i, done := 0, false; !(done || i > attempts); i = delay(i) {
+		c.Join(func() {
+			done = c.Nets[sonnet].Nick == "shakespeare"
+		})
+	}
+	c.Join(func() {
+		if got, ok := c.Chans[eighteen]; ok {
+			t.Errorf("unexpected update of removed channel: %+v", got)
+		}
+	})
+	if cfg := b.Configuration(sonnet.Net); cfg.Channel(eighteen.Name) != nil {
+		t.Errorf("unexpected configuration of removed channel: %+v", cfg.Channel(eighteen.Name))
+	}
+}
+
+func TestManage(t *testing.T) {
+	t.Parallel()
+	attempts := 4
+	b := demo.New()
+	c := testhelper.NewClient()
+	b.Subscribe(c)
+
+	cfg, err := config.Parse("test", `
+[network "sonnet"]
+server = irc.sonnet.example
+nick = will
+[channel "sonnet" "#eighteen"]
+[channel "sonnet" "#nineteen"]
+autojoin = false
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Apply(b, config.Diff(&config.Config{}, cfg))
+
+	for i, done := 0, false; !(done || i > attempts); i = delay(i) {
+		c.Join(func() {
+			net := c.Nets[sonnet]
+			ch := c.Chans[eighteen]
+			expect_connected := net.State == data.Connected && net.Nick == "will"
+			expect_joined := ch.Presence == data.Joined
+			expect_autojoin := len(c.Chans) == 1
+			done = expect_connected && expect_joined && expect_autojoin
+
+			if !expect_connected && i == attempts {
+				t.Errorf("unexpected network state: got: %+v want: connected as will", net)
+			}
+			if !expect_joined && i == attempts {
+				t.Errorf("unexpected channel state: got: %+v want: joined", ch)
+			}
+			if !expect_autojoin && i == attempts {
+				t.Errorf("unexpected channels: got: %v want: only %v", c.Chans, eighteen)
+			}
+		})
+	}
+
+	b.SetNick(sonnet.Net, "shakespeare")
+	b.Part(eighteen)
+
+	for i, done := 0, false; !(done || i > attempts); i = delay(i) {
+		c.Join(func() {
+			expect_nick := c.Nets[sonnet].Nick == "shakespeare"
+			expect_parted := c.Chans[eighteen].Presence == data.NotPresent
+			done = expect_nick && expect_parted
+
+			if !expect_nick && i == attempts {
+				t.Errorf("unexpected nick: got: %q want: %q", c.Nets[sonnet].Nick, "shakespeare")
+			}
+			if !expect_parted && i == attempts {
+				t.Errorf("unexpected channel state: got: %+v want: not present", c.Chans[eighteen])
+			}
+		})
+	}
+}
+
+func TestReportError(t *testing.T) {
+	t.Parallel()
+	attempts := 4
+	b := demo.New()
+	c := testhelper.NewClient()
+	ch := testhelper.NewChannel(eighteen.Net, eighteen.Name)
+
+	// Channel views don't see internal errors.
+	b.Subscribe(ch)
+	b.ReportError(errors.New("not for channels"))
+	b.Subscribe(c)
+	b.ReportError(errors.New("bad config"))
+
+	for i, done := 0, false; !(done || i > attempts); i = delay(i) {
+		c.Join(func() {
+			done = len(c.Errors) == 1 && c.Errors[0] == "bad config"
+			if !done && i == attempts {
+				t.Errorf("unexpected errors: got: %q want: %q", c.Errors, []string{"bad config"})
+			}
+		})
+	}
+	ch.Join(func() {
+		if len(ch.Errors) != 0 {
+			t.Errorf("unexpected errors for channel: got: %q want: none", ch.Errors)
+		}
+	})
+}
+
+func TestPersistJoins(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "discoirc-demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config")
+	original := `[network "sonnet"]
+server = irc.sonnet.example
+nick = will
+persist_joins = true
+`
+	if err := ioutil.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	b := demo.New()
+	b.SetConfigPath(path)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Apply(b, config.Diff(&config.Config{}, cfg))
+
+	b.Join(eighteen, "")
+	b.SetNick(sonnet.Net, "shakespeare")
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[network "sonnet"]
+server = irc.sonnet.example
+nick = shakespeare
+persist_joins = true
+
+[channel "sonnet" "#eighteen"]
+`
+	if diff := cmp.Diff(string(got), want); diff != "" {
+		t.Errorf("unexpected config: (-got +want)\n%s", diff)
+	}
+}
+
+func TestConnect_PasswordError(t *testing.T) {
+	t.Parallel()
+	attempts := 4
+	b := demo.New()
+	c := testhelper.NewClient()
+	b.Subscribe(c)
+
+	cfg, err := config.Parse("test", `
+[network "sonnet"]
+server = irc.sonnet.example
+nick = will
+password_command = echo no such password >&2; exit 1
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Apply(b, config.Diff(&config.Config{}, cfg))
+
+	want := `can't connect to sonnet: password command "echo no such password >&2; exit 1" failed: exit status 1: no such password`
+	for i, done := 0, false; !(done || i > attempts); i = delay(i) {
+		c.Join(func() {
+			done = len(c.Errors) == 1 && c.Errors[0] == want
+			if !done && i == attempts {
+				t.Errorf("unexpected errors: got: %q want: %q", c.Errors, []string{want})
+			}
+			if net := c.Nets[sonnet]; net.State == data.Connected {
+				t.Errorf("unexpected network state: got: %+v want: not connected", net)
+			}
+		})
+	}
+}
+
+func TestDirectory(t *testing.T) {
+	t.Parallel()
+	b := demo.New()
+	cfg, err := config.Parse("test", `
+[network "sonnet"]
+server = irc.sonnet.example
+nick = will
+[channel "sonnet" "#nineteen"]
+autojoin = false
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Apply(b, config.Diff(&config.Config{}, cfg))
+	b.Join(eighteen, "")
+
+	if diff := cmp.Diff(b.Networks(), []string{"sonnet"}); diff != "" {
+		t.Errorf("unexpected networks: (-got +want)\n%s", diff)
+	}
+	if diff := cmp.Diff(b.Channels("sonnet"), []string{"#eighteen", "#nineteen"}); diff != "" {
+		t.Errorf("unexpected channels: (-got +want)\n%s", diff)
+	}
+	if diff := cmp.Diff(b.Channels("sonata"), []string{}); diff != "" {
+		t.Errorf("unexpected channels: (-got +want)\n%s", diff)
+	}
+}
+
+func TestMembers(t *testing.T) {
+	t.Parallel()
+	b := demo.New()
+	b.TickMessages(eighteen.Net, eighteen.Name)
+	b.SetNick(eighteen.Net, "will")
+	b.Connect(eighteen.Net)
+	b.Send(eighteen, "Who will believe my verse in time to come")
+	waitMessages(t, b, eighteen, []string{
+		"<troilus> Shall I compare thee to a summer’s day?",
+		"<will> Who will believe my verse in time to come",
+	})
+	b.TickMessages(eighteen.Net, eighteen.Name)
+
+	if diff := cmp.Diff(b.Members(eighteen), []string{"troilus", "will"}); diff != "" {
+		t.Errorf("unexpected members: (-got +want)\n%s", diff)
+	}
+	if got := b.Members(data.Scope{Net: "sonnet", Name: "#twenty"}); len(got) != 0 {
+		t.Errorf("unexpected members of unknown channel: got: %q want: none", got)
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	t.Parallel()
+	attempts := 4
+	b := demo.New()
+	c := testhelper.NewChannel(eighteen.Net, eighteen.Name)
+	c.Archive = b
+	b.Subscribe(c)
+
+	b.Send(eighteen, "Who will believe my verse in time to come")
+	b.Send(eighteen, "If it were filled with your most high deserts?")
+	b.Send(eighteen, "Though yet heaven knows it is but as a tomb")
+
+	pending := b.Pending(eighteen)
+	if len(pending) != 3 {
+		t.Fatalf("unexpected pending messages: got: %v want: 3", pending)
+	}
+	if !b.Cancel(eighteen, pending[1].ID) {
+		t.Errorf("message %d not cancelled", pending[1].ID)
+	}
+	if b.Cancel(eighteen, pending[1].ID) {
+		t.Errorf("message %d cancelled twice", pending[1].ID)
+	}
+
+	for i, done := 0, false; !(done || i > attempts); i = delay(i) {
+		c.Join(func() {
+			got := c.Chans[eighteen].Pending
+			done = got == 2 && len(c.Contents[eighteen]) == 0
+			if !done && i == attempts {
+				t.Errorf("unexpected state while disconnected: got: %d pending, %v want: 2 pending, no messages", got, c.Contents[eighteen])
+			}
+		})
+	}
+
+	// Messages to a channel wait until it's joined.
+	b.Connect(eighteen.Net)
+	if got := len(b.Pending(eighteen)); got != 2 {
+		t.Errorf("unexpected pending messages before joining: got: %d want: 2", got)
+	}
+	b.Join(eighteen, "")
+
+	want := []string{
+		"<> Who will believe my verse in time to come",
+		"<> Though yet heaven knows it is but as a tomb",
+	}
+	for i, done := 0, false; !(done || i > attempts); i = delay(i) {
+		c.Join(func() {
+			var got []string
+			for _, e := range c.Contents[eighteen] {
+				got = append(got, e.String())
+			}
+			diff := cmp.Diff(got, want)
+			done = diff == "" && c.Chans[eighteen].Pending == 0
+			if !done && i == attempts {
+				t.Errorf("unexpected messages after joining: (-got +want)\n%s\npending: %d", diff, c.Chans[eighteen].Pending)
+			}
+		})
+	}
+	if got := b.Pending(eighteen); len(got) != 0 {
+		t.Errorf("unexpected pending messages after joining: got: %v want: none", got)
+	}
+}
+
+func TestHighlights(t *testing.T) {
+	t.Parallel()
+	attempts := 4
+	b := demo.New()
+	c := testhelper.NewClient()
+	b.Subscribe(c)
+
+	cfg, err := config.Parse("test", `
+[highlight "sonnet" "#eighteen"]
+word = summer
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Apply(b, config.Diff(&config.Config{}, cfg))
+
+	// "Shall I compare thee to a summer’s day?"
+	b.TickMessages(eighteen.Net, eighteen.Name)
+	// "Thou art more lovely and more temperate."
+	b.TickMessages(eighteen.Net, eighteen.Name)
+
+	for i, done := 0, false; !(done || i > attempts); i = delay(i) {
+		c.Join(func() {
+			ch := c.Chans[eighteen]
+			done = ch.Unread == 2 && ch.Highlights == 1
+			if !done && i == attempts {
+				t.Errorf("unexpected channel state: got: %+v want: 2 unread, 1 highlight", ch)
+			}
+		})
+	}
+
+	var got []bool
+	for _, e := range b.EventsBefore(eighteen, 2, 2) {
+		got = append(got, e.(*data.MessageEvent).Highlight)
+	}
+	if diff := cmp.Diff(got, []bool{true, false}); diff != "" {
+		t.Errorf("unexpected highlights: (-got +want)\n%s", diff)
+	}
+
+	for i, done := 0, false; !(done || i > attempts); i = delay(i) {
+		c.Join(func() {
+			ch := c.Chans[eighteen]
+			// Reading the messages marks them read.
+			done = ch.Unread == 0 && ch.Highlights == 0
+			if !done && i == attempts {
+				t.Errorf("unexpected channel state: got: %+v want: no unread highlights", ch)
+			}
+		})
+	}
+}
+
+func TestIgnores(t *testing.T) {
+	t.Parallel()
+	attempts := 4
+	b := demo.New()
+	c := testhelper.NewClient()
+	b.Subscribe(c)
+
+	cfg, err := config.Parse("test", `
+[ignore "sonnet" "#eighteen"]
+regex = summer
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Apply(b, config.Diff(&config.Config{}, cfg))
+	r, err := ignore.ParseRule(data.Scope{Net: eighteen.Net}, "troilus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Ignore(r)
+
+	// troilus: "Shall I compare thee to a summer’s day?"
+	b.TickMessages(eighteen.Net, eighteen.Name)
+	if !b.Unignore(data.Scope{Net: eighteen.Net}, "troilus") {
+		t.Errorf("unexpected result: Unignore found no rule")
+	}
+	// troilus: "Thou art more lovely and more temperate."
+	b.TickMessages(eighteen.Net, eighteen.Name)
+
+	for i, done := 0, false; !(done || i > attempts); i = delay(i) {
+		c.Join(func() {
+			ch := c.Chans[eighteen]
+			done = ch.Unread == 1
+			if !done && i == attempts {
+				t.Errorf("unexpected channel state: got: %+v want: 1 unread", ch)
+			}
+		})
+	}
+
+	var got []bool
+	for _, e := range b.EventsBefore(eighteen, 2, 2) {
+		got = append(got, data.Ignored(e))
+	}
+	if diff := cmp.Diff(got, []bool{true, false}); diff != "" {
+		t.Errorf("unexpected ignored messages: (-got +want)\n%s", diff)
+	}
+
+	var rules []string
+	for _, r := range b.Ignores(eighteen) {
+		rules = append(rules, r.String())
+	}
+	if diff := cmp.Diff(rules, []string{"re:summer in sonnet/#eighteen"}); diff != "" {
+		t.Errorf("unexpected rules: (-got +want)\n%s", diff)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+	b := demo.New()
+	// "Shall I compare thee to a summer’s day?"
+	b.TickMessages(eighteen.Net, eighteen.Name)
+	// "Thou art more lovely and more temperate."
+	b.TickMessages(eighteen.Net, eighteen.Name)
+
+	for _, reindex := range []bool{false, true} {
+		if reindex {
+			b.Reindex()
+		}
+		for _, tt := range []struct {
+			query string
+			want  []string
+		}{
+			{query: `in:#eighteen "more lovely"`, want: []string{"Thou art more lovely and more temperate."}},
+			{query: `SUMMER`, want: []string{"Shall I compare thee to a summer’s day?"}},
+			// The index has both words, but not in this order.
+			{query: `"lovely more"`},
+			{query: `thou -temperate`},
+			{query: `kind:message -re:^Shall`, want: []string{"Thou art more lovely and more temperate."}},
+		} {
+			f, err := data.ParseFilter(tt.query)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.query, err)
+			}
+			var got []string
+			for _, e := range b.Search(f, 0) {
+				got = append(got, data.Text(e))
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("%s: unexpected results (reindexed: %v): (-got +want)\n%s", tt.query, reindex, diff)
+			}
+		}
+	}
+
+	got := b.Search(data.Filter{}, 1)
+	if len(got) != 1 || got[0].ID().Seq != 2 {
+		t.Errorf("unexpected results with a limit of 1: got: %v want: the newest message", got)
+	}
+	f, _ := data.ParseFilter("more")
+	got = b.MessagesAfter(eighteen, 0, f)
+	if len(got) != 1 || got[0].ID().Seq != 2 {
+		t.Errorf("unexpected messages after 0: got: %v want: the second message", got)
+	}
+	if got := b.MessagesAfter(eighteen, 2, data.Filter{}); len(got) != 0 {
+		t.Errorf("unexpected messages after 2: got: %v want: none", got)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	t.Parallel()
+	cfg, err := config.Parse("test", `
+[highlight]
+word = summer
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		test string
+		view backend.Receiver
+		want bool
+	}{
+		{test: "client view", view: testhelper.NewClient(), want: true},
+		{test: "other channel", view: testhelper.NewChannel(eighteen.Net, "#nineteen"), want: true},
+		{test: "same channel", view: testhelper.NewChannel(eighteen.Net, eighteen.Name), want: false},
+	} {
+		ran := make(chan string, 1)
+		b := demo.New()
+		b.SetNotifier(notify.New(config.Notify{Command: "notify"}, func(_ []byte, name string, args ...string) error {
+			ran <- name
+			return nil
+		}))
+		b.Subscribe(tt.view)
+		backend.Apply(b, config.Diff(&config.Config{}, cfg))
+
+		// "Shall I compare thee to a summer’s day?"
+		b.TickMessages(eighteen.Net, eighteen.Name)
+
+		select {
+		case <-ran:
+			if !tt.want {
+				t.Errorf("%s: unexpected notification", tt.test)
+			}
+		case <-time.After(500 * time.Millisecond):
+			if tt.want {
+				t.Errorf("%s: no notification", tt.test)
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package demo
+
+import (
+	"sort"
+
+	"github.com/cceckman/discoirc/backend"
+	"github.com/cceckman/discoirc/data"
+)
+
+var _ backend.Directory = &Demo{}
+
+// Networks returns the names of the configured or connected networks.
+func (d *Demo) Networks() []string {
+	d.RLock()
+	defer d.RUnlock()
+
+	names := make(map[string]bool)
+	for name := range d.configs {
+		names[name] = true
+	}
+	for scope := range d.nets {
+		names[scope.Net] = true
+	}
+	return sorted(names)
+}
+
+// Channels returns the names of the channels configured or joined on the
+// network.
+func (d *Demo) Channels(network string) []string {
+	d.RLock()
+	defer d.RUnlock()
+
+	names := make(map[string]bool)
+	if cfg, ok := d.configs[network]; ok {
+		for _, c := range cfg.Channels {
+			names[c.Name] = true
+		}
+	}
+	for scope := range d.chans {
+		if scope.Net == network {
+			names[scope.Name] = true
+		}
+	}
+	return sorted(names)
+}
+
+// Members returns the nicks that have spoken in the channel, most recently
+// active first.
+func (d *Demo) Members(s data.Scope) []string {
+	d.RLock()
+	defer d.RUnlock()
+
+	return append([]string(nil), d.members[s]...)
+}
+
+// touch moves the nick to the front of the channel's members.
+// It must be called under the write lock.
+func (d *Demo) touch(s data.Scope, nick string) {
+	if nick == "" {
+		return
+	}
+	members := []string{nick}
+	for _, m := range d.members[s] {
+		if m != nick {
+			members = append(members, m)
+		}
+	}
+	d.members[s] = members
+}
+
+func sorted(set map[string]bool) []string {
+	r := make([]string, 0, len(set))
+	for k := range set {
+		r = append(r, k)
+	}
+	sort.Strings(r)
+	return r
+}
@@ -0,0 +1,44 @@
+package demo
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cceckman/discoirc/backend"
+	"github.com/cceckman/discoirc/backend/ignore"
+	"github.com/cceckman/discoirc/config"
+	"github.com/cceckman/discoirc/data"
+)
+
+var _ backend.Ignorer = &Demo{}
+
+// SetIgnores sets the rules from the configuration for ignoring users.
+func (d *Demo) SetIgnores(rules []*config.Ignore) {
+	d.Lock()
+	defer d.Unlock()
+	if err := d.ignores.SetConfig(rules); err != nil {
+		go d.ReportError(fmt.Errorf("ignore rules not set: %v", err))
+	}
+}
+
+// Ignore adds the rule, replacing any for the same target in the same scope.
+func (d *Demo) Ignore(r *ignore.Rule) {
+	d.Lock()
+	defer d.Unlock()
+	d.ignores.Add(r)
+}
+
+// Unignore removes the rule added for the target in the scope, and reports
+// whether there was one.
+func (d *Demo) Unignore(s data.Scope, target string) bool {
+	d.Lock()
+	defer d.Unlock()
+	return d.ignores.Remove(s, target)
+}
+
+// Ignores returns the rules that apply in the scope.
+func (d *Demo) Ignores(s data.Scope) []*ignore.Rule {
+	d.RLock()
+	defer d.RUnlock()
+	return d.ignores.Rules(s, time.Now())
+}
@@ -0,0 +1,322 @@
+package demo
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/cceckman/discoirc/backend"
+	"github.com/cceckman/discoirc/backend/highlight"
+	"github.com/cceckman/discoirc/config"
+	"github.com/cceckman/discoirc/data"
+)
+
+var _ backend.Manager = &Demo{}
+var _ backend.Saver = &Demo{}
+
+// Configure sets the configuration of a network. Its limits on the lines sent
+// to the network apply at once.
+func (d *Demo) Configure(n *config.Network) {
+	d.Lock()
+	defer d.Unlock()
+
+	if _, ok := d.configs[n.Name]; !ok {
+		d.order = append(d.order, n.Name)
+	}
+	// Runtime changes (e.g. joins) are recorded in the configuration,
+	// so keep a copy of our own.
+	d.configs[n.Name] = n.Clone()
+	if c := d.conns[n.Name]; c != nil {
+		c.queue.SetLimits(n.Flood())
+	}
+}
+
+// Configuration returns a copy of the network's configuration.
+func (d *Demo) Configuration(network string) *config.Network {
+	d.RLock()
+	defer d.RUnlock()
+
+	if cfg, ok := d.configs[network]; ok {
+		return cfg.Clone()
+	}
+	return nil
+}
+
+// SetConfigPath sets the file to which Save writes the configuration.
+func (d *Demo) SetConfigPath(path string) {
+	d.Lock()
+	defer d.Unlock()
+
+	d.configPath = path
+}
+
+// Save writes the current configuration of all networks to the configuration
+// file.
+func (d *Demo) Save() error {
+	d.RLock()
+	path := d.configPath
+	cfg := &config.Config{}
+	for _, name := range d.order {
+		cfg.Networks = append(cfg.Networks, d.configs[name].Clone())
+	}
+	d.RUnlock()
+
+	if path == "" {
+		return errors.New("no configuration file to save to")
+	}
+	return config.Save(path, cfg)
+}
+
+// persist saves the configuration if the network's configuration asks for
+// runtime changes to be saved. Errors are reported to the subscriber.
+func (d *Demo) persist(network string) {
+	d.RLock()
+	cfg := d.configs[network]
+	ok := cfg != nil && cfg.PersistJoins
+	d.RUnlock()
+	if !ok {
+		return
+	}
+	if err := d.Save(); err != nil {
+		d.ReportError(fmt.Errorf("configuration not saved: %v", err))
+	}
+}
+
+// Connect "connects" to the network, and joins its autojoin channels.
+// Passwords are resolved first; if they can't be, the network isn't connected.
+func (d *Demo) Connect(network string) {
+	d.ensureNetwork(network)
+
+	d.RLock()
+	cfg := d.configs[network]
+	d.RUnlock()
+	if cfg != nil {
+		if err := resolvePasswords(cfg); err != nil {
+			d.ReportError(fmt.Errorf("can't connect to %s: %v", network, err))
+			return
+		}
+	}
+
+	d.Lock()
+	net := d.nets[data.Scope{Net: network}]
+	if net.State == data.Connected {
+		d.Unlock()
+		return
+	}
+	net.State = data.Connected
+	var autojoin []config.Channel
+	if cfg != nil {
+		net.Nick = cfg.Nick
+		for _, c := range cfg.Channels {
+			if c.AutoJoin {
+				autojoin = append(autojoin, *c)
+			}
+		}
+	}
+	d.Unlock()
+
+	for _, c := range autojoin {
+		d.Join(data.Scope{Net: network, Name: c.Name}, c.Key)
+	}
+
+	d.Lock()
+	d.flushNetwork(network)
+	d.Unlock()
+
+	go d.updateAll()
+}
+
+// resolvePasswords reads the network's passwords, as a real connection would.
+// The demo doesn't use them; they're never logged or shown.
+func resolvePasswords(cfg *config.Network) error {
+	if _, err := cfg.Password.Resolve(); err != nil {
+		return err
+	}
+	if cfg.SASL != nil {
+		if _, err := cfg.SASL.Password.Resolve(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Disconnect "disconnects" from the network.
+func (d *Demo) Disconnect(network string) {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.disconnect(network) {
+		go d.updateAll()
+	}
+}
+
+// disconnect marks the network disconnected, and reports whether it's known.
+// It must be called under the write lock.
+func (d *Demo) disconnect(network string) bool {
+	net, ok := d.nets[data.Scope{Net: network}]
+	if !ok {
+		return false
+	}
+	net.State = data.Disconnected
+	for scope, ch := range d.chans {
+		if scope.Net == network {
+			ch.Presence = data.NotPresent
+		}
+	}
+	return true
+}
+
+// Remove forgets the network. It's disconnected and forgotten at once, so no
+// update for it is sent once it's removed.
+func (d *Demo) Remove(network string) {
+	d.Lock()
+	defer d.Unlock()
+
+	d.disconnect(network)
+
+	delete(d.configs, network)
+	for i, name := range d.order {
+		if name == network {
+			d.order = append(d.order[:i], d.order[i+1:]...)
+			break
+		}
+	}
+	delete(d.nets, data.Scope{Net: network})
+	d.closeConn(network)
+	for scope := range d.chans {
+		if scope.Net == network {
+			delete(d.chans, scope)
+		}
+	}
+}
+
+// SetNick sets the user's nick on the network.
+func (d *Demo) SetNick(network, nick string) {
+	d.ensureNetwork(network)
+
+	d.Lock()
+	d.nets[data.Scope{Net: network}].Nick = nick
+	cfg := d.configs[network]
+	changed := cfg != nil && cfg.Nick != nick
+	if changed {
+		cfg.Nick = nick
+	}
+	d.Unlock()
+
+	if changed {
+		d.persist(network)
+	}
+	go d.updateAll()
+}
+
+// Join "joins" the channel. The demo backend accepts any key.
+// Messages waiting to be sent to the channel are sent once it's joined.
+func (d *Demo) Join(s data.Scope, key string) {
+	d.ensureChannel(s)
+
+	d.Lock()
+	d.chans[s].Presence = data.Joined
+	if d.nets[data.Scope{Net: s.Net}].State == data.Connected {
+		d.flush(s)
+	}
+	changed := false
+	if cfg := d.configs[s.Net]; cfg != nil {
+		ch := cfg.Channel(s.Name)
+		if ch == nil {
+			ch = &config.Channel{Name: s.Name}
+			cfg.Channels = append(cfg.Channels, ch)
+		}
+		changed = !ch.AutoJoin || (key != "" && ch.Key != key)
+		ch.AutoJoin = true
+		if key != "" {
+			ch.Key = key
+		}
+	}
+	d.Unlock()
+
+	if changed {
+		d.persist(s.Net)
+	}
+	go d.updateAll()
+}
+
+// Part "leaves" the channel.
+func (d *Demo) Part(s data.Scope) {
+	d.Lock()
+	ch, ok := d.chans[s]
+	if !ok {
+		d.Unlock()
+		return
+	}
+	ch.Presence = data.NotPresent
+	changed := false
+	if cfg := d.configs[s.Net]; cfg != nil {
+		if c := cfg.Channel(s.Name); c != nil && c.AutoJoin {
+			c.AutoJoin = false
+			changed = true
+		}
+	}
+	d.Unlock()
+
+	if changed {
+		d.persist(s.Net)
+	}
+	go d.updateAll()
+}
+
+// RemoveChannel "leaves" the channel and forgets it. It's forgotten at once,
+// so no update for it is sent once it's removed.
+func (d *Demo) RemoveChannel(s data.Scope) {
+	d.Lock()
+	delete(d.chans, s)
+	changed := false
+	if cfg := d.configs[s.Net]; cfg != nil {
+		for i, c := range cfg.Channels {
+			if c.Name == s.Name {
+				cfg.Channels = append(cfg.Channels[:i:i], cfg.Channels[i+1:]...)
+				changed = true
+				break
+			}
+		}
+	}
+	d.Unlock()
+
+	if changed {
+		d.persist(s.Net)
+	}
+}
+
+// ReportError sends an ErrorEvent to the subscriber, if its filter accepts
+// discoirc-internal events.
+func (d *Demo) ReportError(err error) {
+	d.RLock()
+	defer d.RUnlock()
+
+	if d.subscriber == nil {
+		return
+	}
+	recv := d.subscriber
+	filter := recv.Filter()
+	if !filter.Match(data.Scope{}) {
+		return
+	}
+
+	event := &data.ErrorEvent{
+		EventID: data.EventID{Seq: data.Seq(atomic.AddInt64(&d.seq, 1))},
+		Line:    err.Error(),
+	}
+	go recv.Receive(event)
+}
+
+// SetHighlights sets the rules for highlighting messages.
+func (d *Demo) SetHighlights(rules []*config.Highlight) {
+	r, err := highlight.Compile(rules)
+	if err != nil {
+		d.ReportError(fmt.Errorf("highlight rules not set: %v", err))
+		return
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	d.highlights = r
+}
@@ -0,0 +1,69 @@
+package demo
+
+import (
+	"fmt"
+
+	"github.com/cceckman/discoirc/backend"
+	"github.com/cceckman/discoirc/backend/notify"
+	"github.com/cceckman/discoirc/data"
+)
+
+var _ backend.Notifications = &Demo{}
+
+// SetNotifier sets the Notifier that tells the user of highlights and private
+// messages.
+func (d *Demo) SetNotifier(n *notify.Notifier) {
+	d.Lock()
+	defer d.Unlock()
+	d.notifier = n
+}
+
+// SetDoNotDisturb turns notifications off, or back on.
+func (d *Demo) SetDoNotDisturb(on bool) {
+	d.RLock()
+	defer d.RUnlock()
+	d.notifier.SetDoNotDisturb(on)
+}
+
+// DoNotDisturb reports whether notifications are off.
+func (d *Demo) DoNotDisturb() bool {
+	d.RLock()
+	defer d.RUnlock()
+	return d.notifier.DoNotDisturb()
+}
+
+// notify notifies the user of the message, if it's a highlight or a private
+// message from someone else who isn't ignored, and the subscriber isn't viewing
+// its scope.
+// It must be called under the write lock.
+func (d *Demo) notify(m *data.MessageEvent, own string) {
+	private := !isChannel(m.Scope.Name)
+	if m.Nick == own || m.Ignored || !(m.Highlight || private) || d.viewing(m.Scope) {
+		return
+	}
+	nt := notify.Notification{
+		Network:   m.Scope.Net,
+		Target:    m.Scope.Name,
+		Nick:      m.Nick,
+		Text:      data.StripFormatting(m.Text),
+		Time:      m.Time,
+		Highlight: m.Highlight,
+		Private:   private,
+	}
+	n := d.notifier
+	go func() {
+		if err := n.Notify(nt); err != nil {
+			d.ReportError(fmt.Errorf("notification failed: %v", err))
+		}
+	}()
+}
+
+// viewing reports whether the subscriber is a view of the scope.
+// It must be called under the lock.
+func (d *Demo) viewing(s data.Scope) bool {
+	if d.subscriber == nil {
+		return false
+	}
+	f := d.subscriber.Filter()
+	return f.MatchNet && f.MatchName && f.Match(s)
+}
@@ -0,0 +1,88 @@
+package demo
+
+import (
+	"fmt"
+
+	"github.com/cceckman/discoirc/backend"
+	"github.com/cceckman/discoirc/backend/outbox"
+	"github.com/cceckman/discoirc/data"
+)
+
+var _ backend.Outbox = &Demo{}
+
+// SetOutbox sets the Store that holds messages sent while their network is
+// disconnected, e.g. one saved across restarts.
+func (d *Demo) SetOutbox(o *outbox.Store) {
+	scopes := o.Scopes()
+	for _, s := range scopes {
+		d.ensureChannel(s)
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	d.outbox = o
+	for _, s := range scopes {
+		d.chans[s].Pending = len(o.Pending(s))
+	}
+	go d.updateAll()
+}
+
+// Pending returns the messages waiting to be sent to the scope.
+func (d *Demo) Pending(s data.Scope) []outbox.Message {
+	d.RLock()
+	defer d.RUnlock()
+
+	return d.outbox.Pending(s)
+}
+
+// Cancel drops a message waiting to be sent to the scope.
+func (d *Demo) Cancel(s data.Scope, id int64) bool {
+	d.Lock()
+	defer d.Unlock()
+
+	ok, err := d.outbox.Cancel(s, id)
+	if err != nil {
+		go d.ReportError(fmt.Errorf("outbox not saved: %v", err))
+	}
+	if ch, found := d.chans[s]; ok && found {
+		ch.Pending = len(d.outbox.Pending(s))
+		go d.updateAll()
+	}
+	return ok
+}
+
+// flush sends the messages waiting to be sent to the scope.
+// It must be called under the write lock.
+func (d *Demo) flush(s data.Scope) {
+	msgs, err := d.outbox.Take(s)
+	if err != nil {
+		go d.ReportError(fmt.Errorf("outbox not saved: %v", err))
+	}
+	if len(msgs) == 0 {
+		return
+	}
+	for _, m := range msgs {
+		d.sendLine(s, m.Text)
+	}
+	d.chans[s].Pending = 0
+}
+
+// flushNetwork sends the messages waiting to be sent to users, and to joined
+// channels, on the network. Messages to other channels wait until they're
+// joined.
+// It must be called under the write lock.
+func (d *Demo) flushNetwork(network string) {
+	for _, s := range d.outbox.Scopes() {
+		if s.Net != network {
+			continue
+		}
+		if ch := d.chans[s]; !isChannel(s.Name) || ch.Presence == data.Joined {
+			d.flush(s)
+		}
+	}
+}
+
+// isChannel reports whether the target is a channel, rather than a user.
+func isChannel(name string) bool {
+	return name != "" && (name[0] == '#' || name[0] == '&' || name[0] == '+' || name[0] == '!')
+}
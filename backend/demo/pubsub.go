@@ -52,7 +52,7 @@ func (d *Demo) updateAll() {
 		// channel view; a real backend has to do some amount of
 		// duplication to the channel.
 		/// Do a more specific match here.
-		if filter.MatchNet && scope.Net != filter.Net {
+		if !filter.MatchNetwork(scope.Net) {
 			continue
 		}
 
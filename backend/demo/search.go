@@ -0,0 +1,70 @@
+package demo
+
+import (
+	"sort"
+
+	"github.com/cceckman/discoirc/backend"
+	"github.com/cceckman/discoirc/data"
+)
+
+var _ backend.Searcher = &Demo{}
+
+// Search returns up to limit messages matching the Filter, newest first.
+func (d *Demo) Search(f data.Filter, limit int) data.EventList {
+	ids := d.index.Candidates(&f)
+
+	d.RLock()
+	defer d.RUnlock()
+	var found data.EventList
+	for _, id := range ids {
+		if limit > 0 && len(found) == limit {
+			break
+		}
+		if e := d.event(id); e != nil && f.MatchEvent(e) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// MessagesAfter returns the messages in the scope after the Seq that match the
+// Filter, oldest first.
+func (d *Demo) MessagesAfter(s data.Scope, after data.Seq, f data.Filter) data.EventList {
+	d.RLock()
+	defer d.RUnlock()
+	evs := d.contents[s]
+	i := sort.Search(len(evs), func(i int) bool {
+		return evs[i].ID().Seq > after
+	})
+	var found data.EventList
+	for _, e := range evs[i:] {
+		if _, ok := e.(*data.MessageEvent); ok && f.MatchEvent(e) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// event returns the Event with the ID, or nil if it isn't kept.
+// It must be called with the lock held.
+func (d *Demo) event(id data.EventID) data.Event {
+	evs := d.contents[id.Scope]
+	i := sort.Search(len(evs), func(i int) bool {
+		return evs[i].ID().Seq >= id.Seq
+	})
+	if i < len(evs) && evs[i].ID().Seq == id.Seq {
+		return evs[i]
+	}
+	return nil
+}
+
+// Reindex rebuilds the full-text index from every channel's messages.
+func (d *Demo) Reindex() {
+	d.RLock()
+	defer d.RUnlock()
+	var events []data.Event
+	for _, evs := range d.contents {
+		events = append(events, evs...)
+	}
+	d.index.Rebuild(events)
+}
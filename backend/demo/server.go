@@ -0,0 +1,224 @@
+package demo
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/cceckman/discoirc/backend/multiline"
+	"github.com/cceckman/discoirc/backend/sendq"
+	"github.com/cceckman/discoirc/config"
+	"github.com/cceckman/discoirc/data"
+)
+
+// defaultMultiline are the multiline limits of the demo's servers, unless
+// SetMultiline changes them.
+var defaultMultiline = &multiline.Limits{MaxBytes: 4096, MaxLines: 24}
+
+// conn is the demo's connection to a network's server. The user's lines are
+// sent as IRC lines, through the connection's send queue, as a real client's
+// would be, and the server echoes the messages in them back.
+type conn struct {
+	queue  *sendq.Queue
+	cancel context.CancelFunc
+
+	// limits are the server's multiline limits, or nil if it doesn't
+	// support batches.
+	limits *multiline.Limits
+	// refs counts the batches sent, to name them.
+	refs int
+	// batches are the open batches the server has received, by name.
+	batches map[string]*batch
+}
+
+// batch is a multiline batch the server is receiving.
+type batch struct {
+	target string
+	text   string
+	lines  int
+}
+
+// ref returns the name of a new batch.
+func (c *conn) ref() string {
+	c.refs++
+	return strconv.Itoa(c.refs)
+}
+
+// errClosed is the error writing to a closed connection.
+var errClosed = errors.New("connection closed")
+
+// conn returns the connection to the network, starting its send queue if it's
+// new.
+// It must be called under the write lock.
+func (d *Demo) conn(network string) *conn {
+	c := d.conns[network]
+	if c != nil {
+		return c
+	}
+	c = &conn{limits: defaultMultiline, batches: make(map[string]*batch)}
+	burst, rate := d.flood(network)
+	c.queue = sendq.New(func(line string) error {
+		d.Lock()
+		defer d.Unlock()
+		if d.conns[network] != c {
+			return errClosed
+		}
+		d.receive(network, line)
+		return nil
+	}, burst, rate)
+	var ctx context.Context
+	ctx, c.cancel = context.WithCancel(context.Background())
+	go c.queue.Run(ctx)
+	d.conns[network] = c
+	return c
+}
+
+// flood returns the limits on the lines sent to the network, as configured:
+// the most sent at once, and the lines per second after that.
+// It must be called under the lock.
+func (d *Demo) flood(network string) (burst int, rate float64) {
+	if cfg := d.configs[network]; cfg != nil {
+		return cfg.Flood()
+	}
+	return (&config.Network{}).Flood()
+}
+
+// closeConn stops sending lines to the network's server; any still queued
+// aren't sent.
+// It must be called under the write lock.
+func (d *Demo) closeConn(network string) {
+	if c := d.conns[network]; c != nil {
+		c.cancel()
+		delete(d.conns, network)
+	}
+}
+
+// SetMultiline sets the limits of the network's server on multiline batches,
+// or, if limits is nil, has it not support them, so messages of several lines
+// are sent as separate messages.
+func (d *Demo) SetMultiline(network string, limits *multiline.Limits) {
+	d.Lock()
+	defer d.Unlock()
+	d.conn(network).limits = limits
+}
+
+// sendLine queues a line the user sent, which parseLine accepts, to be sent to
+// the server.
+// It must be called under the write lock.
+func (d *Demo) sendLine(scope data.Scope, line string) {
+	command, text, err := parseLine(line)
+	if err != nil {
+		return
+	}
+	c := d.conn(scope.Net)
+	var lines []string
+	if command == data.Action {
+		// CTCP actions can't span lines.
+		text = strings.Replace(text, "\n", " ", -1)
+		head := "PRIVMSG " + scope.Name + " :"
+		max := sendq.MaxLine - len("\r\n") - sendq.DefaultPrefixLen - len(head) - len("\x01ACTION \x01")
+		for _, p := range sendq.Split(text, max) {
+			lines = append(lines, head+"\x01ACTION "+p+"\x01")
+		}
+	} else {
+		limits := c.limits
+		if !strings.Contains(text, "\n") {
+			// A single line needs no batch.
+			limits = nil
+		}
+		lines = multiline.Encode(scope.Name, strings.Split(text, "\n"), sendq.DefaultPrefixLen, limits, c.ref)
+	}
+	for _, l := range lines {
+		c.queue.Send(l)
+	}
+}
+
+// receive handles an IRC line the network's server receives from the user,
+// echoing the messages it sends.
+// It must be called under the write lock.
+func (d *Demo) receive(network, line string) {
+	tags, params := parseIRCLine(line)
+	if len(params) < 2 {
+		return
+	}
+	c := d.conn(network)
+	nick := d.nets[data.Scope{Net: network}].Nick
+	switch strings.ToUpper(params[0]) {
+	case "BATCH":
+		ref := params[1][1:]
+		switch {
+		case strings.HasPrefix(params[1], "+") && len(params) >= 4:
+			c.batches[ref] = &batch{target: params[3]}
+		case strings.HasPrefix(params[1], "-"):
+			if b := c.batches[ref]; b != nil {
+				delete(c.batches, ref)
+				d.echo(data.Scope{Net: network, Name: b.target}, nick, data.Privmsg, b.text)
+			}
+		}
+	case "PRIVMSG":
+		if len(params) < 3 {
+			return
+		}
+		target, text := params[1], params[2]
+		if b := c.batches[tags["batch"]]; b != nil {
+			if _, concat := tags[multiline.ConcatTag]; b.lines > 0 && !concat {
+				b.text += "\n"
+			}
+			b.text += text
+			b.lines++
+			return
+		}
+		command := data.Privmsg
+		if strings.HasPrefix(text, "\x01ACTION ") && strings.HasSuffix(text, "\x01") {
+			command, text = data.Action, text[len("\x01ACTION "):len(text)-1]
+		}
+		d.echo(data.Scope{Net: network, Name: target}, nick, command, text)
+	}
+}
+
+// echo appends a message the server echoes, unless its channel is gone.
+// It must be called under the write lock.
+func (d *Demo) echo(scope data.Scope, nick string, command data.Command, text string) {
+	if d.chans[scope] == nil {
+		return
+	}
+	d.appendMessage(scope, nick, command, text)
+}
+
+// parseIRCLine returns the tags of an IRC line, and its command and
+// parameters, the last of which may have spaces.
+func parseIRCLine(line string) (map[string]string, []string) {
+	tags := make(map[string]string)
+	if strings.HasPrefix(line, "@") {
+		i := strings.IndexByte(line, ' ')
+		if i < 0 {
+			return tags, nil
+		}
+		for _, kv := range strings.Split(line[1:i], ";") {
+			if j := strings.IndexByte(kv, '='); j >= 0 {
+				tags[kv[:j]] = kv[j+1:]
+			} else {
+				tags[kv] = ""
+			}
+		}
+		line = line[i+1:]
+	}
+	var params []string
+	for line != "" {
+		if strings.HasPrefix(line, ":") {
+			params = append(params, line[1:])
+			break
+		}
+		i := strings.IndexByte(line, ' ')
+		if i < 0 {
+			params = append(params, line)
+			break
+		}
+		if i > 0 {
+			params = append(params, line[:i])
+		}
+		line = line[i+1:]
+	}
+	return tags, params
+}
@@ -44,6 +44,9 @@ func (d *Demo) TickNetwork(network string) {
 	net := d.nets[scope]
 	net.State = nextConnState(net.State)
 	net.Nick = nextNick(net.Nick)
+	if net.State == data.Connected {
+		d.flushNetwork(network)
+	}
 
 	go d.updateAll()
 }
@@ -86,10 +89,15 @@ func (d *Demo) TickMessages(network, channel string) {
 	// different speaker for each iteration.
 	speaker := speakers[iteration%len(speakers)]
 
-	// Update unread before appending;
-	// only these messages may count as unread.
+	// Only these messages may count as unread; ignored ones don't.
+	m := d.appendMessage(scope, speaker, data.Privmsg, msg)
+	if m.Ignored {
+		return
+	}
 	d.chans[scope].Unread++
-	d.appendMessage(scope, speaker, msg)
+	if m.Highlight {
+		d.chans[scope].Highlights++
+	}
 }
 
 func nextNick(nick string) string {
@@ -0,0 +1,158 @@
+// Package highlight decides which messages to highlight: those that mention
+// the user's nick, and those that match the user's highlight rules (see
+// config.Highlight).
+package highlight
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cceckman/discoirc/config"
+	"github.com/cceckman/discoirc/data"
+)
+
+// Rules are compiled highlight rules.
+// A nil *Rules highlights only messages that mention the user's nick.
+type Rules struct {
+	// sets are ordered from the widest scope to the narrowest.
+	sets []*ruleSet
+}
+
+// ruleSet is the compiled form of a config.Highlight.
+type ruleSet struct {
+	scope    data.Scope
+	nick     *bool
+	words    []string
+	patterns []*regexp.Regexp
+	ignore   []string
+}
+
+// applies reports whether the rules apply to messages in the scope.
+func (rs *ruleSet) applies(s data.Scope) bool {
+	if rs.scope.Net == "" {
+		return true
+	}
+	if rs.scope.Net != s.Net {
+		return false
+	}
+	return rs.scope.Name == "" || strings.EqualFold(rs.scope.Name, s.Name)
+}
+
+// Compile returns the Rules described by the configuration.
+func Compile(cfg []*config.Highlight) (*Rules, error) {
+	r := &Rules{}
+	// Wider scopes first, so narrower ones decide whether the nick is
+	// highlighted.
+	for _, depth := range []int{0, 1, 2} {
+		for _, h := range cfg {
+			if scopeDepth(h) != depth {
+				continue
+			}
+			rs := &ruleSet{
+				scope:  data.Scope{Net: h.Network, Name: h.Channel},
+				nick:   h.Nick,
+				ignore: h.Ignore,
+			}
+			for _, w := range h.Words {
+				rs.words = append(rs.words, strings.ToLower(w))
+			}
+			for _, p := range h.Patterns {
+				re, err := regexp.Compile(p)
+				if err != nil {
+					return nil, err
+				}
+				rs.patterns = append(rs.patterns, re)
+			}
+			r.sets = append(r.sets, rs)
+		}
+	}
+	return r, nil
+}
+
+func scopeDepth(h *config.Highlight) int {
+	switch {
+	case h.Channel != "":
+		return 2
+	case h.Network != "":
+		return 1
+	}
+	return 0
+}
+
+// Match reports whether a message from sender, in the scope, is highlighted
+// for the user, whose nick is own. The user's own messages never are. Rules
+// match the text without its formatting codes.
+func (r *Rules) Match(s data.Scope, own, sender, text string) bool {
+	if sender == "" || strings.EqualFold(sender, own) {
+		return false
+	}
+	var sets []*ruleSet
+	if r != nil {
+		for _, rs := range r.sets {
+			if rs.applies(s) {
+				sets = append(sets, rs)
+			}
+		}
+	}
+
+	nick := true
+	for _, rs := range sets {
+		for _, i := range rs.ignore {
+			if strings.EqualFold(i, sender) {
+				return false
+			}
+		}
+		if rs.nick != nil {
+			nick = *rs.nick
+		}
+	}
+
+	text = data.StripFormatting(text)
+	lower := strings.ToLower(text)
+	if nick && own != "" && containsWord(lower, strings.ToLower(own)) {
+		return true
+	}
+	for _, rs := range sets {
+		for _, w := range rs.words {
+			if containsWord(lower, w) {
+				return true
+			}
+		}
+		for _, re := range rs.patterns {
+			if re.MatchString(text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsWord reports whether word appears in text as a whole word, i.e. not
+// as part of a longer word or nick.
+func containsWord(text, word string) bool {
+	if word == "" {
+		return false
+	}
+	for i := 0; i <= len(text)-len(word); {
+		j := strings.Index(text[i:], word)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(word)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if (start == 0 || !isWordRune(before)) && (end == len(text) || !isWordRune(after)) {
+			return true
+		}
+		_, size := utf8.DecodeRuneInString(text[start:])
+		i = start + size
+	}
+	return false
+}
+
+// isWordRune reports whether r may be part of a word or nick.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("[]\\`_^{|}-", r)
+}
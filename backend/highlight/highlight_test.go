@@ -0,0 +1,83 @@
+package highlight_test
+
+import (
+	"testing"
+
+	"github.com/cceckman/discoirc/backend/highlight"
+	"github.com/cceckman/discoirc/config"
+	"github.com/cceckman/discoirc/data"
+)
+
+var (
+	hamlet      = data.Scope{Net: "HamNet", Name: "#hamlet"}
+	battlements = data.Scope{Net: "HamNet", Name: "#battlements"}
+	stage       = data.Scope{Net: "Globe", Name: "#stage"}
+)
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+	cfg, err := config.Parse("highlight.conf", `
+[highlight "HamNet" "#battlements"]
+nick = false
+word = "the King"
+
+[highlight]
+word = Denmark
+regex = (?i)\bghost(ly)?\b
+ignore = polonius
+
+[highlight "Globe"]
+ignore = will
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := highlight.Compile(cfg.Highlights)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		scope  data.Scope
+		sender string
+		text   string
+		want   bool
+	}{
+		{scope: hamlet, sender: "horatio", text: "yorick: alas", want: true},
+		{scope: hamlet, sender: "horatio", text: "Alas, poor Yorick!", want: true},
+		{scope: hamlet, sender: "horatio", text: "yoricks all", want: false},
+		{scope: hamlet, sender: "horatio", text: "ask yorick_", want: false},
+		{scope: hamlet, sender: "yorick", text: "I am yorick", want: false},
+		{scope: hamlet, sender: "horatio", text: "something is rotten in the state of denmark", want: true},
+		{scope: hamlet, sender: "horatio", text: "Danes of Denmarkshire", want: false},
+		{scope: hamlet, sender: "horatio", text: "a GHOSTLY figure", want: true},
+		{scope: hamlet, sender: "horatio", text: "ghostwriter", want: false},
+		{scope: hamlet, sender: "Polonius", text: "yorick, Denmark, ghost", want: false},
+		{scope: battlements, sender: "horatio", text: "yorick!", want: false},
+		{scope: battlements, sender: "horatio", text: "It was the King", want: true},
+		{scope: hamlet, sender: "horatio", text: "It was the King", want: false},
+		{scope: stage, sender: "will", text: "yorick, Denmark", want: false},
+		{scope: stage, sender: "ben", text: "hey yorick", want: true},
+		{scope: stage, sender: "ben", text: "¡yorick!", want: true},
+		{scope: hamlet, sender: "horatio", text: "\x0304yorick\x03: alas", want: true},
+		{scope: hamlet, sender: "horatio", text: "a \x02ghost\x02ly figure", want: true},
+	} {
+		if got := rules.Match(tt.scope, "yorick", tt.sender, tt.text); got != tt.want {
+			t.Errorf("%v <%s> %q: got: %v want: %v", tt.scope, tt.sender, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMatch_NoRules(t *testing.T) {
+	t.Parallel()
+	var rules *highlight.Rules
+	if !rules.Match(hamlet, "yorick", "horatio", "YORICK: alas") {
+		t.Errorf("nick not highlighted without rules")
+	}
+	if rules.Match(hamlet, "yorick", "horatio", "Denmark") {
+		t.Errorf("unexpected highlight without rules")
+	}
+	if rules.Match(hamlet, "", "horatio", "alas") {
+		t.Errorf("unexpected highlight without a nick")
+	}
+}
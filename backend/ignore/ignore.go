@@ -0,0 +1,234 @@
+// Package ignore decides which users to ignore: those matching the user's
+// ignore rules (see config.Ignore), and those added with /ignore. Events from
+// ignored users are kept, but flagged, so views can hide them.
+package ignore
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cceckman/discoirc/config"
+	"github.com/cceckman/discoirc/data"
+)
+
+// Rule ignores the users matching a mask, or with an account, or the messages
+// matching a pattern, in a scope.
+type Rule struct {
+	// Scope is where the rule applies: everywhere if Net is empty, and on
+	// every channel of the network if Name is.
+	Scope data.Scope
+	// One of Mask, Account, and Pattern is set.
+	Mask    string
+	Account string
+	Pattern *regexp.Regexp
+	// Expires is when the rule stops applying, or zero if it doesn't.
+	Expires time.Time
+}
+
+// ParseRule returns the rule ignoring the target in the scope: a
+// nick!user@host mask, where a bare nick matches any user and host;
+// "account:name"; or "re:regexp".
+func ParseRule(s data.Scope, target string) (*Rule, error) {
+	r := &Rule{Scope: s}
+	switch {
+	case target == "" || strings.ContainsAny(target, " \t"):
+		return nil, fmt.Errorf("invalid ignore %q", target)
+	case strings.HasPrefix(target, "account:"):
+		r.Account = strings.TrimPrefix(target, "account:")
+		if r.Account == "" {
+			return nil, fmt.Errorf("no account in %q", target)
+		}
+	case strings.HasPrefix(target, "re:"):
+		re, err := regexp.Compile(strings.TrimPrefix(target, "re:"))
+		if err != nil {
+			return nil, err
+		}
+		r.Pattern = re
+	default:
+		r.Mask = expandMask(target)
+	}
+	return r, nil
+}
+
+// expandMask fills in the parts of a nick!user@host mask that are missing,
+// e.g. "polonius" is "polonius!*@*", and "*@spam.example.net" is
+// "*!*@spam.example.net".
+func expandMask(m string) string {
+	nick, user, host := m, "*", "*"
+	if i := strings.Index(nick, "@"); i >= 0 {
+		nick, host = nick[:i], nick[i+1:]
+	}
+	if i := strings.Index(nick, "!"); i >= 0 {
+		nick, user = nick[:i], nick[i+1:]
+	} else if strings.Contains(m, "@") {
+		// user@host
+		nick, user = "*", nick
+	}
+	for _, p := range []*string{&nick, &user, &host} {
+		if *p == "" {
+			*p = "*"
+		}
+	}
+	return nick + "!" + user + "@" + host
+}
+
+// Target returns what the rule ignores, as given to ParseRule.
+func (r *Rule) Target() string {
+	switch {
+	case r.Account != "":
+		return "account:" + r.Account
+	case r.Pattern != nil:
+		return "re:" + r.Pattern.String()
+	}
+	return r.Mask
+}
+
+// String describes the rule, e.g. "polonius!*@* in HamNet/#hamlet until
+// 2026-01-02 15:04".
+func (r *Rule) String() string {
+	s := r.Target()
+	switch {
+	case r.Scope.Name != "":
+		s += " in " + r.Scope.Net + "/" + r.Scope.Name
+	case r.Scope.Net != "":
+		s += " on " + r.Scope.Net
+	}
+	if !r.Expires.IsZero() {
+		s += " until " + r.Expires.Format("2006-01-02 15:04")
+	}
+	return s
+}
+
+// applies reports whether the rule applies in the scope at the time.
+func (r *Rule) applies(s data.Scope, now time.Time) bool {
+	if !r.Expires.IsZero() && !now.Before(r.Expires) {
+		return false
+	}
+	if r.Scope.Net == "" {
+		return true
+	}
+	if r.Scope.Net != s.Net {
+		return false
+	}
+	return r.Scope.Name == "" || strings.EqualFold(r.Scope.Name, s.Name)
+}
+
+// Source identifies the user an Event is from. User, Host, and Account are
+// empty if they aren't known.
+type Source struct {
+	Nick, User, Host, Account string
+}
+
+// match reports whether the rule matches an Event from the source, with the
+// text.
+func (r *Rule) match(src Source, text string) bool {
+	switch {
+	case r.Account != "":
+		return src.Account != "" && strings.EqualFold(r.Account, src.Account)
+	case r.Pattern != nil:
+		return r.Pattern.MatchString(text)
+	}
+	nick, rest := r.Mask, ""
+	if i := strings.Index(nick, "!"); i >= 0 {
+		nick, rest = nick[:i], nick[i+1:]
+	}
+	user, host := rest, ""
+	if i := strings.Index(user, "@"); i >= 0 {
+		user, host = user[:i], user[i+1:]
+	}
+	return data.MatchGlob(nick, src.Nick) && data.MatchGlob(user, src.User) && data.MatchGlob(host, src.Host)
+}
+
+// List is the rules for ignoring users: those from the configuration, and
+// those added since. A nil *List ignores no one. It's not safe for concurrent
+// use.
+type List struct {
+	config []*Rule
+	added  []*Rule
+}
+
+// New returns a List of the rules from the configuration.
+func New(cfg []*config.Ignore) (*List, error) {
+	l := &List{}
+	if err := l.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// SetConfig replaces the rules from the configuration, keeping those added.
+func (l *List) SetConfig(cfg []*config.Ignore) error {
+	var rules []*Rule
+	for _, i := range cfg {
+		s := data.Scope{Net: i.Network, Name: i.Channel}
+		for _, m := range i.Masks {
+			rules = append(rules, &Rule{Scope: s, Mask: expandMask(m)})
+		}
+		for _, a := range i.Accounts {
+			rules = append(rules, &Rule{Scope: s, Account: a})
+		}
+		for _, p := range i.Patterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return err
+			}
+			rules = append(rules, &Rule{Scope: s, Pattern: re})
+		}
+	}
+	l.config = rules
+	return nil
+}
+
+// Add adds the rule, replacing any added rule for the same target in the same
+// scope.
+func (l *List) Add(r *Rule) {
+	l.Remove(r.Scope, r.Target())
+	l.added = append(l.added, r)
+}
+
+// Remove removes the added rule for the target in the scope, and reports
+// whether there was one. Rules from the configuration can't be removed.
+func (l *List) Remove(s data.Scope, target string) bool {
+	if !strings.HasPrefix(target, "account:") && !strings.HasPrefix(target, "re:") {
+		target = expandMask(target)
+	}
+	for i, r := range l.added {
+		if r.Scope == s && r.Target() == target {
+			l.added = append(l.added[:i:i], l.added[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Rules returns the rules that apply in the scope at the time: those from the
+// configuration, then those added.
+func (l *List) Rules(s data.Scope, now time.Time) []*Rule {
+	if l == nil {
+		return nil
+	}
+	var rules []*Rule
+	for _, rs := range [][]*Rule{l.config, l.added} {
+		for _, r := range rs {
+			if r.applies(s, now) {
+				rules = append(rules, r)
+			}
+		}
+	}
+	return rules
+}
+
+// Match reports whether an Event in the scope, from the source, with the
+// text, is ignored at the time. Patterns match the text without its formatting
+// codes.
+func (l *List) Match(s data.Scope, src Source, text string, now time.Time) bool {
+	text = data.StripFormatting(text)
+	for _, r := range l.Rules(s, now) {
+		if r.match(src, text) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package ignore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cceckman/discoirc/backend/ignore"
+	"github.com/cceckman/discoirc/config"
+	"github.com/cceckman/discoirc/data"
+)
+
+var (
+	hamlet      = data.Scope{Net: "HamNet", Name: "#hamlet"}
+	battlements = data.Scope{Net: "HamNet", Name: "#battlements"}
+	stage       = data.Scope{Net: "Globe", Name: "#stage"}
+	now         = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+)
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+	cfg, err := config.Parse("ignore.conf", `
+[ignore]
+mask = *!*@spam.example.net
+account = spambot
+
+[ignore "HamNet" "#hamlet"]
+mask = polonius
+regex = (?i)\barras\b
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := ignore.New(cfg.Ignores)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := ignore.ParseRule(data.Scope{Net: "Globe"}, "will!*@*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Expires = now.Add(time.Hour)
+	l.Add(r)
+
+	for _, tt := range []struct {
+		scope data.Scope
+		src   ignore.Source
+		text  string
+		at    time.Time
+		want  bool
+	}{
+		{scope: stage, src: ignore.Source{Nick: "bot", User: "b", Host: "spam.example.net"}, want: true},
+		{scope: stage, src: ignore.Source{Nick: "bot", User: "b", Host: "SPAM.example.net"}, want: true},
+		{scope: stage, src: ignore.Source{Nick: "bot", User: "b", Host: "example.net"}, want: false},
+		{scope: stage, src: ignore.Source{Nick: "bot"}, want: false},
+		{scope: hamlet, src: ignore.Source{Nick: "bot", Account: "SpamBot"}, want: true},
+		{scope: hamlet, src: ignore.Source{Nick: "Polonius"}, want: true},
+		{scope: battlements, src: ignore.Source{Nick: "polonius"}, want: false},
+		{scope: hamlet, src: ignore.Source{Nick: "hamlet"}, text: "Behind the arras!", want: true},
+		{scope: hamlet, src: ignore.Source{Nick: "hamlet"}, text: "Behind the \x1farras\x1f!", want: true},
+		{scope: stage, src: ignore.Source{Nick: "will"}, want: true},
+		{scope: stage, src: ignore.Source{Nick: "will"}, at: now.Add(time.Hour), want: false},
+		{scope: hamlet, src: ignore.Source{Nick: "will"}, want: false},
+	} {
+		at := tt.at
+		if at.IsZero() {
+			at = now
+		}
+		if got := l.Match(tt.scope, tt.src, tt.text, at); got != tt.want {
+			t.Errorf("%v %+v %q at %v: got: %v want: %v", tt.scope, tt.src, tt.text, at, got, tt.want)
+		}
+	}
+
+	var nilList *ignore.List
+	if nilList.Match(hamlet, ignore.Source{Nick: "polonius"}, "", now) {
+		t.Errorf("nil list unexpectedly ignores")
+	}
+}
+
+func TestList_AddRemove(t *testing.T) {
+	t.Parallel()
+	l, _ := ignore.New(nil)
+	for _, target := range []string{"polonius", "polonius!*@*", "account:claudius", "re:arras", "*@elsinore.dk"} {
+		r, err := ignore.ParseRule(hamlet, target)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", target, err)
+		}
+		l.Add(r)
+	}
+	var got []string
+	for _, r := range l.Rules(hamlet, now) {
+		got = append(got, r.String())
+	}
+	want := []string{
+		"polonius!*@* in HamNet/#hamlet",
+		"account:claudius in HamNet/#hamlet",
+		"re:arras in HamNet/#hamlet",
+		"*!*@elsinore.dk in HamNet/#hamlet",
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("unexpected rules: (-got +want)\n%s", diff)
+	}
+
+	if !l.Remove(hamlet, "polonius") || l.Remove(hamlet, "polonius") || l.Remove(battlements, "re:arras") {
+		t.Errorf("unexpected removals")
+	}
+	if got := len(l.Rules(hamlet, now)); got != 3 {
+		t.Errorf("unexpected number of rules: got: %d want: 3", got)
+	}
+
+	for _, target := range []string{"", "account:", "re:(", "two words"} {
+		if _, err := ignore.ParseRule(hamlet, target); err == nil {
+			t.Errorf("%q: unexpected success", target)
+		}
+	}
+}
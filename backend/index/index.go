@@ -0,0 +1,213 @@
+// Package index is a full-text index of messages, across every scope, for
+// filters like `from:alice in:#ops "deploy failed" after:2026-01-01` (see
+// data.ParseFilter).
+//
+// The index is kept in memory. It's updated as messages arrive, and rebuilt
+// from the backend's log of events when it starts.
+package index
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/cceckman/discoirc/data"
+)
+
+// Index is an inverted index of messages: from each term to the messages
+// that contain it. It's safe for concurrent use.
+type Index struct {
+	mu   sync.RWMutex
+	docs []*doc
+	// ids are the positions in docs of the indexed Events.
+	ids map[data.EventID]int
+	// postings are the positions in docs of the messages containing each
+	// term, in increasing order.
+	postings map[string][]int
+}
+
+// doc is an indexed message.
+type doc struct {
+	id   data.EventID
+	time time.Time
+}
+
+// New returns an empty Index.
+func New() *Index {
+	return &Index{
+		ids:      make(map[data.EventID]int),
+		postings: make(map[string][]int),
+	}
+}
+
+// Add adds the Event to the index, if it's a message that isn't already
+// indexed. Other Events are ignored.
+func (x *Index) Add(e data.Event) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.add(e)
+}
+
+// Rebuild replaces the contents of the index with the Events, e.g. all those
+// in the backend's log.
+func (x *Index) Rebuild(events []data.Event) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.docs = nil
+	x.ids = make(map[data.EventID]int)
+	x.postings = make(map[string][]int)
+	for _, e := range events {
+		x.add(e)
+	}
+}
+
+// Len returns the number of messages indexed.
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.docs)
+}
+
+// add must be called with mu held.
+func (x *Index) add(e data.Event) {
+	m, ok := e.(*data.MessageEvent)
+	if !ok {
+		return
+	}
+	if _, ok := x.ids[m.EventID]; ok {
+		return
+	}
+	d := &doc{id: m.EventID, time: m.Time}
+	n := len(x.docs)
+	x.docs = append(x.docs, d)
+	x.ids[d.id] = n
+	seen := make(map[string]bool)
+	for _, t := range Tokenize(data.StripFormatting(m.Text)) {
+		if !seen[t] {
+			seen[t] = true
+			x.postings[t] = append(x.postings[t], n)
+		}
+	}
+}
+
+// Candidates returns the IDs of the messages that may match the Filter, newest
+// first: those in its scopes and times whose text has every word of its Text.
+// Its other terms, and whether the words are in order, aren't checked;
+// Filter.MatchEvent checks the messages themselves.
+func (x *Index) Candidates(f *data.Filter) []data.EventID {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	var found []*doc
+	for _, n := range x.candidates(f.Text) {
+		if d := x.docs[n]; f.Match(d.id.Scope) && f.MatchTime(d.time) {
+			found = append(found, d)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if !a.time.Equal(b.time) {
+			return a.time.After(b.time)
+		}
+		if a.id.Seq != b.id.Seq {
+			return a.id.Seq > b.id.Seq
+		}
+		if a.id.Net != b.id.Net {
+			return a.id.Net < b.id.Net
+		}
+		return a.id.Name < b.id.Name
+	})
+	ids := make([]data.EventID, len(found))
+	for i, d := range found {
+		ids[i] = d.id
+	}
+	return ids
+}
+
+// candidates returns the positions of the messages with a term containing
+// each word of the text, or of all messages if it has no words. A word may be
+// part of a term, since text matches within words.
+// It must be called with mu held.
+func (x *Index) candidates(text []string) []int {
+	var words []string
+	for _, t := range text {
+		words = append(words, Tokenize(t)...)
+	}
+	if len(words) == 0 {
+		all := make([]int, len(x.docs))
+		for i := range all {
+			all[i] = i
+		}
+		return all
+	}
+	var result []int
+	for i, w := range words {
+		var found []int
+		for t, ns := range x.postings {
+			if strings.Contains(t, w) {
+				found = union(found, ns)
+			}
+		}
+		if i == 0 {
+			result = found
+		} else {
+			result = intersect(result, found)
+		}
+		if len(result) == 0 {
+			break
+		}
+	}
+	return result
+}
+
+// union returns the positions in either a or b, which are in increasing
+// order.
+func union(a, b []int) []int {
+	result := make([]int, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			result = append(result, a[i])
+			i++
+		case a[i] > b[j]:
+			result = append(result, b[j])
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	result = append(result, a[i:]...)
+	return append(result, b[j:]...)
+}
+
+// intersect returns the positions in both a and b, which are in increasing
+// order.
+func intersect(a, b []int) []int {
+	var result []int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// Tokenize splits the text into the terms it's indexed by: runs of letters
+// and digits, normalized and case-folded (see data.Fold).
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(data.Fold(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r)
+	})
+}
@@ -0,0 +1,153 @@
+package index_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cceckman/discoirc/backend/index"
+	"github.com/cceckman/discoirc/data"
+)
+
+var (
+	ops    = data.Scope{Net: "Work", Name: "#ops"}
+	dev    = data.Scope{Net: "Work", Name: "#dev"}
+	hamlet = data.Scope{Net: "HamNet", Name: "#hamlet"}
+)
+
+// day returns noon on a day of 2025 (before) or 2026, in the local time zone.
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 12, 0, 0, 0, time.Local)
+}
+
+func message(s data.Scope, seq data.Seq, at time.Time, nick, text string) *data.MessageEvent {
+	return &data.MessageEvent{
+		EventID: data.EventID{Scope: s, Seq: seq},
+		Nick:    nick,
+		Text:    text,
+		Time:    at,
+	}
+}
+
+var messages = []data.Event{
+	message(ops, 1, day(2025, 12, 15), "alice", "Deploy failed: disk full"),
+	message(ops, 2, day(2026, 1, 10), "bob", "deploy failed again"),
+	message(ops, 3, day(2026, 1, 12), "alice", "The deploy FAILED!"),
+	message(ops, 4, day(2026, 1, 13), "alice", "failed to deploy"),
+	message(dev, 1, day(2026, 1, 14), "alice", "deploy failed in staging"),
+	message(hamlet, 1, day(2026, 1, 11), "Hamlet", "Ｗｈｏ's there? Qu'est-ce que c'est? Café"),
+	&data.ChannelStateEvent{
+		EventID: data.EventID{Scope: ops, Seq: 5},
+		Line:    "TOPIC deploy failed",
+	},
+}
+
+func id(s data.Scope, seq data.Seq) data.EventID {
+	return data.EventID{Scope: s, Seq: seq}
+}
+
+func TestCandidates(t *testing.T) {
+	t.Parallel()
+	x := index.New()
+	for _, m := range messages {
+		x.Add(m)
+	}
+	if got := x.Len(); got != 6 {
+		t.Errorf("unexpected number of messages indexed: got: %d want: 6", got)
+	}
+
+	// Candidates only narrows by words, scopes, and times; the rest of each
+	// filter is checked against the messages.
+	for _, tt := range []struct {
+		query string
+		want  []data.EventID
+	}{
+		{
+			query: `from:alice in:#ops "deploy failed" after:2026-01-01`,
+			want:  []data.EventID{id(ops, 4), id(ops, 3), id(ops, 2)},
+		},
+		{
+			query: `"deploy failed"`,
+			want:  []data.EventID{id(dev, 1), id(ops, 4), id(ops, 3), id(ops, 2), id(ops, 1)},
+		},
+		{
+			query: `deploy failed before:2026-01-01`,
+			want:  []data.EventID{id(ops, 1)},
+		},
+		{
+			query: `net:work -in:#ops failed`,
+			want:  []data.EventID{id(dev, 1)},
+		},
+		{
+			// Words may be part of a term.
+			query: `deplo stag`,
+			want:  []data.EventID{id(dev, 1)},
+		},
+		{
+			query: `who café`,
+			want:  []data.EventID{id(hamlet, 1)},
+		},
+		{
+			// The decomposed form of café.
+			query: "CAFE\u0301",
+			want:  []data.EventID{id(hamlet, 1)},
+		},
+		{
+			query: `c'est`,
+			want:  []data.EventID{id(hamlet, 1)},
+		},
+		{
+			query: `in:#hamlet`,
+			want:  []data.EventID{id(hamlet, 1)},
+		},
+		{
+			query: `topic`,
+			want:  []data.EventID{},
+		},
+	} {
+		f, err := data.ParseFilter(tt.query)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.query, err)
+			continue
+		}
+		if diff := cmp.Diff(x.Candidates(&f), tt.want); diff != "" {
+			t.Errorf("%s: unexpected results: (-got +want)\n%s", tt.query, diff)
+		}
+	}
+}
+
+func TestCandidates_Formatting(t *testing.T) {
+	t.Parallel()
+	x := index.New()
+	x.Add(message(ops, 1, day(2026, 1, 10), "alice", "\x02deploy\x02 \x0304failed\x03"))
+	f := data.Filter{Text: []string{"deploy failed"}}
+	if diff := cmp.Diff(x.Candidates(&f), []data.EventID{id(ops, 1)}); diff != "" {
+		t.Errorf("unexpected results: (-got +want)\n%s", diff)
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	t.Parallel()
+	x := index.New()
+	x.Add(message(hamlet, 1, day(2026, 1, 1), "Hamlet", "Alas, poor Yorick!"))
+	x.Rebuild(messages)
+	// Adding a message again doesn't duplicate it.
+	x.Add(messages[0])
+	if got := x.Len(); got != 6 {
+		t.Errorf("unexpected number of messages indexed: got: %d want: 6", got)
+	}
+	f := data.Filter{Text: []string{"yorick"}}
+	if got := x.Candidates(&f); len(got) != 0 {
+		t.Errorf("unexpected results after rebuild: got: %v want: none", got)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	t.Parallel()
+	got := index.Tokenize("Straße: ＤＥＰＬＯＹ-failed, 2x")
+	want := []string{"strasse", "deploy", "failed", "2x"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("unexpected terms: (-got +want)\n%s", diff)
+	}
+}
@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"github.com/cceckman/discoirc/config"
+	"github.com/cceckman/discoirc/data"
+)
+
+// Manager changes the set of networks and channels the backend is connected
+// to.
+type Manager interface {
+	// Configure sets the configuration of a network. A network must be
+	// configured before it can be connected.
+	// Changes take effect the next time the network connects.
+	Configure(n *config.Network)
+	// Configuration returns a copy of the network's configuration, or nil
+	// if the network isn't configured.
+	Configuration(network string) *config.Network
+	// Connect connects to the network, and joins its autojoin channels.
+	// It does nothing if the network is already connected.
+	Connect(network string)
+	// Disconnect disconnects from the network.
+	Disconnect(network string)
+	// Remove disconnects from the network and forgets its configuration.
+	Remove(network string)
+	// SetNick changes the user's nick on the network.
+	SetNick(network, nick string)
+	// Join joins the channel, using the key if it is nonempty.
+	Join(s data.Scope, key string)
+	// Part leaves the channel.
+	Part(s data.Scope)
+	// RemoveChannel leaves the channel and forgets it, and its
+	// configuration; no update for it is sent once it's removed.
+	RemoveChannel(s data.Scope)
+	// SetHighlights sets the rules for highlighting messages that arrive
+	// from now on.
+	SetHighlights(rules []*config.Highlight)
+	// SetIgnores sets the rules from the configuration for ignoring users
+	// whose messages arrive from now on.
+	SetIgnores(rules []*config.Ignore)
+}
+
+// Apply makes the configuration changes on the Manager.
+func Apply(m Manager, changes []config.Change) {
+	for _, c := range changes {
+		switch c.Kind {
+		case config.AddNetwork:
+			m.Configure(c.Network)
+			m.Connect(c.Network.Name)
+		case config.RemoveNetwork:
+			m.Remove(c.Network.Name)
+		case config.UpdateNetwork:
+			m.Configure(c.Network)
+		case config.SetNick:
+			m.SetNick(c.Network.Name, c.Network.Nick)
+		case config.JoinChannel:
+			m.Join(data.Scope{Net: c.Network.Name, Name: c.Channel.Name}, c.Channel.Key)
+		case config.PartChannel:
+			m.Part(data.Scope{Net: c.Network.Name, Name: c.Channel.Name})
+		case config.SetHighlights:
+			m.SetHighlights(c.Highlights)
+		case config.SetIgnores:
+			m.SetIgnores(c.Ignores)
+		}
+	}
+}
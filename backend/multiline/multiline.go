@@ -0,0 +1,133 @@
+// Package multiline encodes messages of several lines for an IRC server: as
+// IRCv3 draft/multiline batches, if the server supports them, or as separate
+// PRIVMSG lines if it doesn't. Either way, the lines go through the
+// connection's send queue (package sendq), which limits their rate.
+package multiline
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cceckman/discoirc/backend/sendq"
+)
+
+// Cap is the name of the capability servers advertise to accept multiline
+// batches.
+const Cap = "draft/multiline"
+
+// ConcatTag marks a line of a batch as the continuation of the line before it,
+// rather than a new line.
+const ConcatTag = "draft/multiline-concat"
+
+// Limits are the limits the server puts on a multiline batch.
+type Limits struct {
+	// MaxBytes is the most bytes of text in a batch, counting the newlines
+	// between lines.
+	MaxBytes int
+	// MaxLines is the most lines in a batch, or 0 if there's no limit.
+	MaxLines int
+}
+
+// ParseLimits returns the Limits from the value of the draft/multiline
+// capability, e.g. "max-bytes=4096,max-lines=24".
+func ParseLimits(value string) (Limits, error) {
+	var l Limits
+	for _, kv := range strings.Split(value, ",") {
+		i := strings.Index(kv, "=")
+		if i < 0 {
+			// Unknown keys without values are allowed.
+			continue
+		}
+		var dst *int
+		switch kv[:i] {
+		case "max-bytes":
+			dst = &l.MaxBytes
+		case "max-lines":
+			dst = &l.MaxLines
+		default:
+			continue
+		}
+		n, err := strconv.Atoi(kv[i+1:])
+		if err != nil || n <= 0 {
+			return Limits{}, fmt.Errorf("invalid %s value %q", Cap, value)
+		}
+		*dst = n
+	}
+	if l.MaxBytes == 0 {
+		return Limits{}, fmt.Errorf("invalid %s value %q: no max-bytes", Cap, value)
+	}
+	return l, nil
+}
+
+// part is a piece of a line of text, short enough to send in one PRIVMSG.
+type part struct {
+	text string
+	// concat indicates the part continues the previous one.
+	concat bool
+}
+
+// Encode returns the IRC lines, without line endings, that send the lines of
+// text to target. Long lines are split so they fit in an IRC line when relayed
+// with a prefix of prefixLen bytes (see sendq.PrefixLen).
+// If limits is nil, the server doesn't support multiline batches; each line is
+// sent as a separate PRIVMSG, and blank lines are dropped. Otherwise, the lines
+// are sent in as few batches as fit the limits, each named by a call to ref.
+func Encode(target string, text []string, prefixLen int, limits *Limits, ref func() string) []string {
+	if limits == nil {
+		var lines []string
+		for _, line := range text {
+			if line != "" {
+				lines = append(lines, sendq.Messages("PRIVMSG", target, line, prefixLen)...)
+			}
+		}
+		return lines
+	}
+
+	// Tags don't count towards the length of a line.
+	max := sendq.MaxLine - len("\r\n") - prefixLen - len(privmsg("", target, ""))
+	if limits.MaxBytes < max {
+		max = limits.MaxBytes
+	}
+	var parts []part
+	for _, line := range text {
+		for i, p := range sendq.Split(line, max) {
+			parts = append(parts, part{text: p, concat: i > 0})
+		}
+	}
+
+	var lines []string
+	for len(parts) > 0 {
+		n, size := 0, 0
+		for ; n < len(parts); n++ {
+			add := len(parts[n].text)
+			if n > 0 && !parts[n].concat {
+				add++ // the newline before it
+			}
+			if n > 0 && (size+add > limits.MaxBytes || limits.MaxLines > 0 && n >= limits.MaxLines) {
+				break
+			}
+			size += add
+		}
+		r := ref()
+		lines = append(lines, fmt.Sprintf("BATCH +%s %s %s", r, Cap, target))
+		for i, p := range parts[:n] {
+			tags := "batch=" + r
+			// A batch can't continue a line from the one before it.
+			if p.concat && i > 0 {
+				tags += ";" + ConcatTag
+			}
+			lines = append(lines, privmsg(tags, target, p.text))
+		}
+		lines = append(lines, "BATCH -"+r)
+		parts = parts[n:]
+	}
+	return lines
+}
+
+func privmsg(tags, target, text string) string {
+	if tags != "" {
+		tags = "@" + tags + " "
+	}
+	return fmt.Sprintf("%sPRIVMSG %s :%s", tags, target, text)
+}
@@ -0,0 +1,141 @@
+package multiline_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cceckman/discoirc/backend/multiline"
+	"github.com/cceckman/discoirc/backend/sendq"
+)
+
+func TestParseLimits(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		value   string
+		want    multiline.Limits
+		wantErr bool
+	}{
+		{value: "max-bytes=4096,max-lines=24", want: multiline.Limits{MaxBytes: 4096, MaxLines: 24}},
+		{value: "max-bytes=4096", want: multiline.Limits{MaxBytes: 4096}},
+		{value: "future,max-bytes=512,color=blue", want: multiline.Limits{MaxBytes: 512}},
+		{value: "max-lines=24", wantErr: true},
+		{value: "max-bytes=lots", wantErr: true},
+		{value: "max-bytes=0", wantErr: true},
+	} {
+		got, err := multiline.ParseLimits(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: unexpected error: got: %v want error: %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q: unexpected limits: got: %+v want: %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+// maxText is the most text in a PRIVMSG to #hamlet.
+var maxText = sendq.MaxLine - len("\r\n") - sendq.DefaultPrefixLen - len("PRIVMSG #hamlet :")
+
+var encodeTests = []struct {
+	test   string
+	text   []string
+	limits *multiline.Limits
+	want   []string
+}{
+	{
+		test:   "batch",
+		text:   []string{"Who's there?", "", "Nay, answer me."},
+		limits: &multiline.Limits{MaxBytes: 4096},
+		want: []string{
+			"BATCH +r1 draft/multiline #hamlet",
+			"@batch=r1 PRIVMSG #hamlet :Who's there?",
+			"@batch=r1 PRIVMSG #hamlet :",
+			"@batch=r1 PRIVMSG #hamlet :Nay, answer me.",
+			"BATCH -r1",
+		},
+	},
+	{
+		test:   "max lines",
+		text:   []string{"one", "two", "three"},
+		limits: &multiline.Limits{MaxBytes: 4096, MaxLines: 2},
+		want: []string{
+			"BATCH +r1 draft/multiline #hamlet",
+			"@batch=r1 PRIVMSG #hamlet :one",
+			"@batch=r1 PRIVMSG #hamlet :two",
+			"BATCH -r1",
+			"BATCH +r2 draft/multiline #hamlet",
+			"@batch=r2 PRIVMSG #hamlet :three",
+			"BATCH -r2",
+		},
+	},
+	{
+		test:   "max bytes counts newlines",
+		text:   []string{"one", "two", "three"},
+		limits: &multiline.Limits{MaxBytes: 7},
+		want: []string{
+			"BATCH +r1 draft/multiline #hamlet",
+			"@batch=r1 PRIVMSG #hamlet :one",
+			"@batch=r1 PRIVMSG #hamlet :two",
+			"BATCH -r1",
+			"BATCH +r2 draft/multiline #hamlet",
+			"@batch=r2 PRIVMSG #hamlet :three",
+			"BATCH -r2",
+		},
+	},
+	{
+		test:   "long line",
+		text:   []string{strings.Repeat("a", maxText) + "bc"},
+		limits: &multiline.Limits{MaxBytes: 4096},
+		want: []string{
+			"BATCH +r1 draft/multiline #hamlet",
+			"@batch=r1 PRIVMSG #hamlet :" + strings.Repeat("a", maxText),
+			"@batch=r1;draft/multiline-concat PRIVMSG #hamlet :bc",
+			"BATCH -r1",
+		},
+	},
+	{
+		test:   "split within characters",
+		text:   []string{"ééé"},
+		limits: &multiline.Limits{MaxBytes: 3},
+		want: []string{
+			"BATCH +r1 draft/multiline #hamlet",
+			"@batch=r1 PRIVMSG #hamlet :é",
+			"BATCH -r1",
+			"BATCH +r2 draft/multiline #hamlet",
+			"@batch=r2 PRIVMSG #hamlet :é",
+			"BATCH -r2",
+			"BATCH +r3 draft/multiline #hamlet",
+			"@batch=r3 PRIVMSG #hamlet :é",
+			"BATCH -r3",
+		},
+	},
+	{
+		test: "without multiline",
+		text: []string{"Who's there?", "", "Nay, answer me."},
+		want: []string{
+			"PRIVMSG #hamlet :Who's there?",
+			"PRIVMSG #hamlet :Nay, answer me.",
+		},
+	},
+}
+
+func TestEncode(t *testing.T) {
+	for _, tt := range encodeTests {
+		tt := tt
+		t.Run(tt.test, func(t *testing.T) {
+			t.Parallel()
+			n := 0
+			ref := func() string {
+				n++
+				return fmt.Sprintf("r%d", n)
+			}
+			got := multiline.Encode("#hamlet", tt.text, sendq.DefaultPrefixLen, tt.limits, ref)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("unexpected lines: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
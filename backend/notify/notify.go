@@ -0,0 +1,152 @@
+// Package notify tells the user about highlights and private messages in
+// channels they aren't looking at: by ringing the terminal bell, flagging the
+// channel's tmux window, or running commands.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cceckman/discoirc/backend/sendq"
+	"github.com/cceckman/discoirc/config"
+)
+
+// Timeout is how long a notification command may run before it's killed.
+const Timeout = 10 * time.Second
+
+// Notification describes a message the user is notified of. Notification
+// commands get it as JSON on their standard input.
+type Notification struct {
+	Network string `json:"network"`
+	// Target is the channel the message was sent to, or the sender's nick
+	// if it was sent privately.
+	Target    string    `json:"target"`
+	Nick      string    `json:"nick"`
+	Text      string    `json:"text"`
+	Time      time.Time `json:"time"`
+	Highlight bool      `json:"highlight"`
+	Private   bool      `json:"private"`
+}
+
+// Runner runs a command with the given standard input.
+type Runner func(stdin []byte, name string, args ...string) error
+
+// Exec is a Runner that runs commands with os/exec, for up to Timeout.
+func Exec(stdin []byte, name string, args ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %v: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Notifier sends notifications, as configured, at a limited rate.
+// It's safe for concurrent use.
+type Notifier struct {
+	cfg config.Notify
+	run Runner
+
+	mu sync.Mutex
+	// bell is where the bell is rung, or nil if it isn't.
+	bell io.Writer
+	// alert flags the window showing a channel, or is nil if there's no
+	// window to flag.
+	alert  func(network, channel string) error
+	bucket *sendq.Bucket
+	dnd    bool
+}
+
+// New returns a Notifier that sends notifications as the configuration says,
+// running commands with run.
+func New(cfg config.Notify, run Runner) *Notifier {
+	limit := cfg.Limit()
+	return &Notifier{
+		cfg:    cfg,
+		run:    run,
+		bucket: sendq.NewBucket(limit, float64(limit)/60, time.Time{}),
+		dnd:    cfg.DoNotDisturb,
+	}
+}
+
+// SetBell sets where the bell character is written, i.e. the terminal.
+func (n *Notifier) SetBell(w io.Writer) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.bell = w
+}
+
+// SetAlert sets the function that flags the window showing a channel, e.g.
+// (*wm.Tmux).Alert.
+func (n *Notifier) SetAlert(alert func(network, channel string) error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.alert = alert
+}
+
+// SetDoNotDisturb turns notifications off, or back on.
+func (n *Notifier) SetDoNotDisturb(on bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.dnd = on
+}
+
+// DoNotDisturb reports whether notifications are off.
+func (n *Notifier) DoNotDisturb() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.dnd
+}
+
+// Notify sends the notification, unless notifications are off, or too many
+// have been sent recently. It returns the first error from sending it.
+func (n *Notifier) Notify(nt Notification) error {
+	if !nt.Highlight && !(nt.Private && n.cfg.NotifyPrivate()) {
+		return nil
+	}
+
+	n.mu.Lock()
+	if n.dnd || !n.bucket.Take(nt.Time) {
+		n.mu.Unlock()
+		return nil
+	}
+	bell, alert := n.bell, n.alert
+	n.mu.Unlock()
+
+	var errs []error
+	if n.cfg.Bell && bell != nil {
+		if _, err := io.WriteString(bell, "\a"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if n.cfg.Tmux && alert != nil {
+		errs = append(errs, alert(nt.Network, nt.Target))
+	}
+	if n.cfg.Command != "" {
+		stdin, err := json.Marshal(nt)
+		if err == nil {
+			err = n.run(stdin, "sh", "-c", n.cfg.Command)
+		}
+		errs = append(errs, err)
+	}
+	if f := strings.Fields(n.cfg.DesktopCommand); len(f) > 0 {
+		title := nt.Network + " " + nt.Target
+		body := fmt.Sprintf("<%s> %s", nt.Nick, nt.Text)
+		errs = append(errs, n.run(nil, f[0], append(f[1:], title, body)...))
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
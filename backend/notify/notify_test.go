@@ -0,0 +1,154 @@
+package notify_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cceckman/discoirc/backend/notify"
+	"github.com/cceckman/discoirc/config"
+)
+
+// stub records the commands run, and their input.
+type stub struct {
+	ran   []string
+	stdin [][]byte
+	err   error
+}
+
+func (s *stub) run(stdin []byte, name string, args ...string) error {
+	s.ran = append(s.ran, strings.Join(append([]string{name}, args...), " "))
+	s.stdin = append(s.stdin, stdin)
+	return s.err
+}
+
+var start = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func highlight(at time.Duration) notify.Notification {
+	return notify.Notification{
+		Network:   "HamNet",
+		Target:    "#hamlet",
+		Nick:      "horatio",
+		Text:      "yorick: alas",
+		Time:      start.Add(at),
+		Highlight: true,
+	}
+}
+
+func TestNotify(t *testing.T) {
+	t.Parallel()
+	s := &stub{}
+	var bell bytes.Buffer
+	var alerts []string
+	n := notify.New(config.Notify{
+		Bell:           true,
+		Tmux:           true,
+		Command:        "cat >> ~/highlights",
+		DesktopCommand: "notify-send -a discoirc",
+	}, s.run)
+	n.SetBell(&bell)
+	n.SetAlert(func(network, channel string) error {
+		alerts = append(alerts, network+" "+channel)
+		return nil
+	})
+
+	if err := n.Notify(highlight(0)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bell.String() != "\a" {
+		t.Errorf("unexpected bell: got: %q want: %q", bell.String(), "\a")
+	}
+	if diff := cmp.Diff(alerts, []string{"HamNet #hamlet"}); diff != "" {
+		t.Errorf("unexpected alerts: (-got +want)\n%s", diff)
+	}
+	want := []string{
+		"sh -c cat >> ~/highlights",
+		"notify-send -a discoirc HamNet #hamlet <horatio> yorick: alas",
+	}
+	if diff := cmp.Diff(s.ran, want); diff != "" {
+		t.Fatalf("unexpected commands: (-got +want)\n%s", diff)
+	}
+	var got notify.Notification
+	if err := json.Unmarshal(s.stdin[0], &got); err != nil {
+		t.Fatalf("invalid JSON input %q: %v", s.stdin[0], err)
+	}
+	if diff := cmp.Diff(got, highlight(0)); diff != "" {
+		t.Errorf("unexpected JSON input: (-got +want)\n%s", diff)
+	}
+}
+
+func TestNotify_Private(t *testing.T) {
+	t.Parallel()
+	private := notify.Notification{Network: "HamNet", Target: "horatio", Nick: "horatio", Text: "my lord", Time: start, Private: true}
+	no := false
+	for _, tt := range []struct {
+		test string
+		cfg  config.Notify
+		want int
+	}{
+		{test: "default", cfg: config.Notify{Command: "true"}, want: 1},
+		{test: "off", cfg: config.Notify{Command: "true", Private: &no}, want: 0},
+	} {
+		s := &stub{}
+		n := notify.New(tt.cfg, s.run)
+		if err := n.Notify(private); err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.test, err)
+		}
+		if len(s.ran) != tt.want {
+			t.Errorf("%s: unexpected commands: got: %q want: %d", tt.test, s.ran, tt.want)
+		}
+	}
+
+	// Other messages aren't notified.
+	s := &stub{}
+	n := notify.New(config.Notify{Command: "true"}, s.run)
+	if err := n.Notify(notify.Notification{Network: "HamNet", Target: "#hamlet", Time: start}); err != nil || len(s.ran) != 0 {
+		t.Errorf("unexpected notification of an ordinary message: got: %q, %v want: none", s.ran, err)
+	}
+}
+
+func TestNotify_RateLimit(t *testing.T) {
+	t.Parallel()
+	s := &stub{}
+	n := notify.New(config.Notify{Command: "true", PerMinute: 2}, s.run)
+	for _, at := range []time.Duration{0, time.Second, 2 * time.Second, 20 * time.Second, 30 * time.Second} {
+		if err := n.Notify(highlight(at)); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	// Two at once, and then one every 30 seconds.
+	if len(s.ran) != 3 {
+		t.Errorf("unexpected notifications: got: %d want: 3", len(s.ran))
+	}
+}
+
+func TestNotify_DoNotDisturb(t *testing.T) {
+	t.Parallel()
+	s := &stub{}
+	n := notify.New(config.Notify{Command: "true", DoNotDisturb: true}, s.run)
+	if !n.DoNotDisturb() {
+		t.Errorf("do not disturb not set from the configuration")
+	}
+	if err := n.Notify(highlight(0)); err != nil || len(s.ran) != 0 {
+		t.Errorf("unexpected notification while not disturbing: got: %q, %v want: none", s.ran, err)
+	}
+
+	n.SetDoNotDisturb(false)
+	if err := n.Notify(highlight(time.Second)); err != nil || len(s.ran) != 1 {
+		t.Errorf("unexpected notifications: got: %q, %v want: 1", s.ran, err)
+	}
+}
+
+func TestNotify_Error(t *testing.T) {
+	t.Parallel()
+	s := &stub{err: errors.New("exit status 1")}
+	n := notify.New(config.Notify{Command: "false"}, s.run)
+	if err := n.Notify(highlight(0)); err == nil || err.Error() != "exit status 1" {
+		t.Errorf("unexpected error: got: %v want: exit status 1", err)
+	}
+}
@@ -0,0 +1,241 @@
+// Package outbox keeps the messages the user sends while their network is
+// disconnected, until they can be sent, and saves them across restarts.
+package outbox
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/cceckman/discoirc/data"
+	"github.com/cceckman/discoirc/internal/files"
+)
+
+// DefaultPath returns the location of the outbox file:
+// $XDG_DATA_HOME/discoirc/outbox, or ~/.local/share/discoirc/outbox.
+func DefaultPath() string {
+	return files.DataPath("outbox")
+}
+
+// Message is a message waiting to be sent.
+type Message struct {
+	// ID identifies the message within the Store.
+	ID   int64  `json:"id"`
+	Text string `json:"text"`
+}
+
+// Store is the messages waiting to be sent to each scope.
+// It's safe for concurrent use.
+//
+// The file a Store is saved to may be shared with other processes, e.g. those
+// of other windows: each change is made to the messages saved there, so each
+// message is sent by only one of them, and none is lost.
+type Store struct {
+	mu sync.Mutex
+	// path is the file the messages are saved to, or "" if they aren't
+	// saved.
+	path    string
+	next    int64
+	pending map[data.Scope][]Message
+	// unsaved are the messages added that couldn't be saved; they're saved
+	// with the next change that can be.
+	unsaved map[data.Scope][]Message
+}
+
+// New returns an empty Store, which doesn't save its messages.
+func New() *Store {
+	return &Store{
+		next:    1,
+		pending: make(map[data.Scope][]Message),
+		unsaved: make(map[data.Scope][]Message),
+	}
+}
+
+// scopeMessages is the saved form of one scope's messages.
+type scopeMessages struct {
+	Net      string    `json:"net"`
+	Name     string    `json:"name"`
+	Messages []Message `json:"messages"`
+}
+
+// Open returns a Store saved to the file at path. If the file exists, the
+// messages are loaded from it.
+func Open(path string) (*Store, error) {
+	s := New()
+	s.path = path
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.pending, s.next, err = parse(contents)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// parse returns the messages saved in contents, and the ID after the last of
+// them.
+func parse(contents []byte) (map[data.Scope][]Message, int64, error) {
+	pending := make(map[data.Scope][]Message)
+	next := int64(1)
+	if len(contents) == 0 {
+		return pending, next, nil
+	}
+	var saved []scopeMessages
+	if err := json.Unmarshal(contents, &saved); err != nil {
+		return nil, 0, err
+	}
+	for _, sm := range saved {
+		pending[data.Scope{Net: sm.Net, Name: sm.Name}] = sm.Messages
+		for _, m := range sm.Messages {
+			if m.ID >= next {
+				next = m.ID + 1
+			}
+		}
+	}
+	return pending, next, nil
+}
+
+// Scopes returns the scopes that have messages waiting, sorted.
+func (s *Store) Scopes() []data.Scope {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var scopes []data.Scope
+	for sc := range s.pending {
+		scopes = append(scopes, sc)
+	}
+	sortScopes(scopes)
+	return scopes
+}
+
+// Pending returns the messages waiting to be sent to the scope, oldest first.
+func (s *Store) Pending(sc data.Scope) []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.pending[sc]...)
+}
+
+// Add adds a message to those waiting to be sent to the scope, and saves the
+// Store. The message is kept even if it can't be saved.
+func (s *Store) Add(sc data.Scope, text string) (Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var m Message
+	err := s.change(func(pending map[data.Scope][]Message) {
+		m = Message{ID: s.next, Text: text}
+		pending[sc] = append(pending[sc], m)
+	})
+	if err != nil {
+		m = Message{ID: s.next, Text: text}
+		s.pending[sc] = append(s.pending[sc], m)
+		s.unsaved[sc] = append(s.unsaved[sc], m)
+	}
+	s.next = m.ID + 1
+	return m, err
+}
+
+// Cancel removes the message with the ID from those waiting to be sent to the
+// scope, and saves the Store. It reports whether the message was waiting.
+func (s *Store) Cancel(sc data.Scope, id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found := false
+	err := s.change(func(pending map[data.Scope][]Message) {
+		msgs := pending[sc]
+		for i, m := range msgs {
+			if m.ID != id {
+				continue
+			}
+			msgs = append(msgs[:i:i], msgs[i+1:]...)
+			if len(msgs) == 0 {
+				delete(pending, sc)
+			} else {
+				pending[sc] = msgs
+			}
+			found = true
+			return
+		}
+	})
+	return found && err == nil, err
+}
+
+// Take removes and returns the messages waiting to be sent to the scope,
+// oldest first, and saves the Store. If it can't be saved, the messages are
+// left waiting, so they're sent only once.
+func (s *Store) Take(sc data.Scope) ([]Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var msgs []Message
+	err := s.change(func(pending map[data.Scope][]Message) {
+		msgs = pending[sc]
+		delete(pending, sc)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// change applies f to the messages waiting, as saved in the file if there is
+// one, along with any unsaved ones, and saves them. If they can't be saved,
+// the Store doesn't change.
+// It must be called with mu held.
+func (s *Store) change(f func(pending map[data.Scope][]Message)) error {
+	if s.path == "" {
+		f(s.pending)
+		return nil
+	}
+	var changed map[data.Scope][]Message
+	err := files.Update(s.path, 0600, func(contents []byte) ([]byte, error) {
+		pending, next, err := parse(contents)
+		if err != nil {
+			return nil, err
+		}
+		for sc, msgs := range s.unsaved {
+			pending[sc] = append(pending[sc], msgs...)
+		}
+		if next > s.next {
+			s.next = next
+		}
+		f(pending)
+		changed = pending
+		return marshal(pending)
+	})
+	if err != nil {
+		return err
+	}
+	s.pending = changed
+	s.unsaved = make(map[data.Scope][]Message)
+	return nil
+}
+
+// marshal returns the saved form of the messages.
+func marshal(pending map[data.Scope][]Message) ([]byte, error) {
+	saved := []scopeMessages{}
+	for sc, msgs := range pending {
+		saved = append(saved, scopeMessages{Net: sc.Net, Name: sc.Name, Messages: msgs})
+	}
+	sort.Slice(saved, func(i, j int) bool {
+		if saved[i].Net != saved[j].Net {
+			return saved[i].Net < saved[j].Net
+		}
+		return saved[i].Name < saved[j].Name
+	})
+	return json.MarshalIndent(saved, "", "\t")
+}
+
+func sortScopes(scopes []data.Scope) {
+	sort.Slice(scopes, func(i, j int) bool {
+		if scopes[i].Net != scopes[j].Net {
+			return scopes[i].Net < scopes[j].Net
+		}
+		return scopes[i].Name < scopes[j].Name
+	})
+}
@@ -0,0 +1,144 @@
+package outbox_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cceckman/discoirc/backend/outbox"
+	"github.com/cceckman/discoirc/data"
+)
+
+var (
+	hamlet      = data.Scope{Net: "HamNet", Name: "#hamlet"}
+	battlements = data.Scope{Net: "HamNet", Name: "#battlements"}
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+	s := outbox.New()
+	for _, text := range []string{"who's there?", "nay, answer me", "long live the king"} {
+		if _, err := s.Add(hamlet, text); err != nil {
+			t.Fatalf("unexpected error adding %q: %v", text, err)
+		}
+	}
+	cold, _ := s.Add(battlements, "'tis bitter cold")
+
+	if ok, err := s.Cancel(hamlet, 2); !ok || err != nil {
+		t.Errorf("unexpected result cancelling: got: %v, %v want: true, nil", ok, err)
+	}
+	if ok, _ := s.Cancel(hamlet, cold.ID); ok {
+		t.Errorf("cancelled a message for another scope")
+	}
+	want := []outbox.Message{{ID: 1, Text: "who's there?"}, {ID: 3, Text: "long live the king"}}
+	if diff := cmp.Diff(s.Pending(hamlet), want); diff != "" {
+		t.Errorf("unexpected pending messages: (-got +want)\n%s", diff)
+	}
+	if diff := cmp.Diff(s.Scopes(), []data.Scope{battlements, hamlet}); diff != "" {
+		t.Errorf("unexpected scopes: (-got +want)\n%s", diff)
+	}
+
+	got, err := s.Take(hamlet)
+	if err != nil {
+		t.Fatalf("unexpected error taking messages: %v", err)
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("unexpected messages taken: (-got +want)\n%s", diff)
+	}
+	if got := s.Pending(hamlet); len(got) != 0 {
+		t.Errorf("unexpected pending messages after taking them: %v", got)
+	}
+}
+
+func TestStore_Persist(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "discoirc-outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "discoirc", "outbox")
+
+	s, err := outbox.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error opening missing file: %v", err)
+	}
+	s.Add(hamlet, "who's there?")
+	s.Add(battlements, "'tis bitter cold")
+	s.Add(hamlet, "nay, answer me")
+	s.Take(battlements)
+
+	reopened, err := outbox.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error reopening: %v", err)
+	}
+	want := []outbox.Message{{ID: 1, Text: "who's there?"}, {ID: 3, Text: "nay, answer me"}}
+	if diff := cmp.Diff(reopened.Pending(hamlet), want); diff != "" {
+		t.Errorf("unexpected pending messages: (-got +want)\n%s", diff)
+	}
+	if diff := cmp.Diff(reopened.Scopes(), []data.Scope{hamlet}); diff != "" {
+		t.Errorf("unexpected scopes: (-got +want)\n%s", diff)
+	}
+	// IDs aren't reused.
+	if m, _ := reopened.Add(hamlet, "stand, and unfold yourself"); m.ID != 4 {
+		t.Errorf("unexpected ID: got: %d want: 4", m.ID)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("unexpected mode: got: %v want: %v", info.Mode().Perm(), os.FileMode(0600))
+	}
+}
+
+func TestStore_Shared(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "discoirc-outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "discoirc", "outbox")
+
+	// Each window's process has its own Store, saved to the same file.
+	first, err := outbox.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := outbox.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Add(hamlet, "who's there?")
+	if m, _ := second.Add(battlements, "'tis bitter cold"); m.ID != 2 {
+		t.Errorf("unexpected ID: got: %d want: 2", m.ID)
+	}
+
+	// Only one of them sends each message.
+	got, err := second.Take(hamlet)
+	if err != nil {
+		t.Fatalf("unexpected error taking messages: %v", err)
+	}
+	if diff := cmp.Diff(got, []outbox.Message{{ID: 1, Text: "who's there?"}}); diff != "" {
+		t.Errorf("unexpected messages taken: (-got +want)\n%s", diff)
+	}
+	if got, _ := first.Take(hamlet); len(got) != 0 {
+		t.Errorf("unexpected messages taken again: %v", got)
+	}
+	if diff := cmp.Diff(first.Pending(battlements), []outbox.Message{{ID: 2, Text: "'tis bitter cold"}}); diff != "" {
+		t.Errorf("unexpected pending messages: (-got +want)\n%s", diff)
+	}
+
+	reopened, err := outbox.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(reopened.Scopes(), []data.Scope{battlements}); diff != "" {
+		t.Errorf("unexpected scopes: (-got +want)\n%s", diff)
+	}
+}
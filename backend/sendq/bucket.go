@@ -0,0 +1,67 @@
+package sendq
+
+import (
+	"math"
+	"time"
+)
+
+// Bucket is a token bucket. It holds up to a burst of tokens, and refills at a
+// steady rate; sending a line takes a token.
+type Bucket struct {
+	burst float64
+	// rate is in tokens per second.
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket returns a full Bucket, which holds up to burst tokens and gains
+// rate tokens per second.
+func NewBucket(burst int, rate float64, now time.Time) *Bucket {
+	return &Bucket{
+		burst:  float64(burst),
+		rate:   rate,
+		tokens: float64(burst),
+		last:   now,
+	}
+}
+
+// Set changes the most tokens the Bucket holds, and the rate at which it
+// refills, from now on.
+func (b *Bucket) Set(burst int, rate float64, now time.Time) {
+	b.refill(now)
+	b.burst, b.rate = float64(burst), rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// refill adds the tokens gained since the last refill.
+func (b *Bucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+}
+
+// Wait returns how long after now a token will be available, or 0 if one is.
+func (b *Bucket) Wait(now time.Time) time.Duration {
+	b.refill(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration(math.Ceil((1 - b.tokens) / b.rate * float64(time.Second)))
+}
+
+// Take takes a token, if one is available at now, and reports whether it did.
+func (b *Bucket) Take(now time.Time) bool {
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
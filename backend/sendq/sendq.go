@@ -0,0 +1,173 @@
+// Package sendq queues lines to send to an IRC server, so that the server
+// doesn't disconnect the client for flooding.
+//
+// Each connection has a Queue, which sends lines at the rate a token bucket
+// allows: a burst of lines at once, and then a steady rate. Lines that keep the
+// connection alive, such as PONG, are sent before messages waiting in the
+// queue.
+package sendq
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Priority is the order in which queued lines are sent: lines of a higher
+// Priority are sent first, and lines of the same Priority in the order they
+// were queued.
+type Priority int
+
+const (
+	// Normal lines are messages, and any other commands.
+	Normal Priority = iota
+	// Control lines keep the connection alive and registered.
+	Control
+
+	numPriorities
+)
+
+// control are the commands of Control lines. Commands whose order matters
+// relative to messages, such as JOIN and BATCH, aren't among them.
+var control = map[string]bool{
+	"PONG":         true,
+	"PING":         true,
+	"CAP":          true,
+	"AUTHENTICATE": true,
+	"PASS":         true,
+	"NICK":         true,
+	"USER":         true,
+	"QUIT":         true,
+}
+
+// PriorityOf returns the Priority of the IRC line.
+func PriorityOf(line string) Priority {
+	f := strings.Fields(line)
+	// Skip tags and the prefix.
+	for len(f) > 0 && (strings.HasPrefix(f[0], "@") || strings.HasPrefix(f[0], ":")) {
+		f = f[1:]
+	}
+	if len(f) > 0 && control[strings.ToUpper(f[0])] {
+		return Control
+	}
+	return Normal
+}
+
+// Queue holds lines to send to a server.
+type Queue struct {
+	write func(line string) error
+
+	mu      sync.Mutex
+	bucket  *Bucket
+	pending [numPriorities][]string
+	// ready is signalled when a line is queued, or the limits change.
+	ready chan struct{}
+}
+
+// New returns a Queue that sends lines with write, up to burst at once and
+// then rate lines per second.
+func New(write func(line string) error, burst int, rate float64) *Queue {
+	return &Queue{
+		write:  write,
+		bucket: NewBucket(burst, rate, time.Now()),
+		ready:  make(chan struct{}, 1),
+	}
+}
+
+// SetLimits changes the lines the Queue sends at once, and per second after
+// that, from now on.
+func (q *Queue) SetLimits(burst int, rate float64) {
+	q.mu.Lock()
+	q.bucket.Set(burst, rate, time.Now())
+	q.mu.Unlock()
+	q.wake()
+}
+
+// Send queues the line, without its line ending, to be sent.
+func (q *Queue) Send(line string) {
+	p := PriorityOf(line)
+	q.mu.Lock()
+	q.pending[p] = append(q.pending[p], line)
+	q.mu.Unlock()
+	q.wake()
+}
+
+// wake signals Run that a line is queued, or that the limits changed.
+func (q *Queue) wake() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Len returns the number of lines waiting to be sent.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, lines := range q.pending {
+		n += len(lines)
+	}
+	return n
+}
+
+// Run sends queued lines, as fast as the token bucket allows, until ctx is
+// done or a write fails.
+func (q *Queue) Run(ctx context.Context) error {
+	for {
+		if q.Len() == 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-q.ready:
+			}
+			continue
+		}
+
+		q.mu.Lock()
+		wait := q.bucket.Wait(time.Now())
+		q.mu.Unlock()
+		if wait > 0 {
+			// Wait again if the limits change in the meantime.
+			t := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return ctx.Err()
+			case <-q.ready:
+				t.Stop()
+			case <-t.C:
+			}
+			continue
+		}
+
+		// Take the line only once it can be sent, so a line of higher
+		// priority queued while waiting goes first.
+		line, ok := q.next()
+		if !ok {
+			continue
+		}
+		if err := q.write(line); err != nil {
+			return err
+		}
+	}
+}
+
+// next takes a token and the next line to send, if there is one.
+func (q *Queue) next() (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for p := numPriorities - 1; p >= 0; p-- {
+		if len(q.pending[p]) == 0 {
+			continue
+		}
+		if !q.bucket.Take(time.Now()) {
+			return "", false
+		}
+		line := q.pending[p][0]
+		q.pending[p] = q.pending[p][1:]
+		return line, true
+	}
+	return "", false
+}
@@ -0,0 +1,170 @@
+package sendq_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cceckman/discoirc/backend/sendq"
+)
+
+func TestPriorityOf(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		line string
+		want sendq.Priority
+	}{
+		{"PONG :irc.elsinore.dk", sendq.Control},
+		{"@label=1 pong irc.elsinore.dk", sendq.Control},
+		{":yorick!y@elsinore.dk QUIT :alas", sendq.Control},
+		{"PRIVMSG #hamlet :Who's there?", sendq.Normal},
+		{"JOIN #hamlet", sendq.Normal},
+		{"BATCH +r1 draft/multiline #hamlet", sendq.Normal},
+		{"", sendq.Normal},
+	} {
+		if got := sendq.PriorityOf(tt.line); got != tt.want {
+			t.Errorf("%q: unexpected priority: got: %v want: %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestBucket(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+	b := sendq.NewBucket(2, 0.5, start)
+
+	for i := 0; i < 2; i++ {
+		if !b.Take(start) {
+			t.Fatalf("token %d of burst not available", i)
+		}
+	}
+	if b.Take(start) {
+		t.Errorf("token available after burst")
+	}
+	if got, want := b.Wait(start), 2*time.Second; got != want {
+		t.Errorf("unexpected wait after burst: got: %v want: %v", got, want)
+	}
+	if got, want := b.Wait(start.Add(time.Second)), time.Second; got != want {
+		t.Errorf("unexpected wait 1s after burst: got: %v want: %v", got, want)
+	}
+	if !b.Take(start.Add(2 * time.Second)) {
+		t.Errorf("token not available after refill")
+	}
+	// Refills stop at the burst.
+	later := start.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if !b.Take(later) {
+			t.Fatalf("token %d of burst not available after refill", i)
+		}
+	}
+	if b.Take(later) {
+		t.Errorf("token available beyond burst")
+	}
+}
+
+func TestBucket_Set(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+	b := sendq.NewBucket(4, 0.5, start)
+
+	// A smaller burst drops the tokens beyond it.
+	b.Set(1, 2, start)
+	if !b.Take(start) {
+		t.Fatalf("token not available")
+	}
+	if b.Take(start) {
+		t.Errorf("token available beyond new burst")
+	}
+	if got, want := b.Wait(start), 500*time.Millisecond; got != want {
+		t.Errorf("unexpected wait at new rate: got: %v want: %v", got, want)
+	}
+}
+
+// recorder records the lines written to it.
+type recorder struct {
+	mu    sync.Mutex
+	lines []string
+	done  chan struct{}
+	want  int
+}
+
+func (r *recorder) write(line string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines = append(r.lines, line)
+	if len(r.lines) == r.want {
+		close(r.done)
+	}
+	return nil
+}
+
+func TestQueue(t *testing.T) {
+	t.Parallel()
+	r := &recorder{done: make(chan struct{}), want: 5}
+	q := sendq.New(r.write, 2, 20)
+	for _, line := range []string{
+		"PRIVMSG #hamlet :one",
+		"PRIVMSG #hamlet :two",
+		"PRIVMSG #hamlet :three",
+		"PONG :irc.elsinore.dk",
+		"PRIVMSG #hamlet :four",
+	} {
+		q.Send(line)
+	}
+	if got, want := q.Len(), 5; got != want {
+		t.Errorf("unexpected queue length: got: %d want: %d", got, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	start := time.Now()
+	go q.Run(ctx)
+	select {
+	case <-r.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("lines not sent; got: %v", r.lines)
+	}
+
+	// Two lines are sent at once, and the rest at 20 per second.
+	if elapsed, min := time.Since(start), 140*time.Millisecond; elapsed < min {
+		t.Errorf("lines sent too quickly: took %v, want at least %v", elapsed, min)
+	}
+	want := []string{
+		"PONG :irc.elsinore.dk",
+		"PRIVMSG #hamlet :one",
+		"PRIVMSG #hamlet :two",
+		"PRIVMSG #hamlet :three",
+		"PRIVMSG #hamlet :four",
+	}
+	if diff := cmp.Diff(r.lines, want); diff != "" {
+		t.Errorf("unexpected lines sent: (-got +want)\n%s", diff)
+	}
+}
+
+func TestQueue_SetLimits(t *testing.T) {
+	t.Parallel()
+	r := &recorder{done: make(chan struct{}), want: 2}
+	// After the first line, the next would wait 1000s.
+	q := sendq.New(r.write, 1, 0.001)
+	q.Send("PRIVMSG #hamlet :one")
+	q.Send("PRIVMSG #hamlet :two")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx)
+	time.Sleep(50 * time.Millisecond)
+	if got := q.Len(); got != 1 {
+		t.Errorf("unexpected queue length: got: %d want: 1", got)
+	}
+
+	// The line waiting is sent at the new rate.
+	q.SetLimits(1, 100)
+	select {
+	case <-r.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("line not sent after raising the rate; got: %v", r.lines)
+	}
+}
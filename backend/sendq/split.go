@@ -0,0 +1,63 @@
+package sendq
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxLine is the most bytes in an IRC line, including the CR LF at its end.
+const MaxLine = 512
+
+// DefaultPrefixLen is the length to allow for the prefix the server adds to a
+// message it relays, e.g. ":nick!user@host ", if the client's own prefix isn't
+// known: a long nick, username, and host.
+const DefaultPrefixLen = 1 + 30 + 1 + 10 + 1 + 63 + 1
+
+// PrefixLen returns the length of the prefix the server adds to messages it
+// relays from the client.
+func PrefixLen(nick, user, host string) int {
+	return len(fmt.Sprintf(":%s!%s@%s ", nick, user, host))
+}
+
+// Messages returns the lines that send text to target with the command (e.g.
+// PRIVMSG or NOTICE), split so each line, relayed with a prefix of prefixLen
+// bytes, fits in MaxLine bytes. Empty text is sent as is.
+func Messages(command, target, text string, prefixLen int) []string {
+	head := fmt.Sprintf("%s %s :", command, target)
+	var lines []string
+	for _, part := range Split(text, MaxLine-len("\r\n")-prefixLen-len(head)) {
+		lines = append(lines, head+part)
+	}
+	return lines
+}
+
+// Split splits text into pieces of at most max bytes, which join to make text
+// again. Pieces end after a space if they can, or else between characters.
+// Empty text is one empty piece. If max is less than one character, e.g. if
+// it's negative, each piece is one character.
+func Split(text string, max int) []string {
+	if max < 1 {
+		max = 1
+	}
+	var pieces []string
+	for len(text) > max {
+		i := strings.LastIndexByte(text[:max], ' ') + 1
+		if i == 0 {
+			i = max
+			for i > 0 && !utf8.RuneStart(text[i]) {
+				i--
+			}
+		}
+		if i == 0 {
+			// max is less than one character.
+			_, i = utf8.DecodeRuneInString(text)
+		}
+		pieces = append(pieces, text[:i])
+		text = text[i:]
+	}
+	if text != "" || len(pieces) == 0 {
+		pieces = append(pieces, text)
+	}
+	return pieces
+}
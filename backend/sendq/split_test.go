@@ -0,0 +1,69 @@
+package sendq_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cceckman/discoirc/backend/sendq"
+)
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		text string
+		max  int
+		want []string
+	}{
+		{text: "", max: 10, want: []string{""}},
+		{text: "to be or not to be", max: 18, want: []string{"to be or not to be"}},
+		{text: "to be or not to be", max: 10, want: []string{"to be or ", "not to be"}},
+		{text: "tobeornottobe", max: 5, want: []string{"tobeo", "rnott", "obe"}},
+		{text: "été été", max: 4, want: []string{"ét", "é ", "ét", "é"}},
+		{text: "é", max: 1, want: []string{"é"}},
+		{text: "éa", max: 0, want: []string{"é", "a"}},
+		{text: "éa", max: -10, want: []string{"é", "a"}},
+	} {
+		got := sendq.Split(tt.text, tt.max)
+		if diff := cmp.Diff(got, tt.want); diff != "" {
+			t.Errorf("Split(%q, %d): (-got +want)\n%s", tt.text, tt.max, diff)
+		}
+	}
+}
+
+func TestMessages(t *testing.T) {
+	t.Parallel()
+	prefix := sendq.PrefixLen("yorick", "yorick", "elsinore.dk")
+	text := strings.Repeat("alas poor yorick ", 40)
+	lines := sendq.Messages("PRIVMSG", "#hamlet", text, prefix)
+
+	if len(lines) != 2 {
+		t.Fatalf("unexpected number of lines: got: %d want: 2", len(lines))
+	}
+	var joined string
+	for _, l := range lines {
+		if n := prefix + len(l) + len("\r\n"); n > sendq.MaxLine {
+			t.Errorf("line too long when relayed: %d bytes", n)
+		}
+		if !strings.HasPrefix(l, "PRIVMSG #hamlet :") {
+			t.Errorf("unexpected line: %q", l)
+		}
+		joined += strings.TrimPrefix(l, "PRIVMSG #hamlet :")
+	}
+	if joined != text {
+		t.Errorf("lines don't make up the text: got: %q", joined)
+	}
+	if !strings.HasSuffix(lines[0], " ") {
+		t.Errorf("line not split at a word boundary: %q", lines[0])
+	}
+}
+
+func TestMessages_LongPrefix(t *testing.T) {
+	t.Parallel()
+	target := "#" + strings.Repeat("elsinore", 60)
+	lines := sendq.Messages("PRIVMSG", target, "alas", sendq.DefaultPrefixLen)
+	if got := strings.Join(lines, ""); !strings.Contains(got, "a") || len(lines) != 4 {
+		t.Errorf("unexpected lines: got: %q want: 4, one character each", lines)
+	}
+}
@@ -30,6 +30,7 @@ type Client struct {
 	Nets     map[data.Scope]data.NetworkState
 	Chans    map[data.Scope]data.ChannelState
 	Contents map[data.Scope][]data.Event
+	Errors   []string
 
 	await chan func()
 
@@ -50,6 +51,10 @@ func (c *Client) Receive(e data.Event) {
 		c.updateNetwork(e.ID().Scope, e.NetworkState)
 	case *data.ChannelStateEvent:
 		c.updateChannel(e.ID().Scope, e.ChannelState)
+	case *data.ErrorEvent:
+		c.Join(func() {
+			c.Errors = append(c.Errors, e.Line)
+		})
 	}
 }
 
@@ -2,6 +2,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -10,18 +11,30 @@ import (
 	"github.com/golang/glog"
 	"github.com/marcusolsson/tui-go"
 
+	"github.com/cceckman/discoirc/backend"
 	"github.com/cceckman/discoirc/backend/demo"
+	"github.com/cceckman/discoirc/backend/notify"
+	"github.com/cceckman/discoirc/backend/outbox"
+	"github.com/cceckman/discoirc/config"
 	gctl "github.com/cceckman/discoirc/ui"
+	"github.com/cceckman/discoirc/ui/history"
+	"github.com/cceckman/discoirc/ui/keymap"
 	"github.com/cceckman/discoirc/ui/widgets"
+	"github.com/cceckman/discoirc/ui/wm"
 )
 
 var (
-	help = flag.Bool("help", false, "Display a usage message.")
+	help       = flag.Bool("help", false, "Display a usage message.")
+	configPath = flag.String("config", config.DefaultPath(), "Configuration file to load.")
+	network    = flag.String("network", "", "Network to open at startup. Without -channel, opens the client view.")
+	channel    = flag.String("channel", "", "Channel to open at startup. Requires -network.")
+	windows    = flag.Bool("windows", true, "When running in tmux or screen, open channels in new windows.")
 )
 
 func main() {
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "%s:	 \nUsage:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s [flags] [discoirc://network/#channel]\n", os.Args[0])
 		flag.PrintDefaults()
 	}
 	flag.Parse()
@@ -31,48 +44,118 @@ func main() {
 	}
 	defer glog.Flush()
 
+	target, err := gctl.ParseTarget(*network, *channel, flag.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+	keys, err := keymap.Load(cfg.Keys)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading key bindings:\n%v\n", err)
+		os.Exit(1)
+	}
+
 	ui, err := tui.New(tui.NewHBox())
 	if err != nil {
 		glog.Fatal("error intitializing UI: ", err)
 	}
 
 	ui.SetTheme(getTheme())
-	// TODO: maybe put this in controller?
-	ui.SetWidget(widgets.NewSplash(ui))
+	defer wm.EnablePaste(os.Stdout)()
+	// Launching with a target skips the splash screen.
+	launched := target != gctl.Target{}
+	if !launched {
+		// TODO: maybe put this in controller?
+		ui.SetWidget(widgets.NewSplash(ui))
+	}
 
-	backend := demo.New()
+	be := demo.New()
+	be.SetConfigPath(*configPath)
+	if ob, err := outbox.Open(outbox.DefaultPath()); err != nil {
+		glog.Warningf("can't load outbox; unsent messages won't be saved: %v", err)
+	} else {
+		be.SetOutbox(ob)
+	}
+	notifier := notify.New(cfg.Notify, notify.Exec)
+	notifier.SetBell(os.Stdout)
+	if os.Getenv("TMUX") != "" {
+		notifier.SetAlert((&wm.Tmux{Run: wm.Exec}).Alert)
+	}
+	be.SetNotifier(notifier)
+	backend.Apply(be, config.Diff(&config.Config{}, cfg))
+	// The search index starts from the events the backend has logged.
+	be.Reindex()
+	go watchConfig(context.Background(), *configPath, cfg, be)
 
-	ctl := gctl.New(ui, backend)
+	ctl := gctl.New(ui, be)
+	ctl.SetKeymap(keys)
+	ctl.SetMembership(cfg.Membership)
+	ctl.SetDisplay(cfg.Display)
+	if hist, err := history.Open(history.DefaultPath(), history.DefaultSize); err != nil {
+		glog.Warningf("can't load input history; it won't be saved: %v", err)
+	} else {
+		ctl.SetHistory(hist)
+	}
+	ctl.SetTitler(wm.NewTitler(os.Getenv, os.Stdout, wm.Exec))
+	if *windows {
+		ctl.SetLauncher(wm.Detect(os.Getenv, launchCommand(*configPath), wm.Exec))
+	}
 
 	go func() {
-		time.Sleep(2 * time.Second)
+		if !launched {
+			time.Sleep(2 * time.Second)
+		}
 		ctl.Update(func() {
-			ctl.ActivateClient()
+			ctl.Open(target)
 		})
 
-		toggle := &Toggle{
-			Demo:     backend,
-			Net:      "Barnetic",
-			Chan:     "#discoirc",
-			Duration: 2 * time.Second,
+		var toggles []*Toggle
+		for _, n := range cfg.Networks {
+			for _, c := range n.Channels {
+				if !c.AutoJoin {
+					continue
+				}
+				toggles = append(toggles, &Toggle{
+					Demo:     be,
+					Net:      n.Name,
+					Chan:     c.Name,
+					Duration: 2 * time.Second,
+				})
+			}
 		}
 
 		ctl.Update(func() {
-			ui.SetKeybinding("Ctrl+R", func() {
-				glog.V(1).Info("toggling network cycling")
-				toggle.network()
-			})
-			ui.SetKeybinding("Ctrl+F", func() {
-				glog.V(1).Info("toggling channel cycling")
-				toggle.channel()
-			})
-			ui.SetKeybinding("Ctrl+V", func() {
-				glog.V(1).Info("toggling message cycling")
-				toggle.messages()
-			})
-			toggle.network()
-			toggle.channel()
-			toggle.messages()
+			for _, b := range []struct {
+				action keymap.Action
+				desc   string
+				toggle func(t *Toggle)
+			}{
+				{keymap.DemoNetwork, "network", (*Toggle).network},
+				{keymap.DemoChannel, "channel", (*Toggle).channel},
+				{keymap.DemoMessages, "message", (*Toggle).messages},
+			} {
+				b := b
+				for _, key := range keys.Keys(keymap.Global, b.action) {
+					ui.SetKeybinding(key, func() {
+						glog.V(1).Infof("toggling %s cycling", b.desc)
+						for _, t := range toggles {
+							b.toggle(t)
+						}
+					})
+				}
+			}
+			for _, t := range toggles {
+				t.network()
+				t.channel()
+				t.messages()
+			}
 		})
 	}()
 
@@ -2,21 +2,139 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"os"
 	"time"
 
+	"github.com/golang/glog"
 	"github.com/marcusolsson/tui-go"
 
+	"github.com/cceckman/discoirc/backend"
 	"github.com/cceckman/discoirc/backend/demo"
+	"github.com/cceckman/discoirc/config"
 )
 
+// demoConfig is used if there is no configuration file at the default path.
+const demoConfig = `
+[network "Barnetic"]
+server = irc.barnetic.example
+nick = discobot
+
+[channel "Barnetic" "#discoirc"]
+`
+
+// loadConfig loads the configuration file at path.
+// If the -config flag wasn't provided and the default file doesn't exist,
+// it returns the demo configuration instead.
+func loadConfig(path string) (*config.Config, error) {
+	cfg, err := config.Load(path)
+	if !os.IsNotExist(err) {
+		return cfg, err
+	}
+
+	explicit := false
+	flag.Visit(func(f *flag.Flag) {
+		explicit = explicit || f.Name == "config"
+	})
+	if explicit {
+		return nil, err
+	}
+	glog.Infof("no configuration at %s; using demo configuration", path)
+	return config.Parse("demo", demoConfig)
+}
+
+// watchConfig applies changes in the configuration file to the backend.
+// If the new configuration is invalid, it reports the error to the backend's
+// subscriber and keeps running with the old configuration.
+func watchConfig(ctx context.Context, path string, cfg *config.Config, be *demo.Demo) {
+	config.Watch(ctx, path, time.Second, func(next *config.Config, err error) {
+		if err != nil {
+			glog.Errorf("not reloading configuration: %v", err)
+			be.ReportError(fmt.Errorf("configuration not reloaded: %v", err))
+			return
+		}
+		changes := config.Diff(cfg, next)
+		for _, c := range changes {
+			glog.V(1).Infof("configuration change: %v", c)
+		}
+		backend.Apply(be, changes)
+		cfg = next
+	})
+}
+
+// launchCommand returns the command line for discoirc processes opened in new
+// windows.
+func launchCommand(configPath string) []string {
+	self, err := os.Executable()
+	if err != nil {
+		self = os.Args[0]
+	}
+	return []string{self, "-config", configPath}
+}
+
 func getTheme() *tui.Theme {
 	t := tui.NewTheme()
 	t.SetStyle("reversed", tui.Style{
 		Reverse: tui.DecorationOn,
 	})
+	t.SetStyle("pending", tui.Style{
+		Fg:   tui.ColorBlack,
+		Bold: tui.DecorationOn,
+	})
+	t.SetStyle("match", tui.Style{
+		Reverse: tui.DecorationOn,
+	})
+	t.SetStyle("ignored", tui.Style{
+		Fg: tui.ColorBlack,
+	})
+	t.SetStyle("event.time", tui.Style{
+		Fg: tui.ColorCyan,
+	})
+	t.SetStyle("event.nick", tui.Style{
+		Bold: tui.DecorationOn,
+	})
+	t.SetStyle("event.own", tui.Style{
+		Fg: tui.ColorYellow,
+	})
+	t.SetStyle("event.action", tui.Style{
+		Fg: tui.ColorMagenta,
+	})
+	t.SetStyle("event.notice", tui.Style{
+		Fg: tui.ColorBlue,
+	})
+	t.SetStyle("event.membership", tui.Style{
+		Fg: tui.ColorGreen,
+	})
+	t.SetStyle("format.bold", tui.Style{
+		Bold: tui.DecorationOn,
+	})
+	// The terminal can't show italics, so they're underlined.
+	t.SetStyle("format.italic", tui.Style{
+		Underline: tui.DecorationOn,
+	})
+	t.SetStyle("format.underline", tui.Style{
+		Underline: tui.DecorationOn,
+	})
+	t.SetStyle("format.reverse", tui.Style{
+		Reverse: tui.DecorationOn,
+	})
+	for code, c := range formatColors {
+		t.SetStyle(fmt.Sprintf("format.fg.%d", code), tui.Style{Fg: c})
+		t.SetStyle(fmt.Sprintf("format.bg.%d", code), tui.Style{Bg: c})
+	}
 	return t
 }
 
+// formatColors are the terminal colors closest to those numbered 0 to 15 in
+// messages' formatting, to which data.ParseFormatting matches the others.
+var formatColors = [...]tui.Color{
+	tui.ColorWhite, tui.ColorBlack, tui.ColorBlue, tui.ColorGreen,
+	tui.ColorRed, tui.ColorRed, tui.ColorMagenta, tui.ColorYellow,
+	tui.ColorYellow, tui.ColorGreen, tui.ColorCyan, tui.ColorCyan,
+	tui.ColorBlue, tui.ColorMagenta, tui.ColorBlack, tui.ColorWhite,
+}
+
 // Toggle is a stub.Channel wrapper that toggles message / metadata updates.
 type Toggle struct {
 	Net, Chan string
@@ -0,0 +1,641 @@
+// Package config loads discoirc's configuration: the networks to connect to,
+// and the channels to join on them.
+//
+// The configuration file is made up of sections, each of which starts with a
+// header line in square brackets and continues with "key = value" entries.
+// Lines starting with '#' or ';' are comments. Values may be double-quoted to
+// preserve leading or trailing spaces; within quotes, a backslash escapes the
+// following character.
+//
+//	# Networks are named by the user; the name is used only within discoirc.
+//	[network "Barnetic"]
+//	server = irc.barnetic.org:6697
+//	server = irc2.barnetic.org
+//	tls = true
+//	nick = discobot
+//	alt_nick = discobot_
+//	realname = "Disco Bot"
+//	sasl_user = discobot
+//	sasl_password_command = pass show irc/barnetic
+//
+//	# Channels are named by the network they are on, and their own name.
+//	[channel "Barnetic" "#discoirc"]
+//	key = sesame
+//
+// See CONFIGURATION.md in the repository root for a description of each key.
+package config
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Default ports, used when a server entry doesn't specify one.
+const (
+	DefaultPort    = 6667
+	DefaultTLSPort = 6697
+)
+
+// Default flood control: a burst of lines, and then one line every two seconds.
+const (
+	DefaultFloodBurst = 5
+	DefaultFloodRate  = 0.5
+)
+
+// Config is the full discoirc configuration.
+type Config struct {
+	// Networks are listed in the order they appear in the file.
+	Networks []*Network
+	// Keys configures key bindings.
+	Keys Keys
+	// Highlights are the rules for highlighting messages, in the order they
+	// appear in the file.
+	Highlights []*Highlight
+	// Ignores are the rules for ignoring users, in the order they appear in
+	// the file.
+	Ignores []*Ignore
+	// Notify configures notifications.
+	Notify Notify
+	// Membership configures how joins, parts, and quits are shown.
+	Membership Membership
+	// Display configures how channel views show events.
+	Display Display
+}
+
+// Network returns the configuration of the named network, or nil if there is
+// no such network.
+func (c *Config) Network(name string) *Network {
+	for _, n := range c.Networks {
+		if n.Name == name {
+			return n
+		}
+	}
+	return nil
+}
+
+// Network is the configuration of a connection to an IRC network.
+type Network struct {
+	Name string
+
+	// Servers are tried in order when connecting.
+	Servers []Server
+	TLS     bool
+
+	Nick string
+	// AltNicks are tried in order if Nick is unavailable.
+	AltNicks []string
+	// User is the username to connect with. If empty, Nick is used.
+	User     string
+	RealName string
+	// Password is the server password (PASS), if any.
+	Password Secret
+
+	// SASL configures SASL authentication, or is nil if it is not used.
+	SASL *SASL
+
+	// PersistJoins indicates that channels joined or parted, and nick
+	// changes made, while discoirc is running should be saved to the
+	// configuration file.
+	PersistJoins bool
+
+	// FloodBurst is the most lines sent to the network at once, and
+	// FloodRate the lines per second sent after that. If they're zero, the
+	// defaults are used.
+	FloodBurst int
+	FloodRate  float64
+
+	Channels []*Channel
+}
+
+// Flood returns the limits on the lines sent to the network: the most sent at
+// once, and the lines per second after that.
+func (n *Network) Flood() (burst int, rate float64) {
+	burst, rate = n.FloodBurst, n.FloodRate
+	if burst == 0 {
+		burst = DefaultFloodBurst
+	}
+	if rate == 0 {
+		rate = DefaultFloodRate
+	}
+	return burst, rate
+}
+
+// Clone returns a deep copy of the Network.
+func (n *Network) Clone() *Network {
+	r := *n
+	r.Servers = append([]Server(nil), n.Servers...)
+	r.AltNicks = append([]string(nil), n.AltNicks...)
+	if n.SASL != nil {
+		sasl := *n.SASL
+		r.SASL = &sasl
+	}
+	r.Channels = nil
+	for _, c := range n.Channels {
+		ch := *c
+		r.Channels = append(r.Channels, &ch)
+	}
+	return &r
+}
+
+// Channel returns the configuration of the named channel, or nil if there is
+// no such channel on this network.
+func (n *Network) Channel(name string) *Channel {
+	for _, c := range n.Channels {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Server is a single server of an IRC network.
+type Server struct {
+	Host string
+	Port int
+}
+
+// String returns the host:port address of the server.
+func (s Server) String() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+}
+
+// SASL mechanisms supported by discoirc.
+const (
+	SASLPlain    = "PLAIN"
+	SASLExternal = "EXTERNAL"
+)
+
+// SASL is the configuration of SASL authentication for a network.
+type SASL struct {
+	Mechanism string
+	User      string
+	Password  Secret
+}
+
+// Channel is the configuration of a channel on a network.
+type Channel struct {
+	Name string
+	// Key is the channel key (password) used to join, if any.
+	Key string
+	// AutoJoin indicates the channel should be joined upon connecting.
+	AutoJoin bool
+}
+
+// DefaultPath returns the location of the configuration file used if none is
+// specified: $XDG_CONFIG_HOME/discoirc/config, or ~/.config/discoirc/config.
+func DefaultPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	return filepath.Join(dir, "discoirc", "config")
+}
+
+// Load reads and validates the configuration file at path.
+// If the file has errors, the returned error is an ErrorList.
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	doc, errs, err := parseDocument(path, f)
+	if err != nil {
+		return nil, err
+	}
+	return doc.config(errs)
+}
+
+// Parse reads and validates a configuration from a string.
+// name is used as the file name in error messages.
+func Parse(name, contents string) (*Config, error) {
+	doc, errs, err := parseDocument(name, strings.NewReader(contents))
+	if err != nil {
+		return nil, err
+	}
+	return doc.config(errs)
+}
+
+// config builds and validates the Config described by the document.
+// Errors are appended to errs.
+func (d *document) config(errs ErrorList) (*Config, error) {
+	cfg := &Config{}
+	defined := make(map[string]Position)
+	// keysPos, notifyPos, membershipPos and displayPos are where the keys,
+	// notify, membership and display sections are, once they're seen.
+	var keysPos, notifyPos, membershipPos, displayPos Position
+	// highlights and ignores are where the highlight and ignore sections
+	// for each scope are.
+	highlights := make(map[string]Position)
+	ignores := make(map[string]Position)
+
+	// Networks first, so that channels may be defined anywhere in the file.
+	for _, s := range d.sections {
+		if s.header.section != "network" {
+			continue
+		}
+		if len(s.header.args) != 1 {
+			errs.add(s.header.pos, "network section needs exactly one name, e.g. [network \"name\"]")
+			continue
+		}
+		n := &Network{Name: s.header.args[0]}
+		if n.Name == "" {
+			errs.add(s.header.pos, "network name must not be empty")
+			continue
+		}
+		if pos, ok := defined[n.Name]; ok {
+			errs.add(s.header.pos, "network %q is already defined at %s", n.Name, pos)
+			continue
+		}
+		defined[n.Name] = s.header.pos
+
+		applyEntries(s, networkKeys, n, &errs)
+		n.validate(s.header.pos, &errs)
+		cfg.Networks = append(cfg.Networks, n)
+	}
+
+	for _, s := range d.sections {
+		switch s.header.section {
+		case "network":
+			// Handled above.
+		case "channel":
+			if len(s.header.args) != 2 {
+				errs.add(s.header.pos, "channel section needs a network and a channel name, e.g. [channel \"network\" \"#channel\"]")
+				continue
+			}
+			n := cfg.Network(s.header.args[0])
+			if n == nil {
+				errs.add(s.header.pos, "channel %q is on undefined network %q", s.header.args[1], s.header.args[0])
+				continue
+			}
+			c := &Channel{Name: s.header.args[1], AutoJoin: true}
+			if !validChannel(c.Name) {
+				errs.add(s.header.pos, "invalid channel name %q", c.Name)
+				continue
+			}
+			if n.Channel(c.Name) != nil {
+				errs.add(s.header.pos, "channel %q is already defined for network %q", c.Name, n.Name)
+				continue
+			}
+			applyEntries(s, channelKeys, c, &errs)
+			n.Channels = append(n.Channels, c)
+		case "keys":
+			if len(s.header.args) != 0 {
+				errs.add(s.header.pos, "keys section takes no arguments, e.g. [keys]")
+				continue
+			}
+			if keysPos.Line != 0 {
+				errs.add(s.header.pos, "keys section is already defined at %s", keysPos)
+				continue
+			}
+			keysPos = s.header.pos
+			applyEntries(s, keysKeys, &cfg.Keys, &errs)
+		case "notify":
+			if len(s.header.args) != 0 {
+				errs.add(s.header.pos, "notify section takes no arguments, e.g. [notify]")
+				continue
+			}
+			if notifyPos.Line != 0 {
+				errs.add(s.header.pos, "notify section is already defined at %s", notifyPos)
+				continue
+			}
+			notifyPos = s.header.pos
+			applyEntries(s, notifyKeys, &cfg.Notify, &errs)
+		case "membership":
+			if len(s.header.args) != 0 {
+				errs.add(s.header.pos, "membership section takes no arguments, e.g. [membership]")
+				continue
+			}
+			if membershipPos.Line != 0 {
+				errs.add(s.header.pos, "membership section is already defined at %s", membershipPos)
+				continue
+			}
+			membershipPos = s.header.pos
+			applyEntries(s, membershipKeys, &cfg.Membership, &errs)
+		case "display":
+			if len(s.header.args) != 0 {
+				errs.add(s.header.pos, "display section takes no arguments, e.g. [display]")
+				continue
+			}
+			if displayPos.Line != 0 {
+				errs.add(s.header.pos, "display section is already defined at %s", displayPos)
+				continue
+			}
+			displayPos = s.header.pos
+			applyEntries(s, displayKeys, &cfg.Display, &errs)
+		case "highlight":
+			network, channel, ok := scopeArgs(s, &errs)
+			if !ok {
+				continue
+			}
+			h := &Highlight{Network: network, Channel: channel}
+			if pos, ok := highlights[h.String()]; ok {
+				errs.add(s.header.pos, "%s section is already defined at %s", h, pos)
+				continue
+			}
+			highlights[h.String()] = s.header.pos
+			applyEntries(s, highlightKeys, h, &errs)
+			cfg.Highlights = append(cfg.Highlights, h)
+		case "ignore":
+			network, channel, ok := scopeArgs(s, &errs)
+			if !ok {
+				continue
+			}
+			i := &Ignore{Network: network, Channel: channel}
+			if pos, ok := ignores[i.String()]; ok {
+				errs.add(s.header.pos, "%s section is already defined at %s", i, pos)
+				continue
+			}
+			ignores[i.String()] = s.header.pos
+			applyEntries(s, ignoreKeys, i, &errs)
+			cfg.Ignores = append(cfg.Ignores, i)
+		default:
+			errs.add(s.header.pos, "unknown section type %q", s.header.section)
+		}
+	}
+
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// scopeArgs returns the network and channel, either of which may be empty, of
+// a section that applies everywhere, on one network, or in one channel, e.g.
+// [highlight "network" "#channel"].
+func scopeArgs(s *section, errs *ErrorList) (network, channel string, ok bool) {
+	kind, args := s.header.section, s.header.args
+	if len(args) > 2 {
+		errs.add(s.header.pos, "%s section takes at most a network and a channel name, e.g. [%s \"network\" \"#channel\"]", kind, kind)
+		return "", "", false
+	}
+	if len(args) > 0 {
+		network = args[0]
+		if network == "" {
+			errs.add(s.header.pos, "network name must not be empty")
+			return "", "", false
+		}
+	}
+	if len(args) > 1 {
+		channel = args[1]
+		if !validChannel(channel) {
+			errs.add(s.header.pos, "invalid channel name %q", channel)
+			return "", "", false
+		}
+	}
+	return network, channel, true
+}
+
+// validate checks the Network for errors that aren't specific to one entry.
+func (n *Network) validate(pos Position, errs *ErrorList) {
+	if len(n.Servers) == 0 {
+		errs.add(pos, "network %q has no servers", n.Name)
+	}
+	// Fill in ports now that TLS is known.
+	for i := range n.Servers {
+		if n.Servers[i].Port == 0 {
+			n.Servers[i].Port = DefaultPort
+			if n.TLS {
+				n.Servers[i].Port = DefaultTLSPort
+			}
+		}
+	}
+	if n.Nick == "" {
+		errs.add(pos, "network %q has no nick", n.Name)
+	}
+	if err := n.Password.validate("password, password_command, and password_file"); err != nil {
+		errs.add(pos, "network %q: %v", n.Name, err)
+	}
+	if n.SASL != nil {
+		if err := n.SASL.Password.validate("sasl_password, sasl_password_command, and sasl_password_file"); err != nil {
+			errs.add(pos, "network %q: %v", n.Name, err)
+		}
+		switch n.SASL.Mechanism {
+		case "":
+			n.SASL.Mechanism = SASLPlain
+			fallthrough
+		case SASLPlain:
+			if n.SASL.User == "" || n.SASL.Password.IsZero() {
+				errs.add(pos, "network %q: SASL %s needs sasl_user and sasl_password", n.Name, SASLPlain)
+			}
+		case SASLExternal:
+			if !n.TLS {
+				errs.add(pos, "network %q: SASL %s needs tls", n.Name, SASLExternal)
+			}
+		}
+	}
+}
+
+// key describes how to apply an entry to a section's configuration.
+type key struct {
+	// repeated keys may appear more than once in a section.
+	repeated bool
+	set      func(target interface{}, value string) error
+}
+
+var networkKeys = map[string]key{
+	"server": {repeated: true, set: func(t interface{}, v string) error {
+		s, err := parseServer(v)
+		if err != nil {
+			return err
+		}
+		n := t.(*Network)
+		n.Servers = append(n.Servers, s)
+		return nil
+	}},
+	"tls": {set: func(t interface{}, v string) error {
+		return parseBool(v, &t.(*Network).TLS)
+	}},
+	"nick": {set: func(t interface{}, v string) error {
+		return parseNick(v, &t.(*Network).Nick)
+	}},
+	"alt_nick": {repeated: true, set: func(t interface{}, v string) error {
+		n := t.(*Network)
+		var nick string
+		if err := parseNick(v, &nick); err != nil {
+			return err
+		}
+		n.AltNicks = append(n.AltNicks, nick)
+		return nil
+	}},
+	"user": {set: func(t interface{}, v string) error {
+		if strings.ContainsAny(v, " @") {
+			return fmt.Errorf("invalid user %q", v)
+		}
+		t.(*Network).User = v
+		return nil
+	}},
+	"realname": {set: func(t interface{}, v string) error {
+		t.(*Network).RealName = v
+		return nil
+	}},
+	"password": {set: func(t interface{}, v string) error {
+		t.(*Network).Password.Value = v
+		return nil
+	}},
+	"password_command": {set: func(t interface{}, v string) error {
+		t.(*Network).Password.Command = v
+		return nil
+	}},
+	"password_file": {set: func(t interface{}, v string) error {
+		t.(*Network).Password.File = v
+		return nil
+	}},
+	"sasl_mechanism": {set: func(t interface{}, v string) error {
+		v = strings.ToUpper(v)
+		if v != SASLPlain && v != SASLExternal {
+			return fmt.Errorf("unsupported SASL mechanism %q; want %s or %s", v, SASLPlain, SASLExternal)
+		}
+		sasl(t).Mechanism = v
+		return nil
+	}},
+	"sasl_user": {set: func(t interface{}, v string) error {
+		sasl(t).User = v
+		return nil
+	}},
+	"sasl_password": {set: func(t interface{}, v string) error {
+		sasl(t).Password.Value = v
+		return nil
+	}},
+	"sasl_password_command": {set: func(t interface{}, v string) error {
+		sasl(t).Password.Command = v
+		return nil
+	}},
+	"sasl_password_file": {set: func(t interface{}, v string) error {
+		sasl(t).Password.File = v
+		return nil
+	}},
+	"persist_joins": {set: func(t interface{}, v string) error {
+		return parseBool(v, &t.(*Network).PersistJoins)
+	}},
+	"flood_burst": {set: func(t interface{}, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return fmt.Errorf("expected a positive number of lines, got %q", v)
+		}
+		t.(*Network).FloodBurst = n
+		return nil
+	}},
+	"flood_rate": {set: func(t interface{}, v string) error {
+		r, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(r > 0) || math.IsInf(r, 0) {
+			return fmt.Errorf("expected a positive number of lines per second, got %q", v)
+		}
+		t.(*Network).FloodRate = r
+		return nil
+	}},
+}
+
+// sasl returns the SASL configuration of the *Network t, creating it if
+// necessary.
+func sasl(t interface{}) *SASL {
+	n := t.(*Network)
+	if n.SASL == nil {
+		n.SASL = &SASL{}
+	}
+	return n.SASL
+}
+
+var channelKeys = map[string]key{
+	"key": {set: func(t interface{}, v string) error {
+		if strings.ContainsAny(v, " ,") {
+			return fmt.Errorf("channel key must not contain spaces or commas")
+		}
+		t.(*Channel).Key = v
+		return nil
+	}},
+	"autojoin": {set: func(t interface{}, v string) error {
+		return parseBool(v, &t.(*Channel).AutoJoin)
+	}},
+}
+
+// applyEntries sets the values of the section's entries on target.
+func applyEntries(s *section, keys map[string]key, target interface{}, errs *ErrorList) {
+	seen := make(map[string]Position)
+	for _, e := range s.entries {
+		k, ok := keys[e.key]
+		if !ok {
+			errs.add(e.pos, "unknown key %q in %s section", e.key, s.header.section)
+			continue
+		}
+		if pos, ok := seen[e.key]; ok && !k.repeated {
+			errs.add(e.pos, "%q is already set at %s", e.key, pos)
+			continue
+		}
+		seen[e.key] = e.pos
+		if err := k.set(target, e.value); err != nil {
+			errs.add(e.pos, "%s: %v", e.key, err)
+		}
+	}
+}
+
+func parseBool(v string, out *bool) error {
+	switch strings.ToLower(v) {
+	case "true", "yes", "on", "1":
+		*out = true
+	case "false", "no", "off", "0":
+		*out = false
+	default:
+		return fmt.Errorf("expected true or false, got %q", v)
+	}
+	return nil
+}
+
+// parseServer parses a "host" or "host:port" server address.
+func parseServer(v string) (Server, error) {
+	host, port := v, ""
+	if h, p, err := net.SplitHostPort(v); err == nil {
+		host, port = h, p
+	}
+	if host == "" || strings.ContainsAny(host, " /") {
+		return Server{}, fmt.Errorf("invalid server address %q", v)
+	}
+	s := Server{Host: host}
+	if port != "" {
+		p, err := strconv.Atoi(port)
+		if err != nil || p < 1 || p > 65535 {
+			return Server{}, fmt.Errorf("invalid port %q", port)
+		}
+		s.Port = p
+	}
+	return s, nil
+}
+
+// CheckNick returns an error if nick is not a valid IRC nickname.
+func CheckNick(nick string) error {
+	var out string
+	return parseNick(nick, &out)
+}
+
+// parseNick checks that v is a valid IRC nickname (RFC 2812 section 2.3.1).
+func parseNick(v string, out *string) error {
+	if v == "" {
+		return fmt.Errorf("nick must not be empty")
+	}
+	for i, r := range v {
+		letter := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+		special := strings.ContainsRune("[]\\`_^{|}", r)
+		digit := r >= '0' && r <= '9'
+		if letter || special || (i > 0 && (digit || r == '-')) {
+			continue
+		}
+		return fmt.Errorf("invalid nick %q", v)
+	}
+	*out = v
+	return nil
+}
+
+// validChannel reports whether name is a valid channel name
+// (RFC 2812 section 1.3).
+func validChannel(name string) bool {
+	if len(name) < 2 || !strings.ContainsRune("#&+!", rune(name[0])) {
+		return false
+	}
+	return !strings.ContainsAny(name, " ,\a:")
+}
@@ -0,0 +1,517 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cceckman/discoirc/config"
+)
+
+const hamlet = `
+# Elsinore's finest.
+[network "HamNet"]
+server = irc.elsinore.dk:6697
+server = irc2.elsinore.dk
+tls = yes
+nick = yorick
+alt_nick = yorick_
+realname = "  Alas, poor Yorick  "
+sasl_user = yorick
+sasl_password = "I knew him, \"Horatio\""
+
+; Channels may come before or after their network.
+[channel "HamNet" "#hamlet"]
+key = rosemary
+
+[channel "HamNet" "#battlements"]
+autojoin = false
+
+[network "Globe"]
+server = irc.globe.example
+nick = will
+flood_burst = 10
+flood_rate = 1.5
+`
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	got, err := config.Parse("hamlet.conf", hamlet)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := &config.Config{
+		Networks: []*config.Network{
+			{
+				Name: "HamNet",
+				Servers: []config.Server{
+					{Host: "irc.elsinore.dk", Port: 6697},
+					{Host: "irc2.elsinore.dk", Port: config.DefaultTLSPort},
+				},
+				TLS:      true,
+				Nick:     "yorick",
+				AltNicks: []string{"yorick_"},
+				RealName: "  Alas, poor Yorick  ",
+				SASL: &config.SASL{
+					Mechanism: config.SASLPlain,
+					User:      "yorick",
+					Password:  config.Secret{Value: `I knew him, "Horatio"`},
+				},
+				Channels: []*config.Channel{
+					{Name: "#hamlet", Key: "rosemary", AutoJoin: true},
+					{Name: "#battlements", AutoJoin: false},
+				},
+			},
+			{
+				Name:       "Globe",
+				Servers:    []config.Server{{Host: "irc.globe.example", Port: config.DefaultPort}},
+				Nick:       "will",
+				FloodBurst: 10,
+				FloodRate:  1.5,
+			},
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("unexpected config: (-got +want)\n%s", diff)
+	}
+
+	if burst, rate := got.Networks[0].Flood(); burst != config.DefaultFloodBurst || rate != config.DefaultFloodRate {
+		t.Errorf("unexpected default flood control: got: %d, %v want: %d, %v", burst, rate, config.DefaultFloodBurst, config.DefaultFloodRate)
+	}
+}
+
+func TestParse_Keys(t *testing.T) {
+	t.Parallel()
+	got, err := config.Parse("keys.conf", `
+[keys]
+preset = vim
+bind = channel:normal Ctrl+X Ctrl+C quit
+bind = client   q   quit
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := config.Keys{
+		Preset: "vim",
+		Bindings: []config.Binding{
+			{View: "channel", Mode: "normal", Keys: []string{"Ctrl+X", "Ctrl+C"}, Action: "quit"},
+			{View: "client", Keys: []string{"q"}, Action: "quit"},
+		},
+	}
+	if diff := cmp.Diff(got.Keys, want); diff != "" {
+		t.Errorf("unexpected keys: (-got +want)\n%s", diff)
+	}
+}
+
+func TestParse_Highlights(t *testing.T) {
+	t.Parallel()
+	got, err := config.Parse("highlight.conf", `
+[highlight]
+word = Denmark
+regex = (?i)\bghost(ly)?\b
+ignore = polonius
+
+[highlight "HamNet" "#battlements"]
+nick = no
+word = "the King"
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	no := false
+	want := []*config.Highlight{
+		{
+			Words:    []string{"Denmark"},
+			Patterns: []string{`(?i)\bghost(ly)?\b`},
+			Ignore:   []string{"polonius"},
+		},
+		{
+			Network: "HamNet",
+			Channel: "#battlements",
+			Nick:    &no,
+			Words:   []string{"the King"},
+		},
+	}
+	if diff := cmp.Diff(got.Highlights, want); diff != "" {
+		t.Errorf("unexpected highlights: (-got +want)\n%s", diff)
+	}
+}
+
+func TestParse_Ignores(t *testing.T) {
+	t.Parallel()
+	got, err := config.Parse("ignore.conf", `
+[ignore]
+mask = *!*@spam.example.net
+account = spambot
+
+[ignore "HamNet" "#hamlet"]
+mask = polonius
+regex = (?i)\barras\b
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []*config.Ignore{
+		{
+			Masks:    []string{"*!*@spam.example.net"},
+			Accounts: []string{"spambot"},
+		},
+		{
+			Network:  "HamNet",
+			Channel:  "#hamlet",
+			Masks:    []string{"polonius"},
+			Patterns: []string{`(?i)\barras\b`},
+		},
+	}
+	if diff := cmp.Diff(got.Ignores, want); diff != "" {
+		t.Errorf("unexpected ignores: (-got +want)\n%s", diff)
+	}
+}
+
+func TestParse_Membership(t *testing.T) {
+	t.Parallel()
+	got, err := config.Parse("membership.conf", `
+[membership]
+hide_idle = 15m
+collapse = yes
+fold_netsplits = true
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := config.Membership{
+		HideIdle:      15 * time.Minute,
+		Collapse:      true,
+		FoldNetsplits: true,
+	}
+	if diff := cmp.Diff(got.Membership, want); diff != "" {
+		t.Errorf("unexpected membership: (-got +want)\n%s", diff)
+	}
+}
+
+func TestParse_Display(t *testing.T) {
+	t.Parallel()
+	got, err := config.Parse("display.conf", `
+[display]
+time_format = "15:04:05"
+strip_colors = true
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := config.Display{TimeFormat: "15:04:05", StripColors: true}
+	if diff := cmp.Diff(got.Display, want); diff != "" {
+		t.Errorf("unexpected display: (-got +want)\n%s", diff)
+	}
+}
+
+func TestParse_Notify(t *testing.T) {
+	t.Parallel()
+	got, err := config.Parse("notify.conf", `
+[notify]
+bell = yes
+tmux = yes
+command = "logger -t discoirc"
+desktop_command = notify-send -a discoirc
+private = no
+per_minute = 2
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	no := false
+	want := config.Notify{
+		Bell:           true,
+		Tmux:           true,
+		Command:        "logger -t discoirc",
+		DesktopCommand: "notify-send -a discoirc",
+		Private:        &no,
+		PerMinute:      2,
+	}
+	if diff := cmp.Diff(got.Notify, want); diff != "" {
+		t.Errorf("unexpected notify: (-got +want)\n%s", diff)
+	}
+	if got.Notify.NotifyPrivate() || got.Notify.Limit() != 2 {
+		t.Errorf("unexpected settings: got: private %v, limit %d want: no private, limit 2", got.Notify.NotifyPrivate(), got.Notify.Limit())
+	}
+
+	var defaults config.Notify
+	if !defaults.NotifyPrivate() || defaults.Limit() != config.DefaultNotifyPerMinute {
+		t.Errorf("unexpected defaults: got: private %v, limit %d want: private, limit %d", defaults.NotifyPrivate(), defaults.Limit(), config.DefaultNotifyPerMinute)
+	}
+}
+
+var errorTests = []struct {
+	test     string
+	contents string
+	// want are the expected errors, in order.
+	want []string
+}{
+	{
+		test:     "entry outside section",
+		contents: "nick = yorick\n",
+		want:     []string{`test.conf:1: "nick" is not in a section`},
+	},
+	{
+		test: "bad header",
+		contents: `[network HamNet]
+server = irc.elsinore.dk
+[network "HamNet"
+`,
+		want: []string{
+			`test.conf:1: section arguments must be quoted: HamNet`,
+			`test.conf:3: section header is missing a closing ']'`,
+		},
+	},
+	{
+		test: "unknown section and key",
+		contents: `[server "irc.elsinore.dk"]
+[network "HamNet"]
+server = irc.elsinore.dk
+nick = yorick
+colour = blue
+`,
+		want: []string{
+			`test.conf:1: unknown section type "server"`,
+			`test.conf:5: unknown key "colour" in network section`,
+		},
+	},
+	{
+		test: "incomplete network",
+		contents: `
+[network "HamNet"]
+realname = Yorick
+`,
+		want: []string{
+			`test.conf:2: network "HamNet" has no servers`,
+			`test.conf:2: network "HamNet" has no nick`,
+		},
+	},
+	{
+		test: "bad values",
+		contents: `[network "HamNet"]
+server = irc.elsinore.dk:99999
+tls = maybe
+nick = 2b
+nick = yorick
+sasl_mechanism = SCRAM-SHA-256
+flood_burst = 0
+flood_rate = fast
+`,
+		want: []string{
+			`test.conf:1: network "HamNet" has no servers`,
+			`test.conf:1: network "HamNet" has no nick`,
+			`test.conf:2: server: invalid port "99999"`,
+			`test.conf:3: tls: expected true or false, got "maybe"`,
+			`test.conf:4: nick: invalid nick "2b"`,
+			`test.conf:5: "nick" is already set at test.conf:4`,
+			`test.conf:6: sasl_mechanism: unsupported SASL mechanism "SCRAM-SHA-256"; want PLAIN or EXTERNAL`,
+			`test.conf:7: flood_burst: expected a positive number of lines, got "0"`,
+			`test.conf:8: flood_rate: expected a positive number of lines per second, got "fast"`,
+		},
+	},
+	{
+		test: "incomplete SASL",
+		contents: `[network "HamNet"]
+server = irc.elsinore.dk
+nick = yorick
+sasl_user = yorick
+`,
+		want: []string{
+			`test.conf:1: network "HamNet": SASL PLAIN needs sasl_user and sasl_password`,
+		},
+	},
+	{
+		test: "ambiguous passwords",
+		contents: `[network "HamNet"]
+server = irc.elsinore.dk
+nick = yorick
+password = hunter2
+password_file = ~/.hamnet
+sasl_user = yorick
+sasl_password_command = pass show irc/hamnet
+sasl_password_file = ~/.hamnet
+`,
+		want: []string{
+			`test.conf:1: network "HamNet": only one of password, password_command, and password_file may be set`,
+			`test.conf:1: network "HamNet": only one of sasl_password, sasl_password_command, and sasl_password_file may be set`,
+		},
+	},
+	{
+		test: "duplicates",
+		contents: `[network "HamNet"]
+server = irc.elsinore.dk
+nick = yorick
+[channel "HamNet" "#hamlet"]
+[channel "HamNet" "#hamlet"]
+[network "HamNet"]
+`,
+		want: []string{
+			`test.conf:5: channel "#hamlet" is already defined for network "HamNet"`,
+			`test.conf:6: network "HamNet" is already defined at test.conf:1`,
+		},
+	},
+	{
+		test: "bad channels",
+		contents: `[network "HamNet"]
+server = irc.elsinore.dk
+nick = yorick
+[channel "HamNet" "hamlet"]
+[channel "Globe" "#globe"]
+[channel "HamNet"]
+`,
+		want: []string{
+			`test.conf:4: invalid channel name "hamlet"`,
+			`test.conf:5: channel "#globe" is on undefined network "Globe"`,
+			`test.conf:6: channel section needs a network and a channel name, e.g. [channel "network" "#channel"]`,
+		},
+	},
+	{
+		test: "bad keys",
+		contents: `[keys]
+preset = Vim
+bind = channel quit
+bind = Channel q quit
+bind = channel:normal: q quit
+[keys "vim"]
+[keys]
+`,
+		want: []string{
+			`test.conf:2: preset: invalid preset "Vim"`,
+			`test.conf:3: bind: want "view[:mode] key... action", got "channel quit"`,
+			`test.conf:4: bind: invalid view "Channel"`,
+			`test.conf:5: bind: invalid mode "normal:"`,
+			`test.conf:6: keys section takes no arguments, e.g. [keys]`,
+			`test.conf:7: keys section is already defined at test.conf:1`,
+		},
+	},
+	{
+		test: "bad highlights",
+		contents: `[highlight "HamNet"]
+nick = perhaps
+word = " "
+regex = (ghost
+ignore = 2b
+[highlight "HamNet" "battlements"]
+[highlight "HamNet" "#hamlet" "yorick"]
+[highlight "HamNet"]
+`,
+		want: []string{
+			`test.conf:2: nick: expected true or false, got "perhaps"`,
+			`test.conf:3: word: word must not be empty`,
+			"test.conf:4: regex: error parsing regexp: missing closing ): `(ghost`",
+			`test.conf:5: ignore: invalid nick "2b"`,
+			`test.conf:6: invalid channel name "battlements"`,
+			`test.conf:7: highlight section takes at most a network and a channel name, e.g. [highlight "network" "#channel"]`,
+			`test.conf:8: highlight "HamNet" section is already defined at test.conf:1`,
+		},
+	},
+	{
+		test: "bad notify",
+		contents: `[notify]
+bell = loudly
+per_minute = 0
+[notify "HamNet"]
+[notify]
+`,
+		want: []string{
+			`test.conf:2: bell: expected true or false, got "loudly"`,
+			`test.conf:3: per_minute: expected a positive number of notifications, got "0"`,
+			`test.conf:4: notify section takes no arguments, e.g. [notify]`,
+			`test.conf:5: notify section is already defined at test.conf:1`,
+		},
+	},
+	{
+		test: "bad ignores",
+		contents: `[ignore "HamNet"]
+mask = ""
+regex = (arras
+[ignore "HamNet" "hamlet"]
+[ignore "HamNet"]
+`,
+		want: []string{
+			`test.conf:2: mask: invalid mask ""`,
+			"test.conf:3: regex: error parsing regexp: missing closing ): `(arras`",
+			`test.conf:4: invalid channel name "hamlet"`,
+			`test.conf:5: ignore "HamNet" section is already defined at test.conf:1`,
+		},
+	},
+	{
+		test: "bad membership",
+		contents: `[membership]
+hide_idle = 10
+collapse = sometimes
+[membership]
+`,
+		want: []string{
+			`test.conf:2: hide_idle: expected a duration, e.g. 10m, got "10"`,
+			`test.conf:3: collapse: expected true or false, got "sometimes"`,
+			`test.conf:4: membership section is already defined at test.conf:1`,
+		},
+	},
+	{
+		test: "bad display",
+		contents: `[display]
+time_format = %H:%M
+[display]
+`,
+		want: []string{
+			`test.conf:2: time_format: expected a Go time layout, e.g. 15:04, got "%H:%M"`,
+			`test.conf:3: display section is already defined at test.conf:1`,
+		},
+	},
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, tt := range errorTests {
+		tt := tt
+		t.Run(tt.test, func(t *testing.T) {
+			t.Parallel()
+			cfg, err := config.Parse("test.conf", tt.contents)
+			if err == nil {
+				t.Fatalf("unexpected success: got config %+v", cfg)
+			}
+			errs, ok := err.(config.ErrorList)
+			if !ok {
+				t.Fatalf("unexpected error type: got: %T want: config.ErrorList", err)
+			}
+			got := make([]string, len(errs))
+			for i, e := range errs {
+				got[i] = e.Error()
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("unexpected errors: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "discoirc-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config")
+	if _, err := config.Load(path); !os.IsNotExist(err) {
+		t.Errorf("unexpected error for missing file: got: %v want: not-exist", err)
+	}
+
+	contents := strings.Replace(hamlet, "tls = yes", "tls = sometimes", 1)
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = config.Load(path)
+	want := path + `:6: tls: expected true or false, got "sometimes"`
+	if err == nil || err.Error() != want {
+		t.Errorf("unexpected error: got: %v want: %s", err, want)
+	}
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ChangeKind is the type of a Change between configurations.
+type ChangeKind int
+
+const (
+	// AddNetwork indicates a new network should be configured and connected.
+	AddNetwork ChangeKind = iota
+	// RemoveNetwork indicates a network should be disconnected and forgotten.
+	RemoveNetwork
+	// UpdateNetwork indicates a network's configuration has changed.
+	// The new configuration applies the next time the network connects.
+	UpdateNetwork
+	// SetNick indicates the nick on a network should change.
+	SetNick
+	// JoinChannel indicates a channel should be joined.
+	JoinChannel
+	// PartChannel indicates a channel should be left.
+	PartChannel
+	// SetHighlights indicates the rules for highlighting messages have
+	// changed.
+	SetHighlights
+	// SetIgnores indicates the rules for ignoring users have changed.
+	SetIgnores
+)
+
+// Change is a difference between two configurations.
+type Change struct {
+	Kind ChangeKind
+	// Network is the new configuration of the network, or the old
+	// configuration if Kind is RemoveNetwork.
+	Network *Network
+	// Channel is the channel to join or part, for JoinChannel and
+	// PartChannel.
+	Channel *Channel
+	// Highlights are the new highlight rules, for SetHighlights.
+	Highlights []*Highlight
+	// Ignores are the new ignore rules, for SetIgnores.
+	Ignores []*Ignore
+}
+
+// String implements fmt.Stringer.
+func (c Change) String() string {
+	switch c.Kind {
+	case AddNetwork:
+		return fmt.Sprintf("add network %q", c.Network.Name)
+	case RemoveNetwork:
+		return fmt.Sprintf("remove network %q", c.Network.Name)
+	case UpdateNetwork:
+		return fmt.Sprintf("update network %q", c.Network.Name)
+	case SetNick:
+		return fmt.Sprintf("set nick on %q to %q", c.Network.Name, c.Network.Nick)
+	case JoinChannel:
+		return fmt.Sprintf("join %q on %q", c.Channel.Name, c.Network.Name)
+	case PartChannel:
+		return fmt.Sprintf("part %q on %q", c.Channel.Name, c.Network.Name)
+	case SetHighlights:
+		return "set highlight rules"
+	case SetIgnores:
+		return "set ignore rules"
+	}
+	return fmt.Sprintf("unknown change %d", c.Kind)
+}
+
+// Diff returns the changes needed to move from the old configuration to the
+// new one. Either may be empty, but not nil.
+//
+// Added networks join their autojoin channels upon connecting, so channel
+// changes are only listed for networks present in both configurations.
+// Highlight and ignore rules change first, so they apply to the messages that
+// follow.
+func Diff(old, new *Config) []Change {
+	var changes []Change
+
+	if !reflect.DeepEqual(old.Highlights, new.Highlights) {
+		changes = append(changes, Change{Kind: SetHighlights, Highlights: new.Highlights})
+	}
+	if !reflect.DeepEqual(old.Ignores, new.Ignores) {
+		changes = append(changes, Change{Kind: SetIgnores, Ignores: new.Ignores})
+	}
+
+	for _, o := range old.Networks {
+		if new.Network(o.Name) == nil {
+			changes = append(changes, Change{Kind: RemoveNetwork, Network: o})
+		}
+	}
+
+	for _, n := range new.Networks {
+		o := old.Network(n.Name)
+		if o == nil {
+			changes = append(changes, Change{Kind: AddNetwork, Network: n})
+			continue
+		}
+		if reflect.DeepEqual(o, n) {
+			continue
+		}
+		changes = append(changes, Change{Kind: UpdateNetwork, Network: n})
+		if o.Nick != n.Nick {
+			changes = append(changes, Change{Kind: SetNick, Network: n})
+		}
+
+		for _, oc := range o.Channels {
+			if nc := n.Channel(oc.Name); oc.AutoJoin && (nc == nil || !nc.AutoJoin) {
+				changes = append(changes, Change{Kind: PartChannel, Network: n, Channel: oc})
+			}
+		}
+		for _, nc := range n.Channels {
+			if oc := o.Channel(nc.Name); nc.AutoJoin && (oc == nil || !oc.AutoJoin) {
+				changes = append(changes, Change{Kind: JoinChannel, Network: n, Channel: nc})
+			}
+		}
+	}
+	return changes
+}
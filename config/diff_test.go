@@ -0,0 +1,166 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cceckman/discoirc/config"
+)
+
+const elsinore = `
+[network "HamNet"]
+server = irc.elsinore.dk
+nick = yorick
+[channel "HamNet" "#hamlet"]
+[channel "HamNet" "#battlements"]
+autojoin = false
+
+[network "Globe"]
+server = irc.globe.example
+nick = will
+`
+
+var diffTests = []struct {
+	test string
+	old  string
+	new  string
+	want []string
+}{
+	{
+		test: "no change",
+		old:  elsinore,
+		new:  elsinore,
+	},
+	{
+		test: "from empty",
+		old:  "",
+		new:  elsinore,
+		want: []string{
+			`add network "HamNet"`,
+			`add network "Globe"`,
+		},
+	},
+	{
+		test: "to empty",
+		old:  elsinore,
+		new:  "",
+		want: []string{
+			`remove network "HamNet"`,
+			`remove network "Globe"`,
+		},
+	},
+	{
+		test: "nick change",
+		old:  elsinore,
+		new: `
+[network "HamNet"]
+server = irc.elsinore.dk
+nick = hamlet
+[channel "HamNet" "#hamlet"]
+[channel "HamNet" "#battlements"]
+autojoin = false
+
+[network "Globe"]
+server = irc.globe.example
+nick = will
+`,
+		want: []string{
+			`update network "HamNet"`,
+			`set nick on "HamNet" to "hamlet"`,
+		},
+	},
+	{
+		test: "server change",
+		old:  elsinore,
+		new: `
+[network "HamNet"]
+server = irc.elsinore.dk
+nick = yorick
+[channel "HamNet" "#hamlet"]
+[channel "HamNet" "#battlements"]
+autojoin = false
+
+[network "Globe"]
+server = irc.globe.example
+server = irc2.globe.example
+nick = will
+`,
+		want: []string{
+			`update network "Globe"`,
+		},
+	},
+	{
+		test: "channel changes",
+		old:  elsinore,
+		new: `
+[network "HamNet"]
+server = irc.elsinore.dk
+nick = yorick
+[channel "HamNet" "#battlements"]
+[channel "HamNet" "#ophelia"]
+[channel "HamNet" "#graveyard"]
+autojoin = false
+
+[network "Globe"]
+server = irc.globe.example
+nick = will
+[channel "Globe" "#stage"]
+`,
+		want: []string{
+			`update network "HamNet"`,
+			`part "#hamlet" on "HamNet"`,
+			`join "#battlements" on "HamNet"`,
+			`join "#ophelia" on "HamNet"`,
+			`update network "Globe"`,
+			`join "#stage" on "Globe"`,
+		},
+	},
+	{
+		test: "highlight change",
+		old:  elsinore,
+		new: elsinore + `
+[highlight "HamNet"]
+word = Denmark
+`,
+		want: []string{
+			`set highlight rules`,
+		},
+	},
+	{
+		test: "ignore change",
+		old:  elsinore,
+		new: elsinore + `
+[ignore "HamNet" "#hamlet"]
+mask = polonius!*@*
+`,
+		want: []string{
+			`set ignore rules`,
+		},
+	},
+}
+
+func TestDiff(t *testing.T) {
+	for _, tt := range diffTests {
+		tt := tt
+		t.Run(tt.test, func(t *testing.T) {
+			t.Parallel()
+			old, err := config.Parse("old", tt.old)
+			if err != nil {
+				t.Fatalf("invalid old config: %v", err)
+			}
+			new, err := config.Parse("new", tt.new)
+			if err != nil {
+				t.Fatalf("invalid new config: %v", err)
+			}
+
+			var got []string
+			for _, c := range config.Diff(old, new) {
+				got = append(got, c.String())
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("unexpected changes: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultTimeFormat is the layout of timestamps if the configuration doesn't
+// set one: hours and minutes, e.g. "14:05".
+const DefaultTimeFormat = "15:04"
+
+// Display configures how channel views show events.
+type Display struct {
+	// TimeFormat is the layout of timestamps, as for time.Time.Format. If
+	// it's empty, DefaultTimeFormat is used.
+	TimeFormat string
+	// StripColors shows messages' colors in the terminal's default colors;
+	// bold, italics, underline, and reverse are still shown.
+	StripColors bool
+}
+
+var displayKeys = map[string]key{
+	"time_format": {set: func(t interface{}, v string) error {
+		// A layout without any of the reference time's parts formats as
+		// itself, e.g. strftime's "%H:%M".
+		if v == "" || strings.Contains(v, "\n") || (time.Time{}).Format(v) == v {
+			return fmt.Errorf("expected a Go time layout, e.g. 15:04, got %q", v)
+		}
+		t.(*Display).TimeFormat = v
+		return nil
+	}},
+	"strip_colors": {set: func(t interface{}, v string) error {
+		return parseBool(v, &t.(*Display).StripColors)
+	}},
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Highlight is a set of rules for highlighting messages: everywhere, on one
+// network, or in one channel.
+type Highlight struct {
+	// Network is the network the rules apply to, or empty if they apply on
+	// every network.
+	Network string
+	// Channel is the channel the rules apply to, or empty if they apply to
+	// every channel and user on the network.
+	Channel string
+
+	// Nick indicates whether messages that mention the user's nick are
+	// highlighted. If it's nil, the rules of a wider scope decide; by
+	// default, they are.
+	Nick *bool
+	// Words are highlighted, ignoring case, wherever they appear as whole
+	// words.
+	Words []string
+	// Patterns are regular expressions; messages that match any of them are
+	// highlighted.
+	Patterns []string
+	// Ignore are the nicks whose messages are never highlighted.
+	Ignore []string
+}
+
+// String describes the scope of the rules, as in the section header.
+func (h *Highlight) String() string {
+	var args []string
+	for _, a := range []string{h.Network, h.Channel} {
+		if a != "" {
+			args = append(args, fmt.Sprintf("%q", a))
+		}
+	}
+	return strings.Join(append([]string{"highlight"}, args...), " ")
+}
+
+var highlightKeys = map[string]key{
+	"nick": {set: func(t interface{}, v string) error {
+		var b bool
+		if err := parseBool(v, &b); err != nil {
+			return err
+		}
+		t.(*Highlight).Nick = &b
+		return nil
+	}},
+	"word": {repeated: true, set: func(t interface{}, v string) error {
+		if strings.TrimSpace(v) == "" {
+			return fmt.Errorf("word must not be empty")
+		}
+		h := t.(*Highlight)
+		h.Words = append(h.Words, v)
+		return nil
+	}},
+	"regex": {repeated: true, set: func(t interface{}, v string) error {
+		if _, err := regexp.Compile(v); err != nil {
+			return err
+		}
+		h := t.(*Highlight)
+		h.Patterns = append(h.Patterns, v)
+		return nil
+	}},
+	"ignore": {repeated: true, set: func(t interface{}, v string) error {
+		var nick string
+		if err := parseNick(v, &nick); err != nil {
+			return err
+		}
+		h := t.(*Highlight)
+		h.Ignore = append(h.Ignore, nick)
+		return nil
+	}},
+}
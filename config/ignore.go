@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Ignore is a set of rules for ignoring users: everywhere, on one network, or
+// in one channel. Their messages, joins, parts and quits are kept, but hidden.
+type Ignore struct {
+	// Network is the network the rules apply to, or empty if they apply on
+	// every network.
+	Network string
+	// Channel is the channel the rules apply to, or empty if they apply to
+	// every channel and user on the network.
+	Channel string
+
+	// Masks are nick!user@host patterns of the users to ignore, in which
+	// '*' matches any run of characters and '?' any one, e.g.
+	// "*!*@spam.example.net".
+	Masks []string
+	// Accounts are the account names of the users to ignore.
+	Accounts []string
+	// Patterns are regular expressions; messages that match any of them are
+	// ignored.
+	Patterns []string
+}
+
+// String describes the scope of the rules, as in the section header.
+func (i *Ignore) String() string {
+	var args []string
+	for _, a := range []string{i.Network, i.Channel} {
+		if a != "" {
+			args = append(args, fmt.Sprintf("%q", a))
+		}
+	}
+	return strings.Join(append([]string{"ignore"}, args...), " ")
+}
+
+var ignoreKeys = map[string]key{
+	"mask": {repeated: true, set: func(t interface{}, v string) error {
+		if v == "" || strings.ContainsAny(v, " \t") {
+			return fmt.Errorf("invalid mask %q", v)
+		}
+		i := t.(*Ignore)
+		i.Masks = append(i.Masks, v)
+		return nil
+	}},
+	"account": {repeated: true, set: func(t interface{}, v string) error {
+		if v == "" || strings.ContainsAny(v, " \t") {
+			return fmt.Errorf("invalid account %q", v)
+		}
+		i := t.(*Ignore)
+		i.Accounts = append(i.Accounts, v)
+		return nil
+	}},
+	"regex": {repeated: true, set: func(t interface{}, v string) error {
+		if _, err := regexp.Compile(v); err != nil {
+			return err
+		}
+		i := t.(*Ignore)
+		i.Patterns = append(i.Patterns, v)
+		return nil
+	}},
+}
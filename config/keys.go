@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Keys is the configuration of key bindings.
+type Keys struct {
+	// Preset is the name of the built-in keymap to start from, e.g. "vim".
+	// If empty, the default ("legacy") keymap is used.
+	Preset string
+	// Bindings are added to the preset's, in order; a later binding of the
+	// same keys replaces an earlier one.
+	Bindings []Binding
+}
+
+// Binding binds a sequence of keys to an action in a view.
+type Binding struct {
+	// View is the view the binding applies in, e.g. "channel".
+	View string
+	// Mode is the mode of the view the binding applies in, e.g. "normal",
+	// or empty if it applies in every mode.
+	Mode string
+	// Keys are the names of the keys to press, in order, e.g.
+	// ["Ctrl+X", "Ctrl+C"].
+	Keys []string
+	// Action is the name of the action, e.g. "quit".
+	Action string
+}
+
+// String returns the Binding as it's written in the configuration file.
+func (b Binding) String() string {
+	scope := b.View
+	if b.Mode != "" {
+		scope += ":" + b.Mode
+	}
+	return fmt.Sprintf("%s %s %s", scope, strings.Join(b.Keys, " "), b.Action)
+}
+
+// parseBinding parses a "view[:mode] key... action" binding.
+// It checks only the form of the binding; whether the view, keys, and action
+// exist is up to the UI.
+func parseBinding(v string) (Binding, error) {
+	fields := strings.Fields(v)
+	if len(fields) < 3 {
+		return Binding{}, fmt.Errorf("want \"view[:mode] key... action\", got %q", v)
+	}
+	var b Binding
+	parts := strings.SplitN(fields[0], ":", 2)
+	b.View = parts[0]
+	if len(parts) == 2 {
+		b.Mode = parts[1]
+		if !isKey(b.Mode) {
+			return Binding{}, fmt.Errorf("invalid mode %q", b.Mode)
+		}
+	}
+	if !isKey(b.View) {
+		return Binding{}, fmt.Errorf("invalid view %q", b.View)
+	}
+	b.Keys = fields[1 : len(fields)-1]
+	b.Action = fields[len(fields)-1]
+	return b, nil
+}
+
+var keysKeys = map[string]key{
+	"preset": {set: func(t interface{}, v string) error {
+		if !isKey(v) {
+			return fmt.Errorf("invalid preset %q", v)
+		}
+		t.(*Keys).Preset = v
+		return nil
+	}},
+	"bind": {repeated: true, set: func(t interface{}, v string) error {
+		b, err := parseBinding(v)
+		if err != nil {
+			return err
+		}
+		k := t.(*Keys)
+		k.Bindings = append(k.Bindings, b)
+		return nil
+	}},
+}
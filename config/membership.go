@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Membership configures how channel views show joins, parts, and quits.
+type Membership struct {
+	// HideIdle, if not zero, hides the joins, parts, and quits of users who
+	// haven't spoken within that long of them.
+	HideIdle time.Duration
+	// Collapse shows consecutive joins, parts, and quits as one summary,
+	// e.g. "+3 joined, -5 left".
+	Collapse bool
+	// FoldNetsplits shows the quits caused by a netsplit as one event.
+	FoldNetsplits bool
+}
+
+var membershipKeys = map[string]key{
+	"hide_idle": {set: func(t interface{}, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return fmt.Errorf("expected a duration, e.g. 10m, got %q", v)
+		}
+		t.(*Membership).HideIdle = d
+		return nil
+	}},
+	"collapse": {set: func(t interface{}, v string) error {
+		return parseBool(v, &t.(*Membership).Collapse)
+	}},
+	"fold_netsplits": {set: func(t interface{}, v string) error {
+		return parseBool(v, &t.(*Membership).FoldNetsplits)
+	}},
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultNotifyPerMinute is the most notifications sent in a minute, if
+// the configuration doesn't say.
+const DefaultNotifyPerMinute = 6
+
+// Notify is the configuration of notifications of highlights and private
+// messages.
+type Notify struct {
+	// Bell indicates the terminal bell should ring.
+	Bell bool
+	// Tmux indicates the tmux window showing the channel should be flagged.
+	Tmux bool
+	// Command is a shell command to run, with the notification as JSON on
+	// its standard input.
+	Command string
+	// DesktopCommand is a desktop notification command, e.g. notify-send,
+	// to run with a title and the message as its last two arguments.
+	DesktopCommand string
+
+	// Private indicates whether private messages are notified, like
+	// highlights. If it's nil, they are.
+	Private *bool
+	// PerMinute is the most notifications sent in a minute. If it's zero,
+	// the default is used.
+	PerMinute int
+	// DoNotDisturb indicates discoirc starts with notifications off.
+	DoNotDisturb bool
+}
+
+// NotifyPrivate reports whether private messages are notified.
+func (n *Notify) NotifyPrivate() bool {
+	return n.Private == nil || *n.Private
+}
+
+// Limit returns the most notifications sent in a minute.
+func (n *Notify) Limit() int {
+	if n.PerMinute == 0 {
+		return DefaultNotifyPerMinute
+	}
+	return n.PerMinute
+}
+
+var notifyKeys = map[string]key{
+	"bell": {set: func(t interface{}, v string) error {
+		return parseBool(v, &t.(*Notify).Bell)
+	}},
+	"tmux": {set: func(t interface{}, v string) error {
+		return parseBool(v, &t.(*Notify).Tmux)
+	}},
+	"command": {set: func(t interface{}, v string) error {
+		t.(*Notify).Command = v
+		return nil
+	}},
+	"desktop_command": {set: func(t interface{}, v string) error {
+		if strings.TrimSpace(v) == "" {
+			return fmt.Errorf("desktop_command must not be empty")
+		}
+		t.(*Notify).DesktopCommand = v
+		return nil
+	}},
+	"private": {set: func(t interface{}, v string) error {
+		var b bool
+		if err := parseBool(v, &b); err != nil {
+			return err
+		}
+		t.(*Notify).Private = &b
+		return nil
+	}},
+	"per_minute": {set: func(t interface{}, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return fmt.Errorf("expected a positive number of notifications, got %q", v)
+		}
+		t.(*Notify).PerMinute = n
+		return nil
+	}},
+	"dnd": {set: func(t interface{}, v string) error {
+		return parseBool(v, &t.(*Notify).DoNotDisturb)
+	}},
+}
@@ -0,0 +1,254 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Position is a location within a configuration file.
+type Position struct {
+	File string
+	Line int
+}
+
+// String implements fmt.Stringer.
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d", p.File, p.Line)
+}
+
+// Error is a problem found at a particular Position in a configuration file.
+type Error struct {
+	Position
+	Msg string
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Position, e.Msg)
+}
+
+// ErrorList is a list of Errors found while loading a configuration file.
+type ErrorList []*Error
+
+// Error implements the error interface.
+func (l ErrorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, e := range l {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// err returns the list as an error, or nil if the list is empty.
+func (l ErrorList) err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	sort.SliceStable(l, func(i, j int) bool { return l[i].Line < l[j].Line })
+	return l
+}
+
+func (l *ErrorList) add(pos Position, format string, args ...interface{}) {
+	*l = append(*l, &Error{
+		Position: pos,
+		Msg:      fmt.Sprintf(format, args...),
+	})
+}
+
+// lineKind is the syntactic role of a line in a configuration file.
+type lineKind int
+
+const (
+	blankLine lineKind = iota
+	commentLine
+	sectionLine
+	entryLine
+)
+
+// line is a single line of a configuration file.
+// The raw text is retained so that a file can be rewritten without disturbing
+// lines that haven't changed.
+type line struct {
+	raw  string
+	kind lineKind
+	pos  Position
+
+	// Set for sectionLines.
+	section string
+	args    []string
+
+	// Set for entryLines.
+	key, value string
+}
+
+// section is a header line and the entries that follow it.
+type section struct {
+	header  *line
+	entries []*line
+}
+
+// document is the syntactic structure of a configuration file.
+type document struct {
+	name     string
+	lines    []*line
+	sections []*section
+}
+
+// parseDocument splits the contents of r into lines and sections.
+// Syntax errors are accumulated in the returned ErrorList; lines with errors
+// are retained as comments, so that later stages can continue.
+func parseDocument(name string, r io.Reader) (*document, ErrorList, error) {
+	doc := &document{name: name}
+	var errs ErrorList
+	var cur *section
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		l := &line{
+			raw: scanner.Text(),
+			pos: Position{File: name, Line: n},
+		}
+		doc.lines = append(doc.lines, l)
+
+		text := strings.TrimSpace(l.raw)
+		switch {
+		case text == "":
+			l.kind = blankLine
+		case text[0] == '#' || text[0] == ';':
+			l.kind = commentLine
+		case text[0] == '[':
+			kind, args, err := parseHeader(text)
+			if err != nil {
+				errs.add(l.pos, "%v", err)
+				l.kind = commentLine
+				// Drop entries until the next valid header.
+				cur = &section{header: l}
+				continue
+			}
+			l.kind = sectionLine
+			l.section, l.args = kind, args
+			cur = &section{header: l}
+			doc.sections = append(doc.sections, cur)
+		default:
+			key, value, err := parseEntry(text)
+			if err != nil {
+				errs.add(l.pos, "%v", err)
+				l.kind = commentLine
+				continue
+			}
+			if cur == nil {
+				errs.add(l.pos, "%q is not in a section", key)
+				l.kind = commentLine
+				continue
+			}
+			l.kind = entryLine
+			l.key, l.value = key, value
+			cur.entries = append(cur.entries, l)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return doc, errs, nil
+}
+
+// parseHeader parses a section header, e.g. `[channel "Barnetic" "#discoirc"]`.
+func parseHeader(text string) (string, []string, error) {
+	if !strings.HasSuffix(text, "]") {
+		return "", nil, fmt.Errorf("section header is missing a closing ']'")
+	}
+	rest := strings.TrimSpace(text[1 : len(text)-1])
+
+	end := strings.IndexAny(rest, " \t")
+	if end < 0 {
+		end = len(rest)
+	}
+	kind := rest[:end]
+	if !isKey(kind) {
+		return "", nil, fmt.Errorf("invalid section name %q", kind)
+	}
+	rest = strings.TrimSpace(rest[end:])
+
+	var args []string
+	for rest != "" {
+		if rest[0] != '"' {
+			return "", nil, fmt.Errorf("section arguments must be quoted: %s", rest)
+		}
+		arg, n, err := unquote(rest)
+		if err != nil {
+			return "", nil, err
+		}
+		args = append(args, arg)
+		rest = strings.TrimSpace(rest[n:])
+	}
+	return kind, args, nil
+}
+
+// parseEntry parses a key-value entry, e.g. `nick = discobot`.
+func parseEntry(text string) (string, string, error) {
+	eq := strings.IndexByte(text, '=')
+	if eq < 0 {
+		return "", "", fmt.Errorf("expected 'key = value': %s", text)
+	}
+	key := strings.TrimSpace(text[:eq])
+	value := strings.TrimSpace(text[eq+1:])
+	if !isKey(key) {
+		return "", "", fmt.Errorf("invalid key %q", key)
+	}
+	if strings.HasPrefix(value, `"`) {
+		v, n, err := unquote(value)
+		if err != nil {
+			return "", "", err
+		}
+		if n != len(value) {
+			return "", "", fmt.Errorf("unexpected text after quoted value: %s", value[n:])
+		}
+		value = v
+	}
+	return key, value, nil
+}
+
+// unquote reads a double-quoted string from the start of s, returning its
+// contents and the number of bytes consumed. Backslash escapes the following
+// character.
+func unquote(s string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+			if i == len(s) {
+				return "", 0, fmt.Errorf("unterminated escape in %s", s)
+			}
+			b.WriteByte(s[i])
+		case '"':
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated quoted string %s", s)
+}
+
+// quote returns s in a form that parseEntry and parseHeader read back as s.
+func quote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// isKey reports whether s is a valid section name or key:
+// lower-case letters, digits, and underscores.
+func isKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,313 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cceckman/discoirc/internal/files"
+)
+
+// Save writes the configuration to the file at path.
+//
+// If the file already exists, Save changes only the sections and entries that
+// differ from the configuration; comments, ordering, and formatting of the
+// rest of the file are preserved. The file is replaced atomically.
+func Save(path string, c *Config) error {
+	doc := &document{name: path}
+	mode := os.FileMode(0600)
+	f, err := os.Open(path)
+	switch {
+	case err == nil:
+		if info, err := f.Stat(); err == nil {
+			mode = info.Mode().Perm()
+		}
+		// Syntax errors are ignored; lines with errors are kept as they
+		// are.
+		doc, _, err = parseDocument(path, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	case !os.IsNotExist(err):
+		return err
+	}
+
+	return files.WriteAtomic(path, doc.update(c), mode)
+}
+
+// entry is a key and value to write to a configuration file.
+type entry struct {
+	key, value string
+	// optional entries have their default value. They're written only if
+	// the key is already in the file, e.g. to keep an explicit "tls = false".
+	optional bool
+}
+
+// entries returns the entries describing the Network.
+func (n *Network) entries() []entry {
+	var r []entry
+	for _, s := range n.Servers {
+		v := s.String()
+		if s.Port == defaultPort(n.TLS) {
+			v = s.Host
+		}
+		r = append(r, entry{key: "server", value: v})
+	}
+	r = append(r,
+		entry{key: "tls", value: strconv.FormatBool(n.TLS), optional: !n.TLS},
+		entry{key: "nick", value: n.Nick},
+	)
+	for _, a := range n.AltNicks {
+		r = append(r, entry{key: "alt_nick", value: a})
+	}
+	r = append(r,
+		entry{key: "user", value: n.User, optional: n.User == ""},
+		entry{key: "realname", value: n.RealName, optional: n.RealName == ""},
+	)
+	r = append(r, n.Password.entries("password")...)
+	if n.SASL != nil {
+		r = append(r,
+			entry{key: "sasl_mechanism", value: n.SASL.Mechanism, optional: n.SASL.Mechanism == SASLPlain},
+			entry{key: "sasl_user", value: n.SASL.User, optional: n.SASL.User == ""},
+		)
+		r = append(r, n.SASL.Password.entries("sasl_password")...)
+	}
+	r = append(r,
+		entry{key: "persist_joins", value: strconv.FormatBool(n.PersistJoins), optional: !n.PersistJoins},
+		entry{key: "flood_burst", value: strconv.Itoa(n.FloodBurst), optional: n.FloodBurst == 0},
+		entry{key: "flood_rate", value: strconv.FormatFloat(n.FloodRate, 'g', -1, 64), optional: n.FloodRate == 0},
+	)
+	return r
+}
+
+// entries returns the entries describing the Secret, with keys starting with
+// prefix.
+func (s Secret) entries(prefix string) []entry {
+	return []entry{
+		{key: prefix, value: s.Value, optional: s.Value == ""},
+		{key: prefix + "_command", value: s.Command, optional: s.Command == ""},
+		{key: prefix + "_file", value: s.File, optional: s.File == ""},
+	}
+}
+
+// entries returns the entries describing the Channel.
+func (c *Channel) entries() []entry {
+	return []entry{
+		{key: "key", value: c.Key, optional: c.Key == ""},
+		{key: "autojoin", value: strconv.FormatBool(c.AutoJoin), optional: c.AutoJoin},
+	}
+}
+
+func defaultPort(tls bool) int {
+	if tls {
+		return DefaultTLSPort
+	}
+	return DefaultPort
+}
+
+// edit records changes to make to a document.
+type edit struct {
+	deleted map[*line]bool
+	// after holds lines to insert after existing lines.
+	after map[*line][]string
+	// tail holds lines to append to the end of the document.
+	tail []string
+}
+
+// update returns the contents of the document, changed to describe c.
+func (d *document) update(c *Config) []byte {
+	ed := &edit{
+		deleted: make(map[*line]bool),
+		after:   make(map[*line][]string),
+	}
+
+	// The last line of the last section for each network;
+	// new channel sections are inserted after it.
+	last := make(map[string]*line)
+	present := make(map[*Channel]bool)
+	for _, s := range d.sections {
+		h := s.header
+		switch {
+		case h.section == "network" && len(h.args) == 1:
+			n := c.Network(h.args[0])
+			if n == nil || last[n.Name] != nil {
+				ed.deleteSection(s)
+				continue
+			}
+			last[n.Name] = ed.updateSection(s, n.entries(), networkKeys, n.TLS)
+		case h.section == "channel" && len(h.args) == 2:
+			n := c.Network(h.args[0])
+			var ch *Channel
+			if n != nil {
+				ch = n.Channel(h.args[1])
+			}
+			if ch == nil || present[ch] {
+				ed.deleteSection(s)
+				continue
+			}
+			present[ch] = true
+			last[n.Name] = ed.updateSection(s, ch.entries(), channelKeys, false)
+		}
+	}
+
+	for _, n := range c.Networks {
+		var add []string
+		if last[n.Name] == nil {
+			add = append(add, newSection("network", []string{n.Name}, n.entries())...)
+		}
+		for _, ch := range n.Channels {
+			if !present[ch] {
+				add = append(add, newSection("channel", []string{n.Name, ch.Name}, ch.entries())...)
+			}
+		}
+		if l := last[n.Name]; l != nil {
+			ed.after[l] = append(ed.after[l], add...)
+		} else {
+			ed.tail = append(ed.tail, add...)
+		}
+	}
+
+	var b bytes.Buffer
+	for _, l := range d.lines {
+		if !ed.deleted[l] {
+			b.WriteString(l.raw)
+			b.WriteByte('\n')
+		}
+		for _, a := range ed.after[l] {
+			b.WriteString(a)
+			b.WriteByte('\n')
+		}
+	}
+	if len(ed.tail) > 0 && len(d.lines) == 0 {
+		// Don't start a new file with a blank line.
+		ed.tail = ed.tail[1:]
+	}
+	for _, a := range ed.tail {
+		b.WriteString(a)
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// deleteSection removes the section's header and entries.
+// Comments are left in place.
+func (ed *edit) deleteSection(s *section) {
+	ed.deleted[s.header] = true
+	for _, e := range s.entries {
+		ed.deleted[e] = true
+	}
+}
+
+// updateSection changes the section's entries to match want.
+// Entries for keys not in known are left as they are.
+// It returns the last line of the section.
+func (ed *edit) updateSection(s *section, want []entry, known map[string]key, tls bool) *line {
+	remaining := make(map[string][]entry)
+	for _, e := range want {
+		remaining[e.key] = append(remaining[e.key], e)
+	}
+
+	lastLine := s.header
+	lastOfKey := make(map[string]*line)
+	for _, l := range s.entries {
+		lastLine = l
+		if _, ok := known[l.key]; !ok {
+			continue
+		}
+		if len(remaining[l.key]) == 0 {
+			ed.deleted[l] = true
+			continue
+		}
+		e := remaining[l.key][0]
+		remaining[l.key] = remaining[l.key][1:]
+		lastOfKey[l.key] = l
+
+		switch {
+		case e.optional && e.value == "":
+			ed.deleted[l] = true
+		case !sameValue(l.key, l.value, e.value, tls):
+			l.raw = indentOf(l) + formatEntry(e)
+		}
+	}
+
+	// Add new entries after others with the same key, or at the end of the
+	// section.
+	for _, e := range want {
+		if len(remaining[e.key]) == 0 || remaining[e.key][0] != e {
+			continue
+		}
+		remaining[e.key] = remaining[e.key][1:]
+		if e.optional {
+			continue
+		}
+		anchor := lastOfKey[e.key]
+		if anchor == nil {
+			anchor = lastLine
+		}
+		ed.after[anchor] = append(ed.after[anchor], indentOf(lastLine)+formatEntry(e))
+	}
+	return lastLine
+}
+
+// indentOf returns the leading whitespace of an entry line, or the empty
+// string for other lines.
+func indentOf(l *line) string {
+	if l.kind != entryLine {
+		return ""
+	}
+	return l.raw[:len(l.raw)-len(strings.TrimLeft(l.raw, " \t"))]
+}
+
+// sameValue reports whether two values of the key are equivalent.
+func sameValue(key, a, b string, tls bool) bool {
+	if a == b {
+		return true
+	}
+	switch key {
+	case "tls", "autojoin", "persist_joins":
+		var x, y bool
+		return parseBool(a, &x) == nil && parseBool(b, &y) == nil && x == y
+	case "flood_burst", "flood_rate":
+		x, errX := strconv.ParseFloat(a, 64)
+		y, errY := strconv.ParseFloat(b, 64)
+		return errX == nil && errY == nil && x == y
+	case "server":
+		x, errX := parseServer(a)
+		y, errY := parseServer(b)
+		if errX != nil || errY != nil {
+			return false
+		}
+		for _, s := range []*Server{&x, &y} {
+			if s.Port == 0 {
+				s.Port = defaultPort(tls)
+			}
+		}
+		return x == y
+	}
+	return false
+}
+
+// newSection returns the lines of a new section, preceded by a blank line.
+func newSection(kind string, args []string, entries []entry) []string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = quote(a)
+	}
+	r := []string{"", "[" + kind + " " + strings.Join(quoted, " ") + "]"}
+	for _, e := range entries {
+		if !e.optional {
+			r = append(r, formatEntry(e))
+		}
+	}
+	return r
+}
+
+func formatEntry(e entry) string {
+	v := e.value
+	if v == "" || v != strings.TrimSpace(v) || strings.HasPrefix(v, `"`) {
+		v = quote(v)
+	}
+	return e.key + " = " + v
+}
@@ -0,0 +1,198 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cceckman/discoirc/config"
+)
+
+const saveOriginal = `# Elsinore's finest.
+[network "HamNet"]
+	server = irc.elsinore.dk:6697
+	tls = yes
+	nick = yorick
+	; Commented-out keys are left alone.
+	; realname = Alas
+	persist_joins = on
+
+# The main stage.
+[channel "HamNet" "#hamlet"]
+	key = rosemary
+
+[channel "HamNet" "#battlements"]
+	autojoin = false
+
+[network "Globe"]
+server = irc.globe.example
+nick = will
+`
+
+var saveTests = []struct {
+	test string
+	// change modifies the configuration loaded from saveOriginal.
+	change func(c *config.Config)
+	want   string
+}{
+	{
+		test:   "unchanged",
+		change: func(*config.Config) {},
+		want:   saveOriginal,
+	},
+	{
+		test: "runtime changes",
+		change: func(c *config.Config) {
+			ham := c.Network("HamNet")
+			ham.Nick = "hamlet"
+			ham.Channels = []*config.Channel{
+				ham.Channels[1],
+				{Name: "#ophelia", AutoJoin: true},
+			}
+			ham.Channels[0].AutoJoin = true
+			ham.Channels[0].Key = "sesame"
+		},
+		want: `# Elsinore's finest.
+[network "HamNet"]
+	server = irc.elsinore.dk:6697
+	tls = yes
+	nick = hamlet
+	; Commented-out keys are left alone.
+	; realname = Alas
+	persist_joins = on
+
+# The main stage.
+
+[channel "HamNet" "#battlements"]
+	autojoin = true
+	key = sesame
+
+[channel "HamNet" "#ophelia"]
+
+[network "Globe"]
+server = irc.globe.example
+nick = will
+`,
+	},
+	{
+		test: "network changes",
+		change: func(c *config.Config) {
+			globe := c.Network("Globe")
+			globe.Servers = append(globe.Servers, config.Server{Host: "irc2.globe.example", Port: 7000})
+			globe.AltNicks = []string{"shakespeare"}
+			globe.RealName = " William "
+			globe.FloodRate = 0.25
+			c.Networks = []*config.Network{
+				globe,
+				{
+					Name:     "Swan",
+					Servers:  []config.Server{{Host: "irc.swan.example", Port: config.DefaultPort}},
+					Nick:     "will",
+					Channels: []*config.Channel{{Name: "#avon", AutoJoin: true}},
+				},
+			}
+		},
+		want: `# Elsinore's finest.
+	; Commented-out keys are left alone.
+	; realname = Alas
+
+# The main stage.
+
+
+[network "Globe"]
+server = irc.globe.example
+server = irc2.globe.example:7000
+nick = will
+alt_nick = shakespeare
+realname = " William "
+flood_rate = 0.25
+
+[network "Swan"]
+server = irc.swan.example
+nick = will
+
+[channel "Swan" "#avon"]
+`,
+	},
+}
+
+func TestSave(t *testing.T) {
+	for _, tt := range saveTests {
+		tt := tt
+		t.Run(tt.test, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "discoirc-save")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "config")
+			if err := ioutil.WriteFile(path, []byte(saveOriginal), 0640); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := config.Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.change(cfg)
+			if err := config.Save(path, cfg); err != nil {
+				t.Fatalf("unexpected error saving: %v", err)
+			}
+
+			got, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(string(got), tt.want); diff != "" {
+				t.Errorf("unexpected contents: (-got +want)\n%s", diff)
+			}
+
+			// The saved file should load back to the same configuration.
+			reloaded, err := config.Load(path)
+			if err != nil {
+				t.Fatalf("saved configuration doesn't load: %v", err)
+			}
+			if diff := cmp.Diff(reloaded, cfg); diff != "" {
+				t.Errorf("saved configuration differs: (-got +want)\n%s", diff)
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0640 {
+				t.Errorf("unexpected mode: got: %v want: %v", info.Mode().Perm(), os.FileMode(0640))
+			}
+		})
+	}
+}
+
+func TestSave_NewFile(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "discoirc-save")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "discoirc", "config")
+
+	cfg, err := config.Parse("test", elsinore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Save(path, cfg); err != nil {
+		t.Fatalf("unexpected error saving: %v", err)
+	}
+
+	reloaded, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("saved configuration doesn't load: %v", err)
+	}
+	if diff := cmp.Diff(reloaded, cfg); diff != "" {
+		t.Errorf("saved configuration differs: (-got +want)\n%s", diff)
+	}
+}
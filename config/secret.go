@@ -0,0 +1,114 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Secret is a password. It may be given in the configuration file itself, or
+// read from the output of a command or from a file when it's needed.
+//
+// Secrets print as a description of their source, never as their value, so
+// they can be logged safely.
+type Secret struct {
+	// Value is the secret itself, if it's in the configuration file.
+	Value string
+	// Command is a shell command that prints the secret,
+	// e.g. "pass show irc/barnetic".
+	Command string
+	// File is the path of a file containing the secret.
+	File string
+}
+
+// IsZero reports whether the Secret has no source.
+func (s Secret) IsZero() bool {
+	return s == Secret{}
+}
+
+// String describes the Secret's source, without revealing its value.
+func (s Secret) String() string {
+	switch {
+	case s.Command != "":
+		return fmt.Sprintf("(from command %q)", s.Command)
+	case s.File != "":
+		return fmt.Sprintf("(from file %q)", s.File)
+	case s.Value != "":
+		return "(redacted)"
+	}
+	return "(none)"
+}
+
+// GoString implements fmt.GoStringer, so "%#v" doesn't reveal the value
+// either.
+func (s Secret) GoString() string {
+	return "config.Secret" + s.String()
+}
+
+// Resolve returns the value of the Secret, running its command or reading its
+// file if needed. Only the first line of the command's output or the file's
+// contents is used. Errors don't include the secret.
+func (s Secret) Resolve() (string, error) {
+	switch {
+	case s.Command != "":
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command("/bin/sh", "-c", s.Command)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			if msg := firstLine(stderr.String()); msg != "" {
+				return "", fmt.Errorf("password command %q failed: %v: %s", s.Command, err, msg)
+			}
+			return "", fmt.Errorf("password command %q failed: %v", s.Command, err)
+		}
+		v := firstLine(stdout.String())
+		if v == "" {
+			return "", fmt.Errorf("password command %q printed no password", s.Command)
+		}
+		return v, nil
+	case s.File != "":
+		b, err := ioutil.ReadFile(expandHome(s.File))
+		if err != nil {
+			return "", fmt.Errorf("can't read password file: %v", err)
+		}
+		v := firstLine(string(b))
+		if v == "" {
+			return "", fmt.Errorf("password file %q is empty", s.File)
+		}
+		return v, nil
+	}
+	return s.Value, nil
+}
+
+// validate reports an error if the Secret has more than one source.
+func (s Secret) validate(keys string) error {
+	n := 0
+	for _, v := range []string{s.Value, s.Command, s.File} {
+		if v != "" {
+			n++
+		}
+	}
+	if n > 1 {
+		return fmt.Errorf("only one of %s may be set", keys)
+	}
+	return nil
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSuffix(s, "\r")
+}
+
+// expandHome replaces a leading "~/" in path with the user's home directory.
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(os.Getenv("HOME"), path[2:])
+	}
+	return path
+}
@@ -0,0 +1,108 @@
+package config_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cceckman/discoirc/config"
+)
+
+// stubScript is a password command for tests. It prints the password, or
+// fails if its first argument is "fail".
+const stubScript = `#!/bin/sh
+if [ "$1" = "fail" ]; then
+	echo "gpg: decryption failed" >&2
+	exit 2
+fi
+printf 'hunter2\nusername: yorick\n'
+`
+
+func TestSecret_Resolve(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "discoirc-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := filepath.Join(dir, "pass")
+	if err := ioutil.WriteFile(script, []byte(stubScript), 0700); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "password")
+	if err := ioutil.WriteFile(file, []byte("hunter2\r\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty")
+	if err := ioutil.WriteFile(empty, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		secret  config.Secret
+		want    string
+		wantErr string
+	}{
+		{secret: config.Secret{}, want: ""},
+		{secret: config.Secret{Value: "hunter2"}, want: "hunter2"},
+		{secret: config.Secret{Command: script}, want: "hunter2"},
+		{secret: config.Secret{File: file}, want: "hunter2"},
+		{
+			secret:  config.Secret{Command: script + " fail"},
+			wantErr: fmt.Sprintf("password command %q failed: exit status 2: gpg: decryption failed", script+" fail"),
+		},
+		{
+			secret:  config.Secret{Command: "true"},
+			wantErr: `password command "true" printed no password`,
+		},
+		{
+			secret:  config.Secret{File: empty},
+			wantErr: fmt.Sprintf("password file %q is empty", empty),
+		},
+		{
+			secret:  config.Secret{File: filepath.Join(dir, "missing")},
+			wantErr: "can't read password file: ",
+		},
+	} {
+		got, err := tt.secret.Resolve()
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%v: unexpected error: %v", tt.secret, err)
+		case tt.wantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.wantErr)):
+			t.Errorf("%v: unexpected error: got: %v want: %s", tt.secret, err, tt.wantErr)
+		case got != tt.want:
+			t.Errorf("%v: unexpected value: got: %q want: %q", tt.secret, got, tt.want)
+		}
+	}
+}
+
+func TestSecret_String(t *testing.T) {
+	t.Parallel()
+	n := &config.Network{
+		Name:     "HamNet",
+		Password: config.Secret{Value: "hunter2"},
+		SASL: &config.SASL{
+			User:     "yorick",
+			Password: config.Secret{Value: "swordfish"},
+		},
+	}
+	for _, format := range []string{"%v", "%+v", "%#v"} {
+		for _, v := range []interface{}{n, n.SASL, n.Password} {
+			got := fmt.Sprintf(format, v)
+			if strings.Contains(got, "hunter2") || strings.Contains(got, "swordfish") {
+				t.Errorf("%s reveals a password: %s", format, got)
+			}
+			if strings.Contains(got, "%!") {
+				t.Errorf("%s: bad formatting: %s", format, got)
+			}
+		}
+	}
+	command := config.Secret{Command: "pass show irc/hamnet"}
+	if got, want := command.String(), `(from command "pass show irc/hamnet")`; got != want {
+		t.Errorf("unexpected description: got: %s want: %s", got, want)
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// Watch polls the configuration file at path every interval until ctx is done.
+// Whenever the file changes, Watch loads it and calls onChange with the result.
+//
+// onChange is called from Watch's goroutine; it is not called for the
+// contents of the file when Watch starts.
+func Watch(ctx context.Context, path string, interval time.Duration, onChange func(*Config, error)) {
+	last, lastErr := os.Stat(path)
+
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+
+		info, err := os.Stat(path)
+		if !changed(last, lastErr, info, err) {
+			continue
+		}
+		last, lastErr = info, err
+
+		if err != nil {
+			onChange(nil, err)
+			continue
+		}
+		onChange(Load(path))
+	}
+}
+
+// changed reports whether the file has changed between two calls to os.Stat.
+func changed(before os.FileInfo, beforeErr error, after os.FileInfo, afterErr error) bool {
+	if beforeErr != nil || afterErr != nil {
+		// Report only the transitions in and out of an error state.
+		return (beforeErr == nil) != (afterErr == nil)
+	}
+	return !before.ModTime().Equal(after.ModTime()) || before.Size() != after.Size()
+}
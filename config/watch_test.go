@@ -0,0 +1,80 @@
+package config_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cceckman/discoirc/config"
+)
+
+type loaded struct {
+	cfg *config.Config
+	err error
+}
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "discoirc-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config")
+
+	write := func(contents string, mtime time.Time) {
+		if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+		// Filesystem timestamps may be coarse; make sure each write is
+		// distinguishable.
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+	write(elsinore, start)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := make(chan loaded)
+	go config.Watch(ctx, path, 10*time.Millisecond, func(cfg *config.Config, err error) {
+		updates <- loaded{cfg, err}
+	})
+
+	next := func() loaded {
+		select {
+		case l := <-updates:
+			return l
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for update")
+		}
+		return loaded{}
+	}
+
+	// Give the watcher time to see the original file.
+	time.Sleep(100 * time.Millisecond)
+
+	// Valid change
+	write(elsinore+"[channel \"Globe\" \"#stage\"]\n", start.Add(time.Minute))
+	if l := next(); l.err != nil || len(l.cfg.Network("Globe").Channels) != 1 {
+		t.Errorf("unexpected update: got: %+v want: config with #stage", l)
+	}
+
+	// Invalid change
+	write(elsinore+"[channel \"Swan\" \"#stage\"]\n", start.Add(2*time.Minute))
+	if l := next(); l.err == nil {
+		t.Errorf("unexpected update: got: %+v want: error", l)
+	}
+
+	// Removal
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if l := next(); !os.IsNotExist(l.err) {
+		t.Errorf("unexpected update: got: %+v want: not-exist error", l)
+	}
+}
@@ -18,8 +18,15 @@ type ChannelState struct {
 	Topic   string
 	Members int
 
-	Unread      int
+	Unread int
+	// Highlights is the number of unread messages that are highlighted.
+	Highlights int
+
 	LastMessage Seq
+
+	// Pending is the number of messages waiting to be sent to the channel,
+	// e.g. because the network is disconnected.
+	Pending int
 }
 
 // ChannelStateEvent is an Event indicating a change in a channel's state.
@@ -0,0 +1,20 @@
+package data
+
+// ErrorEvent is an Event indicating an error within discoirc, e.g. an invalid
+// configuration. Its Scope is usually empty, i.e. a discoirc-internal event.
+type ErrorEvent struct {
+	EventID
+
+	// Line describes the error.
+	Line string
+}
+
+var _ Event = &ErrorEvent{}
+
+// ID returns the scope & sequence of this Event.
+func (e *ErrorEvent) ID() *EventID {
+	return &e.EventID
+}
+
+// String implments fmt.Stringer.
+func (e *ErrorEvent) String() string { return e.Line }
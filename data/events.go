@@ -14,19 +14,6 @@ type Scope struct {
 	Name string
 }
 
-// Filter is a pattern for mmatching events.
-type Filter struct {
-	Scope
-	MatchNet, MatchName bool
-}
-
-// Match checks if the given scope is within the filter.
-func (f *Filter) Match(s Scope) bool {
-	net := !f.MatchNet || (s.Net == f.Net)
-	name := !f.MatchName || (s.Name == f.Name)
-	return net && name
-}
-
 // Seq identifies the order of an Event within a Scope.
 type Seq int64
 
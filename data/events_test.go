@@ -94,6 +94,9 @@ func TestStringify(t *testing.T) {
 		&data.ChannelStateEvent{
 			Line: hello,
 		},
+		&data.ErrorEvent{
+			Line: hello,
+		},
 	} {
 		got := ev.String()
 		if diff := cmp.Diff(got, hello); diff != "" {
@@ -101,3 +104,20 @@ func TestStringify(t *testing.T) {
 		}
 	}
 }
+
+func TestStringify_Message(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		command data.Command
+		want    string
+	}{
+		{command: data.Privmsg, want: "<hamlet> words, words, words"},
+		{command: data.Action, want: "* hamlet words, words, words"},
+		{command: data.Notice, want: "-hamlet- words, words, words"},
+	} {
+		e := &data.MessageEvent{Nick: "hamlet", Command: tt.command, Text: "words, words, words"}
+		if got := e.String(); got != tt.want {
+			t.Errorf("unexpected string: got: %q want: %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package data
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Filter is a pattern for matching events: by their scope, and, for views
+// that look at individual Events, by their kind, sender, and text.
+// The zero Filter matches every Event. Filters can be written as text; see
+// ParseFilter.
+type Filter struct {
+	// Scope, MatchNet and MatchName match a single network, or a single
+	// channel or user on it, exactly.
+	Scope
+	MatchNet, MatchName bool
+
+	// In, if not empty, limits matches to scopes matching one of the
+	// patterns. Scopes matching any of NotIn don't match.
+	In, NotIn []ScopePattern
+	// Kinds, if not empty, limits matches to Events of those kinds. Events
+	// of any of NotKinds don't match.
+	Kinds, NotKinds []Kind
+	// From, if not empty, limits matches to Events from senders whose nicks
+	// match one of the glob patterns. Events from any of NotFrom don't
+	// match.
+	From, NotFrom []string
+	// Text are the strings an Event's text must contain, and NotText those
+	// it must not, ignoring case and the form of the characters (see Fold).
+	Text, NotText []string
+	// Patterns are the regular expressions an Event's text must match, and
+	// NotPatterns those it must not.
+	Patterns, NotPatterns []*regexp.Regexp
+	// After and Before, if they're set, limit matches to Events at or after
+	// After, and before Before. Events without a time don't match them.
+	After, Before time.Time
+}
+
+// Match checks if the given scope is within the filter.
+func (f *Filter) Match(s Scope) bool {
+	net := !f.MatchNet || (s.Net == f.Net)
+	name := !f.MatchName || (s.Name == f.Name)
+	if !(net && name) {
+		return false
+	}
+	if len(f.In) > 0 && !matchAnyScope(f.In, s) {
+		return false
+	}
+	return !matchAnyScope(f.NotIn, s)
+}
+
+// MatchNetwork checks if any scope on the network may be within the filter.
+func (f *Filter) MatchNetwork(net string) bool {
+	if f.MatchNet && net != f.Net {
+		return false
+	}
+	if len(f.In) > 0 {
+		found := false
+		for _, p := range f.In {
+			found = found || MatchGlob(p.Net, net)
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, p := range f.NotIn {
+		// Only a pattern for the whole network excludes all of it.
+		if p.Name == "" && MatchGlob(p.Net, net) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchEvent checks if the Event is within the filter: its scope, kind,
+// sender, and text.
+func (f *Filter) MatchEvent(e Event) bool {
+	if !f.Match(e.ID().Scope) {
+		return false
+	}
+	k := KindOf(e)
+	if (len(f.Kinds) > 0 && !containsKind(f.Kinds, k)) || containsKind(f.NotKinds, k) {
+		return false
+	}
+	if !f.MatchTime(Time(e)) {
+		return false
+	}
+	nick := Sender(e)
+	if (len(f.From) > 0 && !matchAnyGlob(f.From, nick)) || matchAnyGlob(f.NotFrom, nick) {
+		return false
+	}
+	text := Text(e)
+	folded := Fold(text)
+	for _, t := range f.Text {
+		if !strings.Contains(folded, Fold(t)) {
+			return false
+		}
+	}
+	for _, t := range f.NotText {
+		if strings.Contains(folded, Fold(t)) {
+			return false
+		}
+	}
+	for _, re := range f.Patterns {
+		if !re.MatchString(text) {
+			return false
+		}
+	}
+	for _, re := range f.NotPatterns {
+		if re.MatchString(text) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchTime checks if an Event at the time, or the zero Time if it has none,
+// may be within the filter.
+func (f *Filter) MatchTime(t time.Time) bool {
+	if !f.After.IsZero() && (t.IsZero() || t.Before(f.After)) {
+		return false
+	}
+	if !f.Before.IsZero() && (t.IsZero() || !t.Before(f.Before)) {
+		return false
+	}
+	return true
+}
+
+// IsZero reports whether the Filter matches every Event.
+func (f *Filter) IsZero() bool {
+	return f.String() == ""
+}
+
+// ScopePattern matches scopes by glob patterns of their network and name,
+// ignoring case: '*' matches any run of characters, and '?' any one. An empty
+// pattern matches any network or name; e.g. {Net: "HamNet"} matches the
+// network, and every channel and user on it.
+type ScopePattern struct {
+	Net, Name string
+}
+
+// Match reports whether the scope matches the pattern.
+func (p ScopePattern) Match(s Scope) bool {
+	return MatchGlob(p.Net, s.Net) && MatchGlob(p.Name, s.Name)
+}
+
+func matchAnyScope(ps []ScopePattern, s Scope) bool {
+	for _, p := range ps {
+		if p.Match(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Kind is the kind of an Event, for filtering.
+type Kind int
+
+const (
+	// KindOther is an Event of no other kind, e.g. an error.
+	KindOther Kind = iota
+	// KindMessage is a message sent to a channel or user.
+	KindMessage
+	// KindMembership is a join, part, or quit.
+	KindMembership
+	// KindState is a change in a network's or channel's state.
+	KindState
+)
+
+var kindNames = []string{
+	KindOther:      "other",
+	KindMessage:    "message",
+	KindMembership: "membership",
+	KindState:      "state",
+}
+
+// String returns the name of the Kind, e.g. "message".
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return kindNames[KindOther]
+	}
+	return kindNames[k]
+}
+
+// KindOf returns the Kind of the Event.
+func KindOf(e Event) Kind {
+	switch e.(type) {
+	case *MessageEvent:
+		return KindMessage
+	case *MembershipEvent:
+		return KindMembership
+	case *NetworkStateEvent, *ChannelStateEvent:
+		return KindState
+	}
+	return KindOther
+}
+
+func containsKind(ks []Kind, k Kind) bool {
+	for _, kk := range ks {
+		if kk == k {
+			return true
+		}
+	}
+	return false
+}
+
+// Sender returns the nick of the Event's sender, or "" if it doesn't have one.
+func Sender(e Event) string {
+	switch e := e.(type) {
+	case *MessageEvent:
+		return e.Nick
+	case *MembershipEvent:
+		return e.Nick
+	}
+	return ""
+}
+
+// Time returns when the Event happened, or the zero Time if it doesn't have a
+// time.
+func Time(e Event) time.Time {
+	switch e := e.(type) {
+	case *MessageEvent:
+		return e.Time
+	case *MembershipEvent:
+		return e.Time
+	}
+	return time.Time{}
+}
+
+// Fold normalizes the text, so that equivalent forms of the same characters
+// are the same, and folds its case.
+func Fold(s string) string {
+	// A Caser can't be shared between goroutines.
+	return cases.Fold().String(norm.NFKC.String(s))
+}
+
+// Ignored reports whether the Event is from an ignored user. Ignored Events are
+// kept, but views hide them unless asked to reveal them.
+func Ignored(e Event) bool {
+	switch e := e.(type) {
+	case *MessageEvent:
+		return e.Ignored
+	case *MembershipEvent:
+		return e.Ignored
+	}
+	return false
+}
+
+// Text returns the text of the Event, without formatting codes: for a
+// message, what was said.
+func Text(e Event) string {
+	switch e := e.(type) {
+	case *MessageEvent:
+		return StripFormatting(e.Text)
+	}
+	return e.String()
+}
+
+func matchAnyGlob(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if MatchGlob(p, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchGlob reports whether s matches the glob pattern, ignoring case: '*'
+// matches any run of characters, and '?' any one. An empty pattern matches
+// anything.
+func MatchGlob(pattern, s string) bool {
+	if pattern == "" {
+		return true
+	}
+	return glob(strings.ToLower(pattern), strings.ToLower(s))
+}
+
+func glob(p, s string) bool {
+	// star and next are where to resume after the last '*': the rest of
+	// the pattern after it, and the part of s it hasn't consumed.
+	star, next := -1, 0
+	i, j := 0, 0
+	for j < len(s) {
+		pr, pn := utf8.DecodeRuneInString(p[i:])
+		sr, sn := utf8.DecodeRuneInString(s[j:])
+		switch {
+		case i < len(p) && pr == '*':
+			star, next = i+pn, j
+			i += pn
+		case i < len(p) && (pr == '?' || pr == sr):
+			i += pn
+			j += sn
+		case star >= 0:
+			// Let the last '*' consume one more rune.
+			_, n := utf8.DecodeRuneInString(s[next:])
+			next += n
+			i, j = star, next
+		default:
+			return false
+		}
+	}
+	for ; i < len(p) && p[i] == '*'; i++ {
+	}
+	return i == len(p)
+}
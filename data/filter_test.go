@@ -2,6 +2,7 @@ package data_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/cceckman/discoirc/data"
 )
@@ -134,3 +135,213 @@ func TestFilter_Chan(t *testing.T) {
 		}
 	}
 }
+
+var (
+	ops     = data.Scope{Net: "Work", Name: "#ops-eu"}
+	dev     = data.Scope{Net: "Work", Name: "#dev"}
+	hamlet  = data.Scope{Net: "HamNet", Name: "#hamlet"}
+	deploy  = &data.MessageEvent{EventID: data.EventID{Scope: ops, Seq: 1}, Nick: "alice", Text: "Deploy failed: disk full", Time: day(1, 10)}
+	retry   = &data.MessageEvent{EventID: data.EventID{Scope: ops, Seq: 2}, Nick: "bob", Text: "retrying the deploy", Time: day(2, 9)}
+	chatter = &data.MessageEvent{EventID: data.EventID{Scope: dev, Seq: 1}, Nick: "alice", Text: "lunch?", Time: day(3, 12)}
+	topic   = &data.ChannelStateEvent{EventID: data.EventID{Scope: ops, Seq: 3}, Line: "alice set the topic: deploys"}
+	samples = []data.Event{deploy, retry, chatter, topic}
+)
+
+// day returns the hour of the day in January 2026, in the local time zone.
+func day(d, hour int) time.Time {
+	return time.Date(2026, time.January, d, hour, 0, 0, 0, time.Local)
+}
+
+func TestFilter_Patterns(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		query string
+		want  []bool
+	}{
+		{query: "", want: []bool{true, true, true, true}},
+		{query: "in:#ops-*", want: []bool{true, true, false, true}},
+		{query: "in:work/#OPS-EU", want: []bool{true, true, false, true}},
+		{query: "in:#dev in:#ops-eu -kind:state", want: []bool{true, true, true, false}},
+		{query: "net:Work -in:#dev", want: []bool{true, true, false, true}},
+		{query: "-net:Work", want: []bool{false, false, false, false}},
+		{query: "kind:message from:a*", want: []bool{true, false, true, false}},
+		{query: "-from:bob", want: []bool{true, false, true, true}},
+		{query: "DEPLOY", want: []bool{true, true, false, true}},
+		{query: `"deploy failed" -full`, want: []bool{false, false, false, false}},
+		{query: `re:"^[Dd]eploy" -re:disk`, want: []bool{false, false, false, false}},
+		{query: `re:^[Dd]eploy`, want: []bool{true, false, false, false}},
+		{query: "after:2026-01-02", want: []bool{false, true, true, false}},
+		{query: "after:2026-01-01 before:2026-01-03", want: []bool{true, true, false, false}},
+		{query: "before:2026-01-02", want: []bool{true, false, false, false}},
+	} {
+		f, err := data.ParseFilter(tt.query)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.query, err)
+			continue
+		}
+		for i, e := range samples {
+			if got := f.MatchEvent(e); got != tt.want[i] {
+				t.Errorf("%q: unexpected match of %q: got: %v want: %v", tt.query, e.String(), got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestFilter_Fold(t *testing.T) {
+	t.Parallel()
+	e := &data.MessageEvent{EventID: data.EventID{Scope: hamlet, Seq: 1}, Nick: "Hamlet", Text: "Ｗｈｏ's there? Café, Straße"}
+	for _, text := range []string{"who's", "CAFE\u0301", "strasse"} {
+		f := data.Filter{Text: []string{text}}
+		if !f.MatchEvent(e) {
+			t.Errorf("%q: unexpected mismatch of %q", text, e.Text)
+		}
+		f = data.Filter{NotText: []string{text}}
+		if f.MatchEvent(e) {
+			t.Errorf("-%q: unexpected match of %q", text, e.Text)
+		}
+	}
+}
+
+func TestFilter_MatchNetwork(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		query string
+		net   string
+		want  bool
+	}{
+		{query: "", net: "Work", want: true},
+		{query: "in:#ops", net: "Work", want: true},
+		{query: "in:work/#ops", net: "Work", want: true},
+		{query: "in:work/#ops", net: "HamNet", want: false},
+		{query: "-in:work/#ops", net: "Work", want: true},
+		{query: "-net:work", net: "Work", want: false},
+		{query: "net:w* net:h*", net: "HamNet", want: true},
+	} {
+		f, err := data.ParseFilter(tt.query)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.query, err)
+			continue
+		}
+		if got := f.MatchNetwork(tt.net); got != tt.want {
+			t.Errorf("%q: unexpected match of %q: got: %v want: %v", tt.query, tt.net, got, tt.want)
+		}
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		query, want string
+	}{
+		{query: "", want: ""},
+		{
+			query: `  deploy  -KIND:state from:alice in:#ops-*  `,
+			want:  `in:#ops-* -kind:state from:alice deploy`,
+		},
+		{
+			query: `-"a: b" in:Work/#ops re:"a b" "-x" c\d`,
+			want:  `in:Work/#ops re:"a b" "-x" c\d -"a: b"`,
+		},
+		{
+			query: `net:Work in:#a/b in:"weird net/#c" -from:"" "x\"y"`,
+			want:  `net:Work in:#a/b in:"weird net/#c" -from:"" x"y`,
+		},
+		{
+			query: `kind:Message -kind:membership -re:\s+$ from:bob`,
+			want:  `kind:message -kind:membership from:bob -re:\s+$`,
+		},
+		{
+			query: `before:2026-01-03 deploy after:2026-01-01T10:30:00Z`,
+			want:  `after:2026-01-01T10:30:00Z before:2026-01-03 deploy`,
+		},
+	} {
+		f, err := data.ParseFilter(tt.query)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.query, err)
+			continue
+		}
+		if got := f.String(); got != tt.want {
+			t.Errorf("%q: unexpected text: got: %q want: %q", tt.query, got, tt.want)
+		}
+		// The text form parses back to the same filter.
+		g, err := data.ParseFilter(tt.want)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.want, err)
+			continue
+		}
+		if got := g.String(); got != tt.want {
+			t.Errorf("%q: text doesn't round-trip: got: %q", tt.want, got)
+		}
+	}
+
+	legacy := data.Filter{Scope: data.Scope{Net: "Work", Name: "#ops"}, MatchNet: true, MatchName: true}
+	if got, want := legacy.String(), "at:Work/#ops"; got != want {
+		t.Errorf("unexpected text: got: %q want: %q", got, want)
+	}
+
+	for _, query := range []string{
+		`kind:joins`,
+		`re:(`,
+		`in:""`,
+		`from:`,
+		`"unterminated`,
+		`-`,
+		`-at:Work`,
+		`at:Work at:HamNet`,
+		`after:yesterday`,
+		`-before:2026-01-01`,
+		`after:2026-01-01 after:2026-01-02`,
+	} {
+		if _, err := data.ParseFilter(query); err == nil {
+			t.Errorf("%q: unexpected success", query)
+		}
+	}
+}
+
+func TestFilter_RoundTrip(t *testing.T) {
+	t.Parallel()
+	scopes := []data.Scope{
+		{Net: "N"},
+		{Net: "N", Name: "#a*"},
+		{Net: "N", Name: "#abc"},
+		{Net: "n", Name: "#a*"},
+		{Net: "M", Name: "#a*"},
+		{Net: "N", Name: "foo/bar"},
+		{Net: "M", Name: "foo/bar"},
+		{Net: "weird net", Name: "#c d"},
+	}
+	for _, f := range []data.Filter{
+		{Scope: data.Scope{Net: "N"}, MatchNet: true},
+		{Scope: data.Scope{Net: "N"}, MatchNet: true, MatchName: true},
+		{Scope: data.Scope{Net: "N", Name: "#a*"}, MatchNet: true, MatchName: true},
+		{Scope: data.Scope{Name: "#a*"}, MatchName: true},
+		{Scope: data.Scope{Net: "weird net", Name: "#c d"}, MatchNet: true, MatchName: true},
+		{Scope: data.Scope{Net: "N", Name: "foo/bar"}, MatchNet: true, MatchName: true},
+		{In: []data.ScopePattern{{Name: "foo/bar"}}},
+		{In: []data.ScopePattern{{Net: "*", Name: "#a*"}}},
+		{Scope: data.Scope{Net: "N"}, MatchNet: true, NotIn: []data.ScopePattern{{Name: "#abc"}}},
+		{After: day(2, 0)},
+		{After: day(1, 11), Before: day(3, 0).Add(time.Millisecond)},
+	} {
+		f := f
+		text := f.String()
+		g, err := data.ParseFilter(text)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", text, err)
+			continue
+		}
+		for _, s := range scopes {
+			if got, want := g.Match(s), f.Match(s); got != want {
+				t.Errorf("%q: unexpected match of %v: got: %v want: %v", text, s, got, want)
+			}
+			if got, want := g.MatchNetwork(s.Net), f.MatchNetwork(s.Net); got != want {
+				t.Errorf("%q: unexpected match of network %q: got: %v want: %v", text, s.Net, got, want)
+			}
+		}
+		for _, e := range samples {
+			if got, want := g.MatchEvent(e), f.MatchEvent(e); got != want {
+				t.Errorf("%q: unexpected match of %q: got: %v want: %v", text, e.String(), got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package data
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Color is a color of mIRC's palette, or NoColor. Its Code is its number in
+// the palette, e.g. 4 for Red.
+type Color uint8
+
+// The colors numbered 0 to 15. Those numbered 16 to 98 are matched to the
+// nearest of them.
+const (
+	// NoColor is the terminal's default color.
+	NoColor Color = iota
+	White
+	Black
+	Navy
+	Green
+	Red
+	Maroon
+	Purple
+	Orange
+	Yellow
+	LightGreen
+	Teal
+	LightCyan
+	LightBlue
+	Pink
+	Grey
+	LightGrey
+)
+
+// maxColorCode is the highest color number; 99 is the default color.
+const maxColorCode = 98
+
+// Code returns the color's number in mIRC's palette, or -1 for NoColor.
+func (c Color) Code() int {
+	return int(c) - 1
+}
+
+// palette is the RGB values of colors 0 to 15, to which colors given in hex
+// are matched.
+var palette = [...]uint32{
+	0xffffff, 0x000000, 0x00007f, 0x009300, 0xff0000, 0x7f0000, 0x9c009c, 0xfc7f00,
+	0xffff00, 0x00fc00, 0x009393, 0x00ffff, 0x0000fc, 0xff00ff, 0x7f7f7f, 0xd2d2d2,
+}
+
+// extendedPalette is the RGB values of colors 16 to 98.
+var extendedPalette = [...]uint32{
+	0x470000, 0x472100, 0x474700, 0x324700, 0x004700, 0x00472c, 0x004747, 0x002747, 0x000047, 0x2e0047, 0x470047, 0x47002a,
+	0x740000, 0x743a00, 0x747400, 0x517400, 0x007400, 0x007449, 0x007474, 0x004074, 0x000074, 0x4b0074, 0x740074, 0x740045,
+	0xb50000, 0xb56300, 0xb5b500, 0x7db500, 0x00b500, 0x00b571, 0x00b5b5, 0x0063b5, 0x0000b5, 0x7500b5, 0xb500b5, 0xb5006b,
+	0xff0000, 0xff8c00, 0xffff00, 0xb2ff00, 0x00ff00, 0x00ffa0, 0x00ffff, 0x008cff, 0x0000ff, 0xa500ff, 0xff00ff, 0xff0098,
+	0xff5959, 0xffb459, 0xffff71, 0xcfff60, 0x6fff6f, 0x65ffc9, 0x6dffff, 0x59b4ff, 0x5959ff, 0xc459ff, 0xff66ff, 0xff59bc,
+	0xff9c9c, 0xffd39c, 0xffff9c, 0xe2ff9c, 0x9cff9c, 0x9cffdb, 0x9cffff, 0x9cd3ff, 0x9c9cff, 0xdc9cff, 0xff9cff, 0xff94d3,
+	0x000000, 0x131313, 0x282828, 0x363636, 0x4d4d4d, 0x656565, 0x818181, 0x9f9f9f, 0xbcbcbc, 0xe2e2e2, 0xffffff,
+}
+
+// nearestColor returns the color of colors 0 to 15 closest to the RGB value.
+func nearestColor(rgb uint32) Color {
+	best, bestDist := NoColor, -1
+	for i, p := range palette {
+		dist := 0
+		for shift := uint(0); shift <= 16; shift += 8 {
+			d := int(rgb>>shift&0xff) - int(p>>shift&0xff)
+			dist += d * d
+		}
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = Color(i+1), dist
+		}
+	}
+	return best
+}
+
+// Format is how a Span of text is formatted.
+type Format struct {
+	Bold, Italic, Underline, Reverse bool
+	Fg, Bg                           Color
+}
+
+// Span is a run of text with the same Format.
+type Span struct {
+	Text string
+	Format
+}
+
+// The control codes of mIRC's formatting, which most IRC clients follow.
+const (
+	codeBold      = '\x02'
+	codeColor     = '\x03'
+	codeHexColor  = '\x04'
+	codeReset     = '\x0f'
+	codeMonospace = '\x11'
+	codeReverse   = '\x16'
+	codeItalic    = '\x1d'
+	codeStrike    = '\x1e'
+	codeUnderline = '\x1f'
+)
+
+// formatCodes are all of the codes.
+const formatCodes = "\x02\x03\x04\x0f\x11\x16\x1d\x1e\x1f"
+
+// ParseFormatting splits text, e.g. a message, into Spans with the Format that
+// its control codes set: \x02 toggles bold, \x1D italics, \x1F underline, and
+// \x16 reverse; \x03 sets the colors, e.g. "\x0304" red or "\x0304,01" red on
+// black, or resets them if no color follows; \x04 does the same with hex RGB
+// values, e.g. "\x04FF0000"; and \x0F resets everything. Colors 16 to 98, and
+// hex values, are matched to the nearest of colors 0 to 15. Monospace (\x11) and strikethrough (\x1E)
+// are removed, but not shown.
+func ParseFormatting(s string) []Span {
+	var spans []Span
+	var f Format
+	var text strings.Builder
+	flush := func() {
+		if text.Len() == 0 {
+			return
+		}
+		if n := len(spans); n > 0 && spans[n-1].Format == f {
+			spans[n-1].Text += text.String()
+		} else {
+			spans = append(spans, Span{Text: text.String(), Format: f})
+		}
+		text.Reset()
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		if strings.IndexByte(formatCodes, c) < 0 {
+			text.WriteByte(c)
+			i++
+			continue
+		}
+		flush()
+		i++
+		switch c {
+		case codeBold:
+			f.Bold = !f.Bold
+		case codeItalic:
+			f.Italic = !f.Italic
+		case codeUnderline:
+			f.Underline = !f.Underline
+		case codeReverse:
+			f.Reverse = !f.Reverse
+		case codeReset:
+			f = Format{}
+		case codeMonospace, codeStrike:
+			// Not shown.
+		case codeColor:
+			i += parseColors(s[i:], parseColorCode, &f)
+		case codeHexColor:
+			i += parseColors(s[i:], parseHexColor, &f)
+		}
+	}
+	flush()
+	return spans
+}
+
+// parseColors parses the colors following a color code, "fg" or "fg,bg",
+// with parse, and sets them in the Format. If there are none, it resets the
+// colors. It returns the length of the colors.
+func parseColors(s string, parse func(string) (Color, int), f *Format) int {
+	fg, n := parse(s)
+	if n == 0 {
+		f.Fg, f.Bg = NoColor, NoColor
+		return 0
+	}
+	f.Fg = fg
+	if strings.HasPrefix(s[n:], ",") {
+		// A comma not followed by a color is just text.
+		if bg, m := parse(s[n+1:]); m > 0 {
+			f.Bg = bg
+			n += 1 + m
+		}
+	}
+	return n
+}
+
+// parseColorCode parses a color number of one or two digits at the start of
+// s, and returns the color, and the number's length, or 0 if there isn't one.
+func parseColorCode(s string) (Color, int) {
+	n := 0
+	for n < 2 && n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	if n == 0 {
+		return NoColor, 0
+	}
+	code, _ := strconv.Atoi(s[:n])
+	switch {
+	case code > maxColorCode:
+		return NoColor, n
+	case code >= len(palette):
+		return nearestColor(extendedPalette[code-len(palette)]), n
+	}
+	return Color(code + 1), n
+}
+
+// parseHexColor parses an RGB value of six hex digits at the start of s, and
+// returns the closest color, and the value's length, or 0 if there isn't one.
+func parseHexColor(s string) (Color, int) {
+	if len(s) < 6 {
+		return NoColor, 0
+	}
+	rgb, err := strconv.ParseUint(s[:6], 16, 32)
+	if err != nil {
+		return NoColor, 0
+	}
+	return nearestColor(uint32(rgb)), 6
+}
+
+// StripFormatting returns the text without its formatting codes; see
+// ParseFormatting.
+func StripFormatting(s string) string {
+	if strings.IndexAny(s, formatCodes) < 0 {
+		return s
+	}
+	var b strings.Builder
+	for _, span := range ParseFormatting(s) {
+		b.WriteString(span.Text)
+	}
+	return b.String()
+}
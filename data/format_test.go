@@ -0,0 +1,97 @@
+package data_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cceckman/discoirc/data"
+)
+
+func TestParseFormatting(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		in   string
+		want []data.Span
+	}{
+		{in: "plain", want: []data.Span{{Text: "plain"}}},
+		{in: ""},
+		{
+			in: "a \x02bold\x02 \x1ditalic\x1d \x1funderlined\x1f \x16reversed\x16 word",
+			want: []data.Span{
+				{Text: "a "},
+				{Text: "bold", Format: data.Format{Bold: true}},
+				{Text: " "},
+				{Text: "italic", Format: data.Format{Italic: true}},
+				{Text: " "},
+				{Text: "underlined", Format: data.Format{Underline: true}},
+				{Text: " "},
+				{Text: "reversed", Format: data.Format{Reverse: true}},
+				{Text: " word"},
+			},
+		},
+		{
+			in: "\x02\x1dboth\x0f none",
+			want: []data.Span{
+				{Text: "both", Format: data.Format{Bold: true, Italic: true}},
+				{Text: " none"},
+			},
+		},
+		{
+			in: "\x034red\x03 \x0304,01red on black\x0399 default",
+			want: []data.Span{
+				{Text: "red", Format: data.Format{Fg: data.Red}},
+				{Text: " "},
+				{Text: "red on black", Format: data.Format{Fg: data.Red, Bg: data.Black}},
+				{Text: " default", Format: data.Format{Bg: data.Black}},
+			},
+		},
+		{
+			// Two digits at most; a comma without a color is text.
+			in: "\x03123, \x0312,",
+			want: []data.Span{
+				{Text: "3, ,", Format: data.Format{Fg: data.LightBlue}},
+			},
+		},
+		{
+			in: "\x0460,\x04FE0101,00007Fhex\x04 x",
+			want: []data.Span{
+				{Text: "60,"},
+				{Text: "hex", Format: data.Format{Fg: data.Red, Bg: data.Navy}},
+				{Text: " x"},
+			},
+		},
+		{
+			// Colors 16 to 98 are matched to the nearest of 0 to 15.
+			in: "\x0352extended\x0342,88 more",
+			want: []data.Span{
+				{Text: "extended", Format: data.Format{Fg: data.Red}},
+				{Text: " more", Format: data.Format{Fg: data.Orange, Bg: data.Black}},
+			},
+		},
+		{
+			in:   "\x11mono\x11 \x1estruck\x1e",
+			want: []data.Span{{Text: "mono struck"}},
+		},
+	} {
+		got := data.ParseFormatting(tt.in)
+		if diff := cmp.Diff(got, tt.want); diff != "" {
+			t.Errorf("%q: unexpected spans: (-got +want)\n%s", tt.in, diff)
+		}
+	}
+}
+
+func TestStripFormatting(t *testing.T) {
+	t.Parallel()
+	in := "\x02Hamlet\x02: \x0304,01to be\x03, or \x1dnot\x1d to be\x0f"
+	if got, want := data.StripFormatting(in), "Hamlet: to be, or not to be"; got != want {
+		t.Errorf("unexpected text: got: %q want: %q", got, want)
+	}
+	e := &data.MessageEvent{Nick: "hamlet", Text: in}
+	if got, want := e.String(), "<hamlet> Hamlet: to be, or not to be"; got != want {
+		t.Errorf("unexpected string: got: %q want: %q", got, want)
+	}
+	if got, want := data.Red.Code(), 4; got != want {
+		t.Errorf("unexpected code: got: %d want: %d", got, want)
+	}
+}
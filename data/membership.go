@@ -0,0 +1,89 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Change is a change in a channel's membership.
+type Change int
+
+const (
+	// Join is a JOIN of the channel.
+	Join Change = iota
+	// Part is a PART from the channel.
+	Part
+	// Quit is a QUIT from the network, seen in each channel the user was in.
+	Quit
+)
+
+// MembershipEvent is an Event indicating a user joined or left a channel.
+type MembershipEvent struct {
+	EventID
+
+	// Nick is the user who joined or left; User, Host, and Account identify
+	// them further, if they're known.
+	Nick    string
+	User    string
+	Host    string
+	Account string
+
+	Change Change
+	// Reason is the part or quit message, if any.
+	Reason string
+	// Time is when the change was seen.
+	Time time.Time
+	// Ignored indicates the user is ignored; see Ignored.
+	Ignored bool
+}
+
+var _ Event = &MembershipEvent{}
+
+// ID returns the scope & sequence of this Event.
+func (e *MembershipEvent) ID() *EventID {
+	return &e.EventID
+}
+
+// String implments fmt.Stringer.
+func (e *MembershipEvent) String() string {
+	verb := [...]string{Join: "JOIN", Part: "PART", Quit: "QUIT"}[e.Change]
+	if e.Reason == "" {
+		return fmt.Sprintf("%s %s", verb, e.Nick)
+	}
+	return fmt.Sprintf("%s %s (%s)", verb, e.Nick, StripFormatting(e.Reason))
+}
+
+// Netsplit returns the servers split from each other, if the Event is a quit
+// caused by a netsplit: one whose reason is two server names, e.g.
+// "hub.example.net leaf.example.net".
+func (e *MembershipEvent) Netsplit() (string, bool) {
+	if e.Change != Quit {
+		return "", false
+	}
+	f := strings.Split(e.Reason, " ")
+	if len(f) != 2 || !isServerName(f[0]) || !isServerName(f[1]) {
+		return "", false
+	}
+	return e.Reason, true
+}
+
+// isServerName reports whether s looks like a server's host name: at least two
+// dot-separated labels of letters, digits, and '-'.
+func isServerName(s string) bool {
+	labels := strings.Split(s, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, l := range labels {
+		if l == "" {
+			return false
+		}
+		for _, r := range l {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package data_test
+
+import (
+	"testing"
+
+	"github.com/cceckman/discoirc/data"
+)
+
+func TestMembershipEvent_Netsplit(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		e    data.MembershipEvent
+		want string
+	}{
+		{e: data.MembershipEvent{Change: data.Quit, Reason: "hub.example.net leaf.example.net"}, want: "hub.example.net leaf.example.net"},
+		{e: data.MembershipEvent{Change: data.Quit, Reason: "irc.a-b.org irc2.a-b.org"}, want: "irc.a-b.org irc2.a-b.org"},
+		{e: data.MembershipEvent{Change: data.Quit, Reason: "Ping timeout: 240 seconds"}},
+		{e: data.MembershipEvent{Change: data.Quit, Reason: "going home.now"}},
+		{e: data.MembershipEvent{Change: data.Quit, Reason: "a.net b.net c.net"}},
+		{e: data.MembershipEvent{Change: data.Quit, Reason: "a..net b.net"}},
+		{e: data.MembershipEvent{Change: data.Part, Reason: "hub.example.net leaf.example.net"}},
+	} {
+		got, ok := tt.e.Netsplit()
+		if ok != (tt.want != "") || got != tt.want {
+			t.Errorf("%s: unexpected netsplit: got: %q, %v want: %q", tt.e.String(), got, ok, tt.want)
+		}
+	}
+}
+
+func TestMembershipEvent_Filter(t *testing.T) {
+	t.Parallel()
+	e := &data.MembershipEvent{Nick: "alice", Change: data.Part, Reason: "bye"}
+	if got := e.String(); got != "PART alice (bye)" {
+		t.Errorf("unexpected text: got: %q want: %q", got, "PART alice (bye)")
+	}
+	f, _ := data.ParseFilter("kind:membership from:alice bye")
+	if !f.MatchEvent(e) {
+		t.Errorf("%q doesn't match %q", f.String(), e.String())
+	}
+}
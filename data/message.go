@@ -0,0 +1,62 @@
+package data
+
+import (
+	"fmt"
+	"time"
+)
+
+// Command is the kind of a message.
+type Command int
+
+const (
+	// Privmsg is an ordinary message.
+	Privmsg Command = iota
+	// Action is a CTCP ACTION, e.g. "/me waves".
+	Action
+	// Notice is a NOTICE, which clients never reply to automatically.
+	Notice
+)
+
+// MessageEvent is an Event indicating a message sent to a channel or user.
+type MessageEvent struct {
+	EventID
+
+	// Nick is the sender of the message; User, Host, and Account identify
+	// them further, if they're known.
+	Nick    string
+	User    string
+	Host    string
+	Account string
+	Command Command
+	// Text may contain formatting codes; see ParseFormatting.
+	Text string
+	// Time is when the message was sent or received.
+	Time time.Time
+
+	// Own indicates the user sent the message.
+	Own bool
+	// Highlight indicates the message mentions the user, or matches one of
+	// their highlight rules.
+	Highlight bool
+	// Ignored indicates the sender is ignored; see Ignored.
+	Ignored bool
+}
+
+var _ Event = &MessageEvent{}
+
+// ID returns the scope & sequence of this Event.
+func (e *MessageEvent) ID() *EventID {
+	return &e.EventID
+}
+
+// String implments fmt.Stringer. The text is without formatting codes.
+func (e *MessageEvent) String() string {
+	text := StripFormatting(e.Text)
+	switch e.Command {
+	case Action:
+		return fmt.Sprintf("* %s %s", e.Nick, text)
+	case Notice:
+		return fmt.Sprintf("-%s- %s", e.Nick, text)
+	}
+	return fmt.Sprintf("<%s> %s", e.Nick, text)
+}
@@ -0,0 +1,336 @@
+package data
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateFormat is the format of dates in filters, e.g. after:2006-01-02.
+const DateFormat = "2006-01-02"
+
+// ParseFilter parses the text form of a Filter: a list of terms, separated by
+// spaces, that matching Events must all satisfy.
+//
+//	in:#channel        a channel (or user) on any network
+//	in:network/#chan   a channel on a network
+//	net:network        anything on a network
+//	at:network/#chan   exactly one channel (or user) on a network; at:network
+//	                   is exactly the network, and at:/#chan the channel on
+//	                   any network
+//	kind:message       a kind of Event: message, membership, state, or other
+//	from:nick          a sender
+//	re:regexp          text matching a regular expression
+//	after:2006-01-02   sent on or after a date, in the local time zone, or a
+//	                   time, e.g. after:2006-01-02T15:04:05Z
+//	before:2006-01-02  sent before a date or time
+//	word, "a phrase"   text containing the word or phrase, ignoring case
+//
+// Networks, channels, and nicks may be glob patterns, e.g. in:#ops-*; several
+// in: or net: terms match any of them, as do several kind: or from: terms. An
+// at: term's names are matched exactly, not as globs. There may be only one
+// each of at:, after:, and before:, which can't be excluded. A term preceded
+// by '-' excludes the Events it matches. Values with spaces are quoted as Go
+// strings, e.g. re:"a b"; unquoted values are taken as they are.
+//
+// Filter.String returns a Filter's text form, which ParseFilter parses back to
+// a Filter matching the same Events.
+func ParseFilter(s string) (Filter, error) {
+	var f Filter
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		negate := strings.HasPrefix(s, "-")
+		if negate {
+			s = s[1:]
+		}
+		key, value, quoted, rest, err := nextTerm(s)
+		if err != nil {
+			return Filter{}, err
+		}
+		s = rest
+		if key == "" && value == "" && !quoted {
+			return Filter{}, fmt.Errorf("empty term")
+		}
+		if err := f.addTerm(key, value, negate); err != nil {
+			return Filter{}, err
+		}
+	}
+	return f, nil
+}
+
+// nextTerm returns the first term of s, and the rest of it: a key, if it's a
+// key:value term, and its value. A quoted value, or a quoted term without a
+// key, is unquoted.
+func nextTerm(s string) (key, value string, quoted bool, rest string, err error) {
+	if !strings.HasPrefix(s, `"`) {
+		end := strings.IndexAny(s, " \t")
+		if end < 0 {
+			end = len(s)
+		}
+		word := s[:end]
+		if i := strings.Index(word, ":"); i > 0 && isFilterKey(strings.ToLower(word[:i])) {
+			key, s = strings.ToLower(word[:i]), s[i+1:]
+		} else {
+			return "", word, false, s[end:], nil
+		}
+		if !strings.HasPrefix(s, `"`) {
+			end := strings.IndexAny(s, " \t")
+			if end < 0 {
+				end = len(s)
+			}
+			if end == 0 {
+				return "", "", false, "", fmt.Errorf("no value for %s:", key)
+			}
+			return key, s[:end], false, s[end:], nil
+		}
+	}
+	// A quoted string runs to the next quote that isn't escaped.
+	end := 1
+	for ; end < len(s) && s[end] != '"'; end++ {
+		if s[end] == '\\' {
+			end++
+		}
+	}
+	if end >= len(s) {
+		return "", "", false, "", fmt.Errorf("unterminated quote in %q", s)
+	}
+	value, err = strconv.Unquote(s[:end+1])
+	if err != nil {
+		return "", "", false, "", fmt.Errorf("invalid quoted string %s: %v", s[:end+1], err)
+	}
+	return key, value, true, s[end+1:], nil
+}
+
+func isFilterKey(k string) bool {
+	switch k {
+	case "at", "in", "net", "kind", "from", "re", "after", "before":
+		return true
+	}
+	return false
+}
+
+// addTerm adds a term to the Filter.
+func (f *Filter) addTerm(key, value string, negate bool) error {
+	switch key {
+	case "at":
+		if negate {
+			return fmt.Errorf("at:%s can't be excluded", value)
+		}
+		if f.MatchNet || f.MatchName {
+			return fmt.Errorf("more than one at: term")
+		}
+		f.Scope, f.MatchNet, f.MatchName = parseExactScope(value)
+	case "after", "before":
+		if negate {
+			return fmt.Errorf("%s:%s can't be excluded", key, value)
+		}
+		t, err := parseDate(value)
+		if err != nil {
+			return fmt.Errorf("invalid date in %s:%s (want e.g. %s)", key, value, DateFormat)
+		}
+		bound := &f.After
+		if key == "before" {
+			bound = &f.Before
+		}
+		if !bound.IsZero() {
+			return fmt.Errorf("more than one %s: term", key)
+		}
+		*bound = t
+	case "in", "net":
+		p := ScopePattern{Net: value}
+		if key == "in" {
+			p = parseScopePattern(value)
+		}
+		if p.Net == "" && p.Name == "" {
+			return fmt.Errorf("no scope in %s:%s", key, value)
+		}
+		if negate {
+			f.NotIn = append(f.NotIn, p)
+		} else {
+			f.In = append(f.In, p)
+		}
+	case "kind":
+		k, ok := parseKind(value)
+		if !ok {
+			return fmt.Errorf("unknown kind %q (want one of %s)", value, strings.Join(kindNames, ", "))
+		}
+		if negate {
+			f.NotKinds = append(f.NotKinds, k)
+		} else {
+			f.Kinds = append(f.Kinds, k)
+		}
+	case "from":
+		if negate {
+			f.NotFrom = append(f.NotFrom, value)
+		} else {
+			f.From = append(f.From, value)
+		}
+	case "re":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return fmt.Errorf("invalid regexp %q: %v", value, err)
+		}
+		if negate {
+			f.NotPatterns = append(f.NotPatterns, re)
+		} else {
+			f.Patterns = append(f.Patterns, re)
+		}
+	default:
+		if value == "" {
+			return nil
+		}
+		if negate {
+			f.NotText = append(f.NotText, value)
+		} else {
+			f.Text = append(f.Text, value)
+		}
+	}
+	return nil
+}
+
+// parseExactScope parses the value of an at: term: a network, a network and a
+// name separated by the first '/', or a name after a '/'.
+func parseExactScope(v string) (s Scope, matchNet, matchName bool) {
+	i := strings.Index(v, "/")
+	switch {
+	case i < 0:
+		return Scope{Net: v}, true, false
+	case i == 0:
+		return Scope{Name: v[1:]}, false, true
+	}
+	return Scope{Net: v[:i], Name: v[i+1:]}, true, true
+}
+
+// parseScopePattern parses the value of an in: term: a channel or user name,
+// or a network and a name, separated by '/'. Channel names may contain '/',
+// so a value starting with a channel prefix is just a name.
+func parseScopePattern(v string) ScopePattern {
+	if v == "" || strings.ContainsAny(v[:1], "#&+!") {
+		return ScopePattern{Name: v}
+	}
+	if i := strings.Index(v, "/"); i >= 0 {
+		net := v[:i]
+		if net == "*" {
+			// Any network, as for an empty pattern.
+			net = ""
+		}
+		return ScopePattern{Net: net, Name: v[i+1:]}
+	}
+	return ScopePattern{Name: v}
+}
+
+// parseDate parses the value of an after: or before: term: a date, at the start
+// of the day in the local time zone, or an RFC 3339 time.
+func parseDate(v string) (time.Time, error) {
+	if t, err := time.ParseInLocation(DateFormat, v, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339Nano, v)
+}
+
+// formatDate returns the value of an after: or before: term for the time.
+func formatDate(t time.Time) string {
+	local := t.In(time.Local)
+	if y, m, d := local.Date(); local.Equal(time.Date(y, m, d, 0, 0, 0, 0, time.Local)) {
+		return local.Format(DateFormat)
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func parseKind(v string) (Kind, bool) {
+	for k, name := range kindNames {
+		if strings.EqualFold(v, name) {
+			return Kind(k), true
+		}
+	}
+	return KindOther, false
+}
+
+// String returns the text form of the Filter; see ParseFilter. The zero
+// Filter's is empty.
+func (f *Filter) String() string {
+	var terms []string
+	addTerms := func(prefix string, values []string) {
+		for _, v := range values {
+			terms = append(terms, prefix+quoteValue(v))
+		}
+	}
+
+	switch {
+	case f.MatchNet && f.MatchName:
+		terms = append(terms, "at:"+quoteValue(f.Net+"/"+f.Name))
+	case f.MatchNet:
+		terms = append(terms, "at:"+quoteValue(f.Net))
+	case f.MatchName:
+		terms = append(terms, "at:"+quoteValue("/"+f.Name))
+	}
+	for i, ps := range [][]ScopePattern{f.In, f.NotIn} {
+		for _, p := range ps {
+			t := scopeTerm(p)
+			if i == 1 {
+				t = "-" + t
+			}
+			terms = append(terms, t)
+		}
+	}
+	for i, ks := range [][]Kind{f.Kinds, f.NotKinds} {
+		var names []string
+		for _, k := range ks {
+			names = append(names, k.String())
+		}
+		addTerms([]string{"kind:", "-kind:"}[i], names)
+	}
+	addTerms("from:", f.From)
+	addTerms("-from:", f.NotFrom)
+	for i, res := range [][]*regexp.Regexp{f.Patterns, f.NotPatterns} {
+		var exprs []string
+		for _, re := range res {
+			exprs = append(exprs, re.String())
+		}
+		addTerms([]string{"re:", "-re:"}[i], exprs)
+	}
+	if !f.After.IsZero() {
+		terms = append(terms, "after:"+formatDate(f.After))
+	}
+	if !f.Before.IsZero() {
+		terms = append(terms, "before:"+formatDate(f.Before))
+	}
+	for i, ts := range [][]string{f.Text, f.NotText} {
+		for _, t := range ts {
+			if strings.ContainsAny(t, ":") || strings.HasPrefix(t, "-") {
+				t = strconv.Quote(t)
+			} else {
+				t = quoteValue(t)
+			}
+			if i == 1 {
+				t = "-" + t
+			}
+			terms = append(terms, t)
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// scopeTerm returns the in: or net: term for the pattern.
+func scopeTerm(p ScopePattern) string {
+	switch {
+	case p.Name == "":
+		return "net:" + quoteValue(p.Net)
+	case p.Net == "" && parseScopePattern(p.Name).Net == "":
+		return "in:" + quoteValue(p.Name)
+	case p.Net == "":
+		// Any network.
+		return "in:" + quoteValue("*/"+p.Name)
+	}
+	return "in:" + quoteValue(p.Net+"/"+p.Name)
+}
+
+// quoteValue quotes the value if it's empty, contains spaces, or starts with a
+// quote.
+func quoteValue(v string) string {
+	if v == "" || strings.ContainsAny(v, " \t") || strings.HasPrefix(v, `"`) {
+		return strconv.Quote(v)
+	}
+	return v
+}
@@ -0,0 +1,74 @@
+// Package files has the helpers discoirc uses for the files it keeps, e.g. its
+// input history and outbox.
+package files
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// DataPath returns the location of discoirc's data file with the name:
+// $XDG_DATA_HOME/discoirc/name, or ~/.local/share/discoirc/name.
+func DataPath(name string) string {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".local", "share")
+	}
+	return filepath.Join(dir, "discoirc", name)
+}
+
+// WriteAtomic replaces the file at path with contents and the mode, creating
+// its directory if need be. Readers see either the old file or the new one,
+// never a partly written one.
+func WriteAtomic(path string, contents []byte, mode os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(contents)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = tmp.Chmod(mode)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Update changes the file at path: it calls update with the file's contents,
+// or nil if there's no file, and replaces the file with what update returns,
+// as WriteAtomic does. Updates of the file by other processes wait until this
+// one is done, so none of their changes are lost.
+func Update(path string, mode os.FileMode, update func(contents []byte) ([]byte, error)) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	unlock, err := lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	contents, err = update(contents)
+	if err != nil {
+		return err
+	}
+	return WriteAtomic(path, contents, mode)
+}
@@ -0,0 +1,104 @@
+package files_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cceckman/discoirc/internal/files"
+)
+
+func TestDataPath(t *testing.T) {
+	defer os.Setenv("XDG_DATA_HOME", os.Getenv("XDG_DATA_HOME"))
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+
+	os.Setenv("XDG_DATA_HOME", "/data")
+	if got, want := files.DataPath("history"), "/data/discoirc/history"; got != want {
+		t.Errorf("unexpected path: got: %q want: %q", got, want)
+	}
+	os.Setenv("XDG_DATA_HOME", "")
+	os.Setenv("HOME", "/home/yorick")
+	if got, want := files.DataPath("outbox"), "/home/yorick/.local/share/discoirc/outbox"; got != want {
+		t.Errorf("unexpected path: got: %q want: %q", got, want)
+	}
+}
+
+func TestWriteAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "discoirc", "outbox")
+
+	for _, contents := range []string{"alas", "poor yorick"} {
+		if err := files.WriteAtomic(path, []byte(contents), 0600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != contents {
+			t.Errorf("unexpected contents: got: %q want: %q", got, contents)
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Mode().Perm(); got != 0600 {
+		t.Errorf("unexpected mode: got: %v want: %v", got, os.FileMode(0600))
+	}
+	if entries, _ := ioutil.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("unexpected files left behind: %v", entries)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "discoirc", "history")
+
+	// Each Update sees the changes of those before it.
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := files.Update(path, 0600, func(contents []byte) ([]byte, error) {
+				return append(contents, '.'), nil
+			})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Repeat(".", n); string(got) != want {
+		t.Errorf("unexpected contents: got: %q want: %q", got, want)
+	}
+
+	// A failed Update leaves the file as it was.
+	err = files.Update(path, 0600, func([]byte) ([]byte, error) {
+		return nil, errors.New("alas")
+	})
+	if err == nil {
+		t.Errorf("unexpected success")
+	}
+	if got, _ := ioutil.ReadFile(path); len(got) != n {
+		t.Errorf("unexpected contents after failure: got: %q", got)
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+// +build !windows
+
+package files
+
+import (
+	"os"
+	"syscall"
+)
+
+// lock waits for, and takes, the lock on the file at path, which is held in
+// path.lock; the lock is released by unlock, or when the process exits.
+func lock(path string) (unlock func(), err error) {
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+	// Closing the file releases the lock.
+	return func() { f.Close() }, nil
+}
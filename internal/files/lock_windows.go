@@ -0,0 +1,7 @@
+package files
+
+// lock does nothing: Windows has no flock, so Updates there aren't kept from
+// overlapping.
+func lock(path string) (unlock func(), err error) {
+	return func() {}, nil
+}
@@ -0,0 +1,383 @@
+package channel
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cceckman/discoirc/backend/ignore"
+	"github.com/cceckman/discoirc/data"
+)
+
+// Command is a command the user can enter in the channel view, e.g. "/join".
+type Command struct {
+	Name string
+	// Args describes the command's arguments, e.g. "#channel [key]".
+	Args string
+	Help string
+	// Run runs the command with the (unparsed) rest of the input line.
+	Run func(v *View, args string)
+	// Complete returns the possible values of the command's nth argument,
+	// counting from 0. It may be nil if the arguments can't be completed.
+	Complete func(v *View, n int) []string
+}
+
+// Commands are the commands available in the channel view, by name.
+var Commands = map[string]*Command{}
+
+func init() {
+	for _, c := range []*Command{
+		{
+			Name: "client",
+			Help: "show the client view",
+			Run: func(v *View, _ string) {
+				if v.ui != nil {
+					v.ui.ActivateClient()
+				}
+			},
+		},
+		{
+			Name: "quit",
+			Help: "quit discoirc",
+			Run: func(v *View, _ string) {
+				if v.ui != nil {
+					v.ui.Quit()
+				}
+			},
+		},
+		{
+			Name:     "join",
+			Args:     "#channel [key]",
+			Help:     "join a channel on this network",
+			Run:      runJoin,
+			Complete: completeChannel,
+		},
+		{
+			Name:     "part",
+			Args:     "[#channel]",
+			Help:     "leave this or another channel",
+			Run:      runPart,
+			Complete: completeChannel,
+		},
+		{
+			Name: "nick",
+			Args: "nick",
+			Help: "change your nick on this network",
+			Run:  runNick,
+		},
+		{
+			Name: "cancel",
+			Args: "[all]",
+			Help: "cancel the last, or every, message waiting to be sent",
+			Run:  runCancel,
+		},
+		{
+			Name: "dnd",
+			Args: "[on|off]",
+			Help: "turn notifications off, or back on",
+			Run:  runDND,
+		},
+		{
+			Name: "search",
+			Args: "[text]",
+			Help: "search the channel's messages, or end the search",
+			Run:  (*View).searchEvents,
+		},
+		{
+			Name: "only",
+			Args: "[query]",
+			Help: "show only the channel's messages that match the query, or all of them",
+			Run:  runOnly,
+		},
+		{
+			Name: "expand",
+			Args: "[on|off]",
+			Help: "list the nicks in folded netsplits, or stop listing them",
+			Run:  runExpand,
+		},
+		{
+			Name:     "ignore",
+			Args:     "[-network|-global] [-for duration] [nick!user@host|account:name|re:regexp]",
+			Help:     "ignore a user in this channel, on this network, or everywhere, or list who's ignored",
+			Run:      runIgnore,
+			Complete: completeIgnore,
+		},
+		{
+			Name: "unignore",
+			Args: "[-network|-global] nick!user@host|account:name|re:regexp",
+			Help: "stop ignoring a user",
+			Run:  runUnignore,
+		},
+		{
+			Name: "reveal",
+			Args: "[on|off]",
+			Help: "show the messages of ignored users, or hide them again",
+			Run:  runReveal,
+		},
+		{
+			Name: "filter",
+			Args: "[query]",
+			Help: "show the messages in every channel that match the query",
+			Run: func(v *View, args string) {
+				if v.ui != nil {
+					v.ui.ActivateFilter(args)
+				}
+			},
+		},
+		{
+			Name: "save",
+			Help: "save the current configuration",
+			Run:  runSave,
+		},
+	} {
+		Commands[c.Name] = c
+	}
+}
+
+// usage returns a short description of how to use the command.
+func (c *Command) usage() string {
+	if c.Args == "" {
+		return "usage: /" + c.Name
+	}
+	return "usage: /" + c.Name + " " + c.Args
+}
+
+// isCommand reports whether the input line is a command: it starts with '/',
+// but not "//", which sends a message starting with '/'.
+func isCommand(line string) bool {
+	return strings.HasPrefix(line, "/") && !strings.HasPrefix(line, "//")
+}
+
+// runCommand runs the command in the input line, if it's one of Commands, and
+// reports whether it was. Other commands, e.g. "/me", are for the backend.
+func (v *View) runCommand(line string) bool {
+	name, args := line[1:], ""
+	if i := strings.IndexAny(name, " \t"); i >= 0 {
+		name, args = name[:i], strings.TrimSpace(name[i:])
+	}
+	c, ok := Commands[strings.ToLower(name)]
+	if !ok {
+		return false
+	}
+	c.Run(v, args)
+	return true
+}
+
+func runJoin(v *View, args string) {
+	f := strings.Fields(args)
+	if len(f) < 1 || len(f) > 2 {
+		v.setNotice(Commands["join"].usage())
+		return
+	}
+	key := ""
+	if len(f) == 2 {
+		key = f[1]
+	}
+	if v.manager == nil {
+		return
+	}
+	v.manager.Join(data.Scope{Net: v.scope.Net, Name: f[0]}, key)
+	if f[0] != v.scope.Name && v.ui != nil {
+		v.ui.ActivateChannel(v.scope.Net, f[0])
+	}
+}
+
+func runPart(v *View, args string) {
+	f := strings.Fields(args)
+	if len(f) > 1 {
+		v.setNotice(Commands["part"].usage())
+		return
+	}
+	s := v.scope
+	if len(f) == 1 {
+		s.Name = f[0]
+	}
+	if v.manager != nil {
+		v.manager.Part(s)
+	}
+}
+
+func runNick(v *View, args string) {
+	f := strings.Fields(args)
+	if len(f) != 1 {
+		v.setNotice(Commands["nick"].usage())
+		return
+	}
+	if v.manager != nil {
+		v.manager.SetNick(v.scope.Net, f[0])
+	}
+}
+
+func runSave(v *View, _ string) {
+	if v.saver == nil {
+		return
+	}
+	if err := v.saver.Save(); err != nil {
+		v.setNotice(fmt.Sprintf("not saved: %v", err))
+		return
+	}
+	v.setNotice("configuration saved")
+}
+
+func runCancel(v *View, args string) {
+	if args != "" && args != "all" {
+		v.setNotice(Commands["cancel"].usage())
+		return
+	}
+	if v.outbox == nil {
+		return
+	}
+	pending := v.outbox.Pending(v.scope)
+	if len(pending) == 0 {
+		v.setNotice("no messages waiting")
+		return
+	}
+	if args == "" {
+		pending = pending[len(pending)-1:]
+	}
+	n := 0
+	for _, m := range pending {
+		if v.outbox.Cancel(v.scope, m.ID) {
+			n++
+		}
+	}
+	v.showPending()
+	if n == 1 {
+		v.setNotice("cancelled 1 message")
+	} else {
+		v.setNotice(fmt.Sprintf("cancelled %d messages", n))
+	}
+}
+
+func runDND(v *View, args string) {
+	if v.notify == nil {
+		return
+	}
+	switch args {
+	case "":
+		v.notify.SetDoNotDisturb(!v.notify.DoNotDisturb())
+	case "on":
+		v.notify.SetDoNotDisturb(true)
+	case "off":
+		v.notify.SetDoNotDisturb(false)
+	default:
+		v.setNotice(Commands["dnd"].usage())
+		return
+	}
+	if v.notify.DoNotDisturb() {
+		v.setNotice("notifications off")
+	} else {
+		v.setNotice("notifications on")
+	}
+}
+
+func runExpand(v *View, args string) {
+	switch args {
+	case "":
+		v.events.SetExpanded(!v.events.expanded)
+	case "on":
+		v.events.SetExpanded(true)
+	case "off":
+		v.events.SetExpanded(false)
+	default:
+		v.setNotice(Commands["expand"].usage())
+	}
+}
+
+// ignoreScope parses the options of /ignore and /unignore that precede the
+// target: the scope, and, if forOK, how long to ignore the target for.
+func (v *View) ignoreScope(args []string, forOK bool) (s data.Scope, d time.Duration, rest []string, ok bool) {
+	s = v.scope
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		switch {
+		case args[0] == "-network":
+			s = data.Scope{Net: v.scope.Net}
+		case args[0] == "-global":
+			s = data.Scope{}
+		case args[0] == "-for" && forOK && len(args) > 1:
+			var err error
+			if d, err = time.ParseDuration(args[1]); err != nil || d <= 0 {
+				return s, 0, nil, false
+			}
+			args = args[1:]
+		default:
+			return s, 0, nil, false
+		}
+		args = args[1:]
+	}
+	return s, d, args, true
+}
+
+func runIgnore(v *View, args string) {
+	s, d, rest, ok := v.ignoreScope(strings.Fields(args), true)
+	if !ok || len(rest) > 1 || (len(rest) == 0 && d != 0) {
+		v.setNotice(Commands["ignore"].usage())
+		return
+	}
+	if v.ignorer == nil {
+		return
+	}
+	if len(rest) == 0 {
+		var rules []string
+		for _, r := range v.ignorer.Ignores(v.scope) {
+			rules = append(rules, r.String())
+		}
+		if len(rules) == 0 {
+			v.setNotice("ignoring no one")
+		} else {
+			v.setNotice("ignoring " + strings.Join(rules, ", "))
+		}
+		return
+	}
+	r, err := ignore.ParseRule(s, rest[0])
+	if err != nil {
+		v.setNotice(fmt.Sprintf("not ignored: %v", err))
+		return
+	}
+	if d != 0 {
+		r.Expires = time.Now().Add(d)
+	}
+	v.ignorer.Ignore(r)
+	v.setNotice("ignoring " + r.String())
+}
+
+func runUnignore(v *View, args string) {
+	s, _, rest, ok := v.ignoreScope(strings.Fields(args), false)
+	if !ok || len(rest) != 1 {
+		v.setNotice(Commands["unignore"].usage())
+		return
+	}
+	if v.ignorer == nil {
+		return
+	}
+	// Unignore the rule's target, e.g. "polonius!*@*" for "polonius".
+	r, err := ignore.ParseRule(s, rest[0])
+	if err != nil {
+		v.setNotice(err.Error())
+		return
+	}
+	if v.ignorer.Unignore(s, r.Target()) {
+		v.setNotice("no longer ignoring " + r.String())
+	} else {
+		v.setNotice("no /ignore of " + r.String() + " to remove")
+	}
+}
+
+func runReveal(v *View, args string) {
+	switch args {
+	case "":
+		v.events.SetReveal(!v.events.reveal)
+	case "on":
+		v.events.SetReveal(true)
+	case "off":
+		v.events.SetReveal(false)
+	default:
+		v.setNotice(Commands["reveal"].usage())
+		return
+	}
+	if v.events.reveal {
+		v.setNotice("showing ignored users' messages")
+	} else {
+		v.setNotice("hiding ignored users' messages")
+	}
+}
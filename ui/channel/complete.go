@@ -0,0 +1,126 @@
+package channel
+
+import (
+	"sort"
+	"strings"
+)
+
+// completion is a cycle through the completions of the last word of the
+// input line.
+type completion struct {
+	// head is the input before the word being completed.
+	head string
+	// matches are the possible replacements for the word.
+	matches []string
+	i       int
+	// line is the input line after the last completion. If the input has
+	// changed since, a new cycle starts.
+	line string
+}
+
+// complete replaces the last word of the input line with its next completion,
+// or the previous one if step is negative.
+func (v *View) complete(step int) {
+	text := v.input.Text()
+	c := v.completion
+	if c == nil || text != c.line {
+		c = v.completions(text)
+		if c == nil {
+			return
+		}
+		v.completion = c
+		if step < 0 {
+			c.i = len(c.matches) - 1
+		}
+	} else {
+		c.i = (c.i + step + len(c.matches)) % len(c.matches)
+	}
+	c.line = c.head + c.matches[c.i]
+	v.input.SetText(c.line)
+}
+
+// completions returns the completions of the last word of the input, or nil if
+// there are none:
+//
+//	/command names at the start of the line;
+//	/command arguments, as the command's Complete gives them;
+//	channels on the network, for words starting with '#';
+//	and nicks of the channel's members, most recently active first.
+//
+// Each completion ends in a space; nicks at the start of the line end in ": ".
+func (v *View) completions(text string) *completion {
+	i := strings.LastIndex(text, " ")
+	head, word := text[:i+1], text[i+1:]
+
+	var candidates []string
+	suffix := " "
+	switch {
+	case head == "" && strings.HasPrefix(word, "/"):
+		for name := range Commands {
+			candidates = append(candidates, "/"+name)
+		}
+		sort.Strings(candidates)
+	case strings.HasPrefix(head, "/"):
+		f := strings.Fields(head)
+		c, ok := Commands[strings.ToLower(f[0][1:])]
+		if !ok || c.Complete == nil {
+			return nil
+		}
+		candidates = c.Complete(v, len(f)-1)
+	case strings.HasPrefix(word, "#"):
+		candidates = v.channels()
+	default:
+		candidates = v.members()
+		if head == "" {
+			suffix = ": "
+		}
+	}
+
+	c := &completion{head: head}
+	for _, m := range candidates {
+		if strings.HasPrefix(strings.ToLower(m), strings.ToLower(word)) {
+			c.matches = append(c.matches, m+suffix)
+		}
+	}
+	if len(c.matches) == 0 {
+		return nil
+	}
+	return c
+}
+
+// channels returns the channels on the view's network.
+func (v *View) channels() []string {
+	if v.directory == nil {
+		return nil
+	}
+	return v.directory.Channels(v.scope.Net)
+}
+
+// members returns the nicks of the channel's members, other than the user.
+func (v *View) members() []string {
+	if v.directory == nil {
+		return nil
+	}
+	var r []string
+	for _, m := range v.directory.Members(v.scope) {
+		if m != v.nick.Text() {
+			r = append(r, m)
+		}
+	}
+	return r
+}
+
+// completeChannel completes a command's first argument with the channels on
+// the network.
+func completeChannel(v *View, arg int) []string {
+	if arg != 0 {
+		return nil
+	}
+	return v.channels()
+}
+
+// completeIgnore completes the target of /ignore, after any options, with the
+// nicks of the channel's members.
+func completeIgnore(v *View, _ int) []string {
+	return v.members()
+}
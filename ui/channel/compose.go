@@ -0,0 +1,50 @@
+package channel
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The input line is the last line of the message being composed; the lines
+// before it are kept in the View's draft, and counted in the input bar.
+
+// text returns the whole message being composed, with its lines separated by
+// '\n'.
+func (v *View) text() string {
+	return strings.Join(append(append([]string(nil), v.draft...), v.input.Text()), "\n")
+}
+
+// setText replaces the message being composed. Its last line is put in the
+// input line.
+func (v *View) setText(text string) {
+	lines := strings.Split(text, "\n")
+	v.draft = lines[:len(lines)-1]
+	v.input.SetText(lines[len(lines)-1])
+	v.showLines()
+}
+
+// newline starts a new line of the message.
+func (v *View) newline() {
+	v.draft = append(v.draft, v.input.Text())
+	v.input.SetText("")
+	v.showLines()
+}
+
+// joinLine joins the input line to the end of the line before it, which
+// becomes the input line.
+func (v *View) joinLine() {
+	last := v.draft[len(v.draft)-1]
+	v.draft = v.draft[:len(v.draft)-1]
+	v.input.SetText(last + v.input.Text())
+	v.showLines()
+}
+
+// showLines shows the number of lines before the input line, if there are
+// any.
+func (v *View) showLines() {
+	if len(v.draft) == 0 {
+		v.lines.SetText("")
+		return
+	}
+	v.lines.SetText(fmt.Sprintf("[+%d] ", len(v.draft)))
+}
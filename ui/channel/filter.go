@@ -0,0 +1,51 @@
+package channel
+
+import (
+	"fmt"
+
+	"github.com/cceckman/discoirc/data"
+)
+
+// runOnly sets the filter of the channel's Events to the query (see
+// data.ParseFilter), or, with no query, removes it.
+func runOnly(v *View, query string) {
+	if query == "" {
+		v.only = nil
+		v.events.SetFilter(nil)
+		v.showFilter()
+		return
+	}
+	f, err := data.ParseFilter(query)
+	if err != nil {
+		v.setNotice(err.Error())
+		return
+	}
+	v.only = &f
+	v.events.SetFilter(v.only)
+	v.showFilter()
+}
+
+// toggleFilter turns the filter set by "/only" off, or back on.
+func (v *View) toggleFilter() {
+	if v.only == nil {
+		v.setNotice("no filter")
+		return
+	}
+	if v.events.filter != nil {
+		v.events.SetFilter(nil)
+	} else {
+		v.events.SetFilter(v.only)
+	}
+	v.showFilter()
+}
+
+// showFilter shows the active filter, and how many Events it hides, in the
+// status bar.
+func (v *View) showFilter() {
+	f := v.events.filter
+	if f == nil {
+		v.filtered.SetText("")
+		return
+	}
+	v.filtered.SetText(fmt.Sprintf(" only %s (%d hidden)", f.String(), v.events.Hidden()))
+}
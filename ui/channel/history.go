@@ -0,0 +1,101 @@
+package channel
+
+import (
+	"fmt"
+
+	"github.com/marcusolsson/tui-go"
+
+	"github.com/cceckman/discoirc/ui/history"
+)
+
+// historySearch is an incremental search of the input history in progress.
+type historySearch struct {
+	query string
+	// failed indicates the query doesn't match any line.
+	failed bool
+}
+
+// cursor returns the position in the input history, starting a new one at
+// the newest line if there isn't one.
+func (v *View) cursor() *history.Cursor {
+	if v.histCursor == nil {
+		v.histCursor = v.history.Cursor(v.scope)
+	}
+	return v.histCursor
+}
+
+// historyPrevious replaces the input line with the previous line in the
+// history.
+func (v *View) historyPrevious() {
+	if line, ok := v.cursor().Previous(v.text()); ok {
+		v.setText(line)
+	}
+}
+
+// historyNext replaces the input line with the next line in the history, or
+// the line that was being edited.
+func (v *View) historyNext() {
+	if line, ok := v.cursor().Next(); ok {
+		v.setText(line)
+	}
+}
+
+// searchHistory starts an incremental search of the history, or, if one is in
+// progress, finds the next older match.
+func (v *View) searchHistory() {
+	if v.search == nil {
+		v.search = &historySearch{}
+		v.showSearch()
+		return
+	}
+	v.findHistory(true)
+}
+
+// findHistory shows the newest line matching the search, starting from the
+// current match, or the one before it if again is set.
+func (v *View) findHistory(again bool) {
+	line, ok := v.cursor().Search(v.search.query, v.text(), again)
+	v.search.failed = !ok
+	if ok {
+		v.setText(line)
+	}
+	v.showSearch()
+}
+
+func (v *View) showSearch() {
+	msg := fmt.Sprintf("history search: %q", v.search.query)
+	if v.search.failed {
+		msg += " (no match)"
+	}
+	v.setNotice(msg)
+}
+
+// searchKey handles a key press while searching the history: characters and
+// Backspace edit the query, Enter accepts the match, and Esc cancels the
+// search. It reports whether the key was handled; other keys end the search,
+// keeping the match, and are handled as usual.
+func (v *View) searchKey(ev tui.KeyEvent) bool {
+	switch {
+	case ev.Key == tui.KeyRune && ev.Modifiers&tui.ModAlt == 0:
+		v.search.query += string(ev.Rune)
+		v.findHistory(false)
+	case ev.Key == tui.KeyBackspace || ev.Key == tui.KeyBackspace2:
+		if q := []rune(v.search.query); len(q) > 0 {
+			v.search.query = string(q[:len(q)-1])
+		}
+		v.findHistory(false)
+	case ev.Key == tui.KeyEnter:
+		v.endSearch()
+	case ev.Key == tui.KeyEsc:
+		v.setText(v.cursor().Reset(v.text()))
+		v.endSearch()
+	default:
+		return false
+	}
+	return true
+}
+
+func (v *View) endSearch() {
+	v.search = nil
+	v.setNotice("")
+}
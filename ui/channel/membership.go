@@ -0,0 +1,162 @@
+package channel
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cceckman/discoirc/config"
+	"github.com/cceckman/discoirc/data"
+)
+
+// foldMembership returns the Events to show in place of the given ones, oldest
+// first, with joins, parts, and quits hidden, collapsed, or folded as the
+// configuration says. If expanded is set, folded netsplits list their nicks.
+func foldMembership(events data.EventList, m config.Membership, expanded bool) data.EventList {
+	if m.FoldNetsplits {
+		events = foldNetsplits(events, expanded)
+	}
+	if m.HideIdle > 0 {
+		events = hideIdle(events, m.HideIdle)
+	}
+	if m.Collapse {
+		events = collapseMembership(events)
+	}
+	return events
+}
+
+// netsplit is the quits caused by a netsplit, shown as one Event. Its ID is
+// that of the last quit.
+type netsplit struct {
+	data.EventID
+	servers string
+	nicks   []string
+	// expanded is set to list the nicks.
+	expanded bool
+}
+
+func (e *netsplit) ID() *data.EventID { return &e.EventID }
+
+func (e *netsplit) String() string {
+	s := fmt.Sprintf("netsplit %s: %d quit", e.servers, len(e.nicks))
+	if e.expanded {
+		s += ": " + strings.Join(e.nicks, ", ")
+	}
+	return s
+}
+
+// foldNetsplits folds each run of quits caused by the same netsplit into a
+// netsplit.
+func foldNetsplits(events data.EventList, expanded bool) data.EventList {
+	var out data.EventList
+	for _, e := range events {
+		m, ok := e.(*data.MembershipEvent)
+		if !ok {
+			out = append(out, e)
+			continue
+		}
+		servers, ok := m.Netsplit()
+		if !ok {
+			out = append(out, e)
+			continue
+		}
+		if len(out) > 0 {
+			if n, ok := out[len(out)-1].(*netsplit); ok && n.servers == servers {
+				n.EventID = m.EventID
+				n.nicks = append(n.nicks, m.Nick)
+				continue
+			}
+		}
+		out = append(out, &netsplit{
+			EventID:  m.EventID,
+			servers:  servers,
+			nicks:    []string{m.Nick},
+			expanded: expanded,
+		})
+	}
+	return out
+}
+
+// hideIdle hides the joins, parts, and quits of users who didn't speak within
+// d before them, or, for joins, within d after. Those of unknown time are
+// shown.
+func hideIdle(events data.EventList, d time.Duration) data.EventList {
+	spoke := make(map[string][]time.Time)
+	for _, e := range events {
+		if m, ok := e.(*data.MessageEvent); ok {
+			nick := strings.ToLower(m.Nick)
+			spoke[nick] = append(spoke[nick], m.Time)
+		}
+	}
+	var out data.EventList
+	for _, e := range events {
+		m, ok := e.(*data.MembershipEvent)
+		if !ok || m.Time.IsZero() {
+			out = append(out, e)
+			continue
+		}
+		from, to := m.Time.Add(-d), m.Time
+		if m.Change == data.Join {
+			to = m.Time.Add(d)
+		}
+		for _, t := range spoke[strings.ToLower(m.Nick)] {
+			if !t.Before(from) && !t.After(to) {
+				out = append(out, e)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// membershipSummary is consecutive joins, parts, and quits, shown as one
+// Event. Its ID is that of the last of them.
+type membershipSummary struct {
+	data.EventID
+	joined, left int
+}
+
+func (e *membershipSummary) ID() *data.EventID { return &e.EventID }
+
+func (e *membershipSummary) String() string {
+	var parts []string
+	if e.joined > 0 {
+		parts = append(parts, fmt.Sprintf("+%d joined", e.joined))
+	}
+	if e.left > 0 {
+		parts = append(parts, fmt.Sprintf("-%d left", e.left))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// collapseMembership collapses each run of more than one join, part, or quit
+// into a membershipSummary.
+func collapseMembership(events data.EventList) data.EventList {
+	var out data.EventList
+	for i := 0; i < len(events); {
+		j := i
+		for j < len(events) {
+			if _, ok := events[j].(*data.MembershipEvent); !ok {
+				break
+			}
+			j++
+		}
+		if j-i < 2 {
+			// Not a run; keep the Event.
+			out = append(out, events[i])
+			i++
+			continue
+		}
+		s := &membershipSummary{EventID: *events[j-1].ID()}
+		for _, e := range events[i:j] {
+			if e.(*data.MembershipEvent).Change == data.Join {
+				s.joined++
+			} else {
+				s.left++
+			}
+		}
+		out = append(out, s)
+		i = j
+	}
+	return out
+}
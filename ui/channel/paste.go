@@ -0,0 +1,118 @@
+package channel
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/marcusolsson/tui-go"
+)
+
+// Terminals with bracketed paste enabled mark pasted text with ESC [ 200 ~
+// before it and ESC [ 201 ~ after it. They arrive as Alt+[, and then the rest
+// as separate runes.
+const (
+	pasteStart = "200~"
+	pasteEnd   = "201~"
+)
+
+// pasteDetector finds the start and end of pastes among key presses.
+type pasteDetector struct {
+	// pending are the keys of what may be a paste marker, seen so far.
+	pending []tui.KeyEvent
+	pasting bool
+
+	// key handles a key press, which is part of a paste if pasted is set.
+	key func(ev tui.KeyEvent, pasted bool)
+	// end is called at the end of a paste.
+	end func()
+}
+
+// feed handles a key press. Keys that may start a marker are held until
+// they're known not to be one.
+func (d *pasteDetector) feed(ev tui.KeyEvent) {
+	if len(d.pending) == 0 {
+		if ev.Key == tui.KeyRune && ev.Rune == '[' && ev.Modifiers&tui.ModAlt != 0 {
+			d.pending = append(d.pending, ev)
+			return
+		}
+		d.key(ev, d.pasting)
+		return
+	}
+
+	d.pending = append(d.pending, ev)
+	var seq []rune
+	for _, p := range d.pending[1:] {
+		if p.Key != tui.KeyRune || p.Modifiers != 0 {
+			seq = nil
+			break
+		}
+		seq = append(seq, p.Rune)
+	}
+	if seq != nil {
+		switch s := string(seq); {
+		case s == pasteStart:
+			d.pending = nil
+			d.pasting = true
+			return
+		case s == pasteEnd:
+			d.pending = nil
+			if d.pasting {
+				d.pasting = false
+				d.end()
+			}
+			return
+		case strings.HasPrefix(pasteStart, s) || strings.HasPrefix(pasteEnd, s):
+			return
+		}
+	}
+
+	// Not a marker after all. The keys after the first may still start one.
+	keys := d.pending
+	d.pending = nil
+	d.key(keys[0], d.pasting)
+	for _, k := range keys[1:] {
+		d.feed(k)
+	}
+}
+
+// pasteKey handles a key that's part of a paste: characters are inserted in
+// the input line, and newlines start a new line. Other keys are dropped, so
+// pasted text can't run commands.
+func (v *View) pasteKey(ev tui.KeyEvent) {
+	switch ev.Key {
+	case tui.KeyRune:
+		v.input.OnKeyEvent(tui.KeyEvent{Key: tui.KeyRune, Rune: ev.Rune})
+	case tui.KeyTab:
+		v.input.OnKeyEvent(tui.KeyEvent{Key: tui.KeyRune, Rune: ' '})
+	case tui.KeyEnter, tui.KeyCtrlJ:
+		v.newline()
+	}
+}
+
+// endPaste asks the user to confirm sending a paste of several lines, so
+// pasting into the wrong window doesn't flood the channel.
+func (v *View) endPaste() {
+	if v.input.Text() == "" && len(v.draft) > 0 {
+		// Don't send the newline at the end of the paste as an empty
+		// line.
+		v.joinLine()
+	}
+	if len(v.draft) == 0 {
+		return
+	}
+	v.confirm = true
+	v.setNotice(fmt.Sprintf("send %d lines to %s? (y/n)", len(v.draft)+1, v.scope.Name))
+}
+
+// confirmKey handles a key press while confirming a paste: 'y' sends it, and
+// 'n' or Esc leaves it in the input to edit. Other keys are ignored.
+func (v *View) confirmKey(ev tui.KeyEvent) {
+	switch {
+	case ev.Key == tui.KeyRune && (ev.Rune == 'y' || ev.Rune == 'Y'):
+		v.confirm = false
+		v.handleInput(v.input)
+	case ev.Key == tui.KeyRune && (ev.Rune == 'n' || ev.Rune == 'N'), ev.Key == tui.KeyEsc:
+		v.confirm = false
+		v.setNotice("")
+	}
+}
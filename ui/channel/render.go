@@ -0,0 +1,289 @@
+package channel
+
+import (
+	"image"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marcusolsson/tui-go"
+	"github.com/marcusolsson/tui-go/wordwrap"
+	"github.com/mattn/go-runewidth"
+
+	"github.com/cceckman/discoirc/config"
+	"github.com/cceckman/discoirc/data"
+)
+
+// nickWidth is the width of the column of senders; longer nicks are cut short.
+const nickWidth = 10
+
+// timeReference is formatted to find the width of timestamps: it's on the day
+// and in the month with the longest names.
+var timeReference = time.Date(2006, time.September, 27, 23, 59, 59, 999999999, time.UTC)
+
+// NewRenderer returns an EventRenderer that lays Events out in columns: when
+// they happened, in the configured format; who they're from, right-aligned;
+// and their text, wrapped to line up after the sender. Actions, notices,
+// joins, parts, and quits, and the user's own messages are drawn in the
+// "event.action", "event.notice", "event.membership", and "event.own" styles.
+// Messages' formatting is drawn in the "format.bold", "format.italic",
+// "format.underline", and "format.reverse" styles, and their colors in
+// "format.fg.N" and "format.bg.N", where N is the color's number from 0 to 15,
+// e.g. "format.fg.4" for red; unless the Display strips colors. Colors 16 to
+// 98 are drawn as the nearest of those (see data.ParseFormatting).
+func NewRenderer(d config.Display) EventRenderer {
+	format := d.TimeFormat
+	if format == "" {
+		format = config.DefaultTimeFormat
+	}
+	width := runewidth.StringWidth(timeReference.Format(format))
+	return func(e data.Event) tui.Widget {
+		l := layoutEvent(e)
+		if d.StripColors {
+			for i := range l.spans {
+				l.spans[i].Fg, l.spans[i].Bg = data.NoColor, data.NoColor
+			}
+		}
+		if !l.time.IsZero() {
+			l.stamp = l.time.Format(format)
+		}
+		// Pad the timestamp, so the columns after it line up.
+		if w := runewidth.StringWidth(l.stamp); w < width {
+			l.stamp += strings.Repeat(" ", width-w)
+		}
+		l.SetSizePolicy(tui.Expanding, tui.Minimum)
+		return l
+	}
+}
+
+// layoutEvent returns the line showing the Event, without its timestamp.
+func layoutEvent(e data.Event) *eventLine {
+	switch e := e.(type) {
+	case *data.MessageEvent:
+		l := &eventLine{time: e.Time, sender: e.Nick, nickStyle: "event.nick"}
+		l.setSpans(data.ParseFormatting(e.Text))
+		switch e.Command {
+		case data.Action:
+			l.sender, l.nickStyle = "*", ""
+			l.setSpans(append([]data.Span{{Text: e.Nick + " "}}, l.spans...))
+			l.style = "event.action"
+		case data.Notice:
+			l.sender = "-" + e.Nick + "-"
+			l.style = "event.notice"
+		}
+		if e.Own {
+			l.style = "event.own"
+		}
+		return l
+	case *data.MembershipEvent:
+		l := &eventLine{time: e.Time, style: "event.membership"}
+		who := e.Nick
+		if e.User != "" && e.Host != "" {
+			who += " (" + e.User + "@" + e.Host + ")"
+		}
+		switch e.Change {
+		case data.Join:
+			l.sender, l.text = "-->", who+" joined"
+		case data.Part:
+			l.sender, l.text = "<--", who+" left"
+		case data.Quit:
+			l.sender, l.text = "<--", who+" quit"
+		}
+		if e.Reason != "" {
+			l.text += ": " + data.StripFormatting(e.Reason)
+		}
+		return l
+	case *netsplit:
+		return &eventLine{sender: "<--", text: e.String(), style: "event.membership"}
+	case *membershipSummary:
+		return &eventLine{sender: "--", text: e.String(), style: "event.membership"}
+	}
+	return &eventLine{sender: "--", text: e.String()}
+}
+
+// eventLine is an Event laid out in columns, with its text wrapped under
+// itself.
+type eventLine struct {
+	tui.WidgetBase
+
+	time time.Time
+	// stamp is the time, formatted, or spaces if it isn't known.
+	stamp  string
+	sender string
+	// text is the text without formatting; spans, if set, are its parts with
+	// their formatting.
+	text  string
+	spans []data.Span
+	// style is the style of the whole line, if any; nickStyle that of the
+	// sender.
+	style     string
+	nickStyle string
+	// query is the text of a search, highlighted where it appears in the
+	// text.
+	query string
+}
+
+var _ tui.Widget = &eventLine{}
+
+// setSpans sets the text to that of the spans.
+func (l *eventLine) setSpans(spans []data.Span) {
+	var b strings.Builder
+	for _, s := range spans {
+		b.WriteString(s.Text)
+	}
+	l.text, l.spans = b.String(), spans
+}
+
+// formats returns the Format of each byte of the text.
+func (l *eventLine) formats() []data.Format {
+	if l.spans == nil {
+		return make([]data.Format, len(l.text))
+	}
+	f := make([]data.Format, 0, len(l.text))
+	for _, s := range l.spans {
+		for i := 0; i < len(s.Text); i++ {
+			f = append(f, s.Format)
+		}
+	}
+	return f
+}
+
+// formatStyles returns the styles that draw text with the Format.
+func formatStyles(f data.Format) []string {
+	var styles []string
+	if f.Bold {
+		styles = append(styles, "format.bold")
+	}
+	if f.Italic {
+		styles = append(styles, "format.italic")
+	}
+	if f.Underline {
+		styles = append(styles, "format.underline")
+	}
+	if f.Reverse {
+		styles = append(styles, "format.reverse")
+	}
+	if f.Fg != data.NoColor {
+		styles = append(styles, "format.fg."+strconv.Itoa(f.Fg.Code()))
+	}
+	if f.Bg != data.NoColor {
+		styles = append(styles, "format.bg."+strconv.Itoa(f.Bg.Code()))
+	}
+	return styles
+}
+
+// withStyles calls fn with the painter in all of the styles.
+func withStyles(p *tui.Painter, styles []string, fn func(p *tui.Painter)) {
+	if len(styles) == 0 {
+		fn(p)
+		return
+	}
+	p.WithStyle(styles[0], func(p *tui.Painter) {
+		withStyles(p, styles[1:], fn)
+	})
+}
+
+// indent is the width of the columns before the text.
+func (l *eventLine) indent() int {
+	return runewidth.StringWidth(l.stamp) + 1 + nickWidth + 1
+}
+
+// lines returns the text, wrapped to fit after the indent, without the spaces
+// at the ends of lines.
+func (l *eventLine) lines() []string {
+	width := l.Size().X - l.indent()
+	if width < 0 {
+		width = 0
+	}
+	lines := strings.Split(wordwrap.WrapString(l.text, width), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " ")
+	}
+	return lines
+}
+
+// MinSizeHint returns the size of the columns and one line of text.
+func (l *eventLine) MinSizeHint() image.Point {
+	return image.Point{l.indent() + 1, 1}
+}
+
+// SizeHint returns the size of the wrapped text and the columns before it.
+func (l *eventLine) SizeHint() image.Point {
+	lines := l.lines()
+	max := 0
+	for _, line := range lines {
+		if w := runewidth.StringWidth(line); w > max {
+			max = w
+		}
+	}
+	return image.Point{l.indent() + max, len(lines)}
+}
+
+// Draw draws the timestamp, the sender, right-aligned, and the text.
+func (l *eventLine) Draw(p *tui.Painter) {
+	withStyle := func(p *tui.Painter, style string, fn func(p *tui.Painter)) {
+		if style == "" {
+			fn(p)
+		} else {
+			p.WithStyle(style, fn)
+		}
+	}
+	withStyle(p, l.style, func(p *tui.Painter) {
+		if !l.time.IsZero() {
+			p.WithStyle("event.time", func(p *tui.Painter) {
+				p.DrawText(0, 0, l.stamp)
+			})
+		}
+		sender := l.sender
+		if runewidth.StringWidth(sender) > nickWidth {
+			sender = runewidth.Truncate(sender, nickWidth, "…")
+		}
+		x := runewidth.StringWidth(l.stamp) + 1 + nickWidth - runewidth.StringWidth(sender)
+		withStyle(p, l.nickStyle, func(p *tui.Painter) {
+			p.DrawText(x, 0, sender)
+		})
+		// Wrapping drops spaces, so the formats of the lines' characters
+		// are found by following them through the text.
+		formats := l.formats()
+		pos := 0
+		for y, line := range l.lines() {
+			x := l.indent()
+			for _, s := range splitMatches(line, l.query) {
+				var styles []string
+				if s.match {
+					styles = []string{"match"}
+				}
+				for _, run := range l.formatRuns(s.text, formats, &pos) {
+					x0, text := x, run.Text
+					withStyles(p, append(styles, formatStyles(run.Format)...), func(p *tui.Painter) {
+						p.DrawText(x0, y, text)
+					})
+					x += runewidth.StringWidth(run.Text)
+				}
+			}
+		}
+	})
+}
+
+// formatRuns splits part of a line into runs with the same Format, finding
+// each character in the text from pos onwards.
+func (l *eventLine) formatRuns(part string, formats []data.Format, pos *int) []data.Span {
+	var runs []data.Span
+	for _, r := range part {
+		c := string(r)
+		for *pos < len(l.text) && !strings.HasPrefix(l.text[*pos:], c) {
+			*pos++
+		}
+		var f data.Format
+		if *pos < len(formats) {
+			f = formats[*pos]
+		}
+		*pos += len(c)
+		if n := len(runs); n > 0 && runs[n-1].Format == f {
+			runs[n-1].Text += c
+		} else {
+			runs = append(runs, data.Span{Text: c, Format: f})
+		}
+	}
+	return runs
+}
@@ -0,0 +1,303 @@
+package channel_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marcusolsson/tui-go"
+
+	"github.com/cceckman/discoirc/config"
+	"github.com/cceckman/discoirc/data"
+	"github.com/cceckman/discoirc/ui/channel"
+	"github.com/cceckman/discoirc/ui/widgets"
+)
+
+// renderEvents are one of each kind of Event the renderer lays out.
+var renderEvents = func() data.EventList {
+	hamlet := data.Scope{Net: "HamNet", Name: "#hamlet"}
+	id := func(seq data.Seq) data.EventID { return data.EventID{Scope: hamlet, Seq: seq} }
+	at := func(min int) time.Time { return time.Date(2026, 1, 1, 12, min, 0, 0, time.UTC) }
+	return data.EventList{
+		&data.MessageEvent{EventID: id(1), Nick: "hamlet", Text: "To be, or not to be, that is the question", Time: at(0)},
+		&data.MessageEvent{EventID: id(2), Nick: "hamlet", Command: data.Action, Text: "draws", Time: at(1)},
+		&data.MessageEvent{EventID: id(3), Nick: "ChanServ", Command: data.Notice, Text: "Welcome to #elsinore", Time: at(2)},
+		&data.MessageEvent{EventID: id(4), Nick: "yorick", Text: "Alas!", Time: at(3), Own: true},
+		&data.MessageEvent{EventID: id(5), Nick: "guildenstern", Text: "My lord!", Time: at(4)},
+		&data.MembershipEvent{EventID: id(6), Nick: "rosencrantz", Change: data.Join, Time: at(5)},
+		&data.MembershipEvent{EventID: id(7), Nick: "polonius", Change: data.Part, Time: at(6)},
+		&data.MembershipEvent{EventID: id(8), Nick: "ophelia", User: "oph", Host: "brook", Change: data.Quit, Reason: "Drowned", Time: at(7)},
+		&data.ErrorEvent{EventID: id(9), Line: "lost connection"},
+	}
+}()
+
+// renderAll paints the Events, rendered by r, on the surface.
+func renderAll(surface *tui.TestSurface, theme *tui.Theme, r channel.EventRenderer, events data.EventList) {
+	var w []tui.Widget
+	for _, e := range events {
+		w = append(w, r(e))
+	}
+	tui.NewPainter(surface, theme).Repaint(widgets.NewTailBox(w...))
+}
+
+var rendererTests = []struct {
+	// style is the one reversed.
+	style           string
+	wantDecorations string
+}{
+	{
+		style: "event.time",
+		wantDecorations: `
+1111100000000000000000000000000000000000
+0000000000000000000000000000000000000000
+1111100000000000000000000000000000000000
+1111100000000000000000000000000000000000
+1111100000000000000000000000000000000000
+1111100000000000000000000000000000000000
+1111100000000000000000000000000000000000
+1111100000000000000000000000000000000000
+1111100000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+`,
+	},
+	{
+		style: "event.nick",
+		wantDecorations: `
+0000000000111111000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000001111111111000000000000000000000000
+0000000000111111000000000000000000000000
+0000001111111111000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+`,
+	},
+	{
+		style: "event.action",
+		wantDecorations: `
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+1111100000000001011111111111100000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+`,
+	},
+	{
+		style: "event.notice",
+		wantDecorations: `
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+1111101111111111011111111111111111111000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+`,
+	},
+	{
+		style: "event.own",
+		wantDecorations: `
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+1111100000111111011111000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+`,
+	},
+	{
+		style: "event.membership",
+		wantDecorations: `
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+1111100000000111011111111111111111100000
+1111100000000111011111111111110000000000
+1111100000000111011111111111111111110000
+0000000000000000011111111111110000000000
+0000000000000000000000000000000000000000
+`,
+	},
+}
+
+func TestRenderer(t *testing.T) {
+	wantContents := `
+12:00     hamlet To be, or not to be,   
+                 that is the question   
+12:01          * hamlet draws           
+12:02 -ChanServ- Welcome to #elsinore   
+12:03     yorick Alas!                  
+12:04 guildenst… My lord!               
+12:05        --> rosencrantz joined     
+12:06        <-- polonius left          
+12:07        <-- ophelia (oph@brook)    
+                 quit: Drowned          
+              -- lost connection        
+`
+	for _, tt := range rendererTests {
+		tt := tt
+		t.Run(tt.style, func(t *testing.T) {
+			t.Parallel()
+			theme := tui.NewTheme()
+			theme.SetStyle(tt.style, tui.Style{Reverse: tui.DecorationOn})
+			surface := tui.NewTestSurface(40, 11)
+			renderAll(surface, theme, channel.NewRenderer(config.Display{}), renderEvents)
+
+			if got := surface.String(); got != wantContents {
+				t.Errorf("unexpected contents:\ngot = \n%s\n--\nwant = \n%s\n--", got, wantContents)
+			}
+			if got := surface.Decorations(); got != tt.wantDecorations {
+				t.Errorf("unexpected decorations:\ngot = \n%s\n--\nwant = \n%s\n--", got, tt.wantDecorations)
+			}
+		})
+	}
+}
+
+func TestRenderer_TimeFormat(t *testing.T) {
+	t.Parallel()
+	at := time.Date(2026, 1, 1, 12, 0, 30, 0, time.UTC)
+	events := data.EventList{
+		&data.MessageEvent{Nick: "polonius", Text: "Though this be madness, yet there is method in't", Time: at},
+		&data.MessageEvent{Nick: "hamlet", Text: "Into my grave?", Time: at.Add(15 * time.Second)},
+	}
+	surface := tui.NewTestSurface(40, 4)
+	renderAll(surface, tui.NewTheme(), channel.NewRenderer(config.Display{TimeFormat: "15:04:05"}), events)
+
+	want := `
+12:00:30   polonius Though this be      
+                    madness, yet there  
+                    is method in't      
+12:00:45     hamlet Into my grave?      
+`
+	if got := surface.String(); got != want {
+		t.Errorf("unexpected contents:\ngot = \n%s\n--\nwant = \n%s\n--", got, want)
+	}
+}
+
+func TestRenderer_Formatting(t *testing.T) {
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := data.EventList{
+		&data.MessageEvent{Nick: "hamlet", Text: "To \x02be\x02, or \x0304not\x03 to be", Time: at},
+		&data.MessageEvent{Nick: "hamlet", Text: "that is the question whether \x02tis nobler\x02 in the mind", Time: at},
+		&data.MessageEvent{Nick: "hamlet", Command: data.Action, Text: "\x0304,01draws\x03", Time: at},
+	}
+	wantContents := `
+12:00     hamlet To be, or not to be    
+12:00     hamlet that is the question   
+                 whether tis nobler in  
+                 the mind               
+12:00          * hamlet draws           
+`
+	for _, tt := range []struct {
+		// style is the one reversed.
+		style           string
+		display         config.Display
+		wantDecorations string
+	}{
+		{
+			style: "format.bold",
+			wantDecorations: `
+0000000000000000000011000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000111111111100000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+`,
+		},
+		{
+			style: "format.fg.4",
+			wantDecorations: `
+0000000000000000000000000001110000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000001111100000000000
+`,
+		},
+		{
+			style: "format.bg.1",
+			wantDecorations: `
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000001111100000000000
+`,
+		},
+		{
+			style:   "format.fg.4",
+			display: config.Display{StripColors: true},
+			wantDecorations: `
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+`,
+		},
+	} {
+		tt := tt
+		t.Run(tt.style, func(t *testing.T) {
+			t.Parallel()
+			theme := tui.NewTheme()
+			theme.SetStyle(tt.style, tui.Style{Reverse: tui.DecorationOn})
+			surface := tui.NewTestSurface(40, 5)
+			renderAll(surface, theme, channel.NewRenderer(tt.display), events)
+
+			if got := surface.String(); got != wantContents {
+				t.Errorf("unexpected contents:\ngot = \n%s\n--\nwant = \n%s\n--", got, wantContents)
+			}
+			if got := surface.Decorations(); got != tt.wantDecorations {
+				t.Errorf("unexpected decorations:\ngot = \n%s\n--\nwant = \n%s\n--", got, tt.wantDecorations)
+			}
+		})
+	}
+}
+
+func TestRenderer_ExtendedColors(t *testing.T) {
+	t.Parallel()
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := data.EventList{
+		// 52 is the brightest red of the extended colors.
+		&data.MessageEvent{Nick: "hamlet", Text: "\x0352alas\x03, poor Yorick", Time: at},
+	}
+	theme := tui.NewTheme()
+	theme.SetStyle("format.fg.4", tui.Style{Reverse: tui.DecorationOn})
+	surface := tui.NewTestSurface(40, 1)
+	renderAll(surface, theme, channel.NewRenderer(config.Display{}), events)
+
+	wantContents := `
+12:00     hamlet alas, poor Yorick      
+`
+	wantDecorations := `
+0000000000000000011110000000000000000000
+`
+	if got := surface.String(); got != wantContents {
+		t.Errorf("unexpected contents:\ngot = \n%s\n--\nwant = \n%s\n--", got, wantContents)
+	}
+	if got := surface.Decorations(); got != wantDecorations {
+		t.Errorf("unexpected decorations:\ngot = \n%s\n--\nwant = \n%s\n--", got, wantDecorations)
+	}
+}
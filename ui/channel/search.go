@@ -0,0 +1,159 @@
+package channel
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/marcusolsson/tui-go"
+	"github.com/marcusolsson/tui-go/wordwrap"
+	"github.com/mattn/go-runewidth"
+
+	"github.com/cceckman/discoirc/data"
+)
+
+// searchPage is how many Events are requested at a time while searching the
+// channel's archive.
+const searchPage = 100
+
+// eventSearch is a search of the channel's messages.
+type eventSearch struct {
+	query string
+	// matches are the matching Events, newest first.
+	matches []data.Seq
+	// current is the index in matches of the match shown.
+	current int
+}
+
+// startSearch starts entering a search of the channel's messages in the input
+// line.
+func (v *View) startSearch() {
+	// In a modal keymap, the view returns to normal mode once the search
+	// is entered, as for a command.
+	v.command = v.keys.Mode() != ""
+	v.setText("/search ")
+}
+
+// searchEvents searches the channel's archive, from the newest Event back, for
+// messages whose text (see data.Text) contains the query, ignoring case, and
+// scrolls to the newest one.
+// Hidden messages from ignored users don't match. An empty query ends the
+// search.
+func (v *View) searchEvents(query string) {
+	v.found = nil
+	v.events.SetHighlight("")
+	if query == "" || v.events.source == nil {
+		return
+	}
+
+	s := &eventSearch{query: query}
+	for last := v.events.last; last > 0; {
+		events := v.events.source.EventsBefore(v.scope, searchPage, last)
+		if len(events) == 0 {
+			break
+		}
+		for i := len(events) - 1; i >= 0; i-- {
+			if data.Ignored(events[i]) && !v.events.reveal {
+				continue
+			}
+			if containsFold(data.Text(events[i]), query) {
+				s.matches = append(s.matches, events[i].ID().Seq)
+			}
+		}
+		last = events[0].ID().Seq - 1
+	}
+	v.found = s
+	v.events.SetHighlight(query)
+	v.showMatch()
+}
+
+// nextMatch scrolls to the nth older match, or newer if n is negative,
+// wrapping around at the oldest and newest.
+func (v *View) nextMatch(n int) {
+	if v.found == nil {
+		v.setNotice("no search")
+		return
+	}
+	if l := len(v.found.matches); l > 0 {
+		v.found.current = ((v.found.current+n)%l + l) % l
+	}
+	v.showMatch()
+}
+
+// showMatch scrolls to the current match, and shows which it is in the status
+// bar.
+func (v *View) showMatch() {
+	s := v.found
+	if len(s.matches) == 0 {
+		v.setNotice(fmt.Sprintf("no match for %q", s.query))
+		return
+	}
+	v.events.ScrollTo(s.matches[s.current])
+	v.setNotice(fmt.Sprintf("match %d of %d", s.current+1, len(s.matches)))
+}
+
+// containsFold reports whether substr is within s, ignoring case.
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// span is part of a line of text, which may match a search.
+type span struct {
+	text  string
+	match bool
+}
+
+// splitMatches splits the line into the parts that match the query, ignoring
+// case, and the parts that don't.
+func splitMatches(line, query string) []span {
+	lower, q := strings.ToLower(line), strings.ToLower(query)
+	if q == "" || len(lower) != len(line) {
+		// Changing case changed the length, so the positions of matches
+		// in lower aren't those in line.
+		return []span{{text: line}}
+	}
+	var spans []span
+	for {
+		i := strings.Index(lower, q)
+		if i < 0 {
+			break
+		}
+		if i > 0 {
+			spans = append(spans, span{text: line[:i]})
+		}
+		spans = append(spans, span{text: line[i : i+len(q)], match: true})
+		line, lower = line[i+len(q):], lower[i+len(q):]
+	}
+	if line != "" {
+		spans = append(spans, span{text: line})
+	}
+	return spans
+}
+
+// matchLabel is a word-wrapped Label that draws the parts of its text that
+// match a search in the "match" style. Matches broken across lines aren't
+// highlighted.
+type matchLabel struct {
+	*tui.Label
+	query string
+}
+
+func (ml *matchLabel) Draw(p *tui.Painter) {
+	lines := strings.Split(wordwrap.WrapString(ml.Text(), ml.Size().X), "\n")
+	p.WithStyle("label", func(p *tui.Painter) {
+		for y, line := range lines {
+			x := 0
+			for _, s := range splitMatches(line, ml.query) {
+				x0, text := x, s.text
+				draw := func(p *tui.Painter) {
+					p.DrawText(x0, y, text)
+				}
+				if s.match {
+					p.WithStyle("match", draw)
+				} else {
+					draw(p)
+				}
+				x += runewidth.StringWidth(s.text)
+			}
+		}
+	})
+}
@@ -1,10 +1,13 @@
 package channel
 
 import (
-	"strings"
+	"fmt"
 
 	"github.com/cceckman/discoirc/backend"
+	"github.com/cceckman/discoirc/config"
 	"github.com/cceckman/discoirc/data"
+	"github.com/cceckman/discoirc/ui/history"
+	"github.com/cceckman/discoirc/ui/keymap"
 	"github.com/cceckman/discoirc/ui/widgets"
 	"github.com/marcusolsson/tui-go"
 )
@@ -24,13 +27,52 @@ type UIController interface {
 	Quit()
 
 	ActivateClient()
+	ActivateChannel(network, channel string)
+	// ActivateFilter shows the filter view of the messages matching the
+	// query.
+	ActivateFilter(query string)
+	SetTitle(title string)
+	// Keymap returns the key bindings to use.
+	Keymap() *keymap.Keymap
+	// History returns the input history to use.
+	History() *history.Store
 }
 
 // View implements the channel view.
 type View struct {
-	ui     UIController
-	sender backend.Sender
-	scope  data.Scope
+	ui        UIController
+	sender    backend.Sender
+	outbox    backend.Outbox
+	notify    backend.Notifications
+	manager   backend.Manager
+	saver     backend.Saver
+	directory backend.Directory
+	ignorer   backend.Ignorer
+	scope     data.Scope
+
+	keys *keymap.State
+	// command is set when the input line was opened by the command-mode
+	// action; the view returns to normal mode once it's submitted.
+	command bool
+	// completion is the last completion of the input line, if any.
+	completion *completion
+
+	history *history.Store
+	// histCursor is the position in the input history, once the user has
+	// moved into it.
+	histCursor *history.Cursor
+	// search is the history search in progress, if any.
+	search *historySearch
+	// found is the search of the channel's messages, if any.
+	found *eventSearch
+	// only is the filter set by "/only", if any, whether it's on or off.
+	only *data.Filter
+
+	// draft are the lines of the message before the input line.
+	draft []string
+	paste *pasteDetector
+	// confirm is set while asking whether to send a paste.
+	confirm bool
 
 	// root element
 	*tui.Box
@@ -41,31 +83,143 @@ type View struct {
 	// status bar
 	connState   *widgets.ConnState
 	channelMode *tui.Label
+	filtered    *tui.Label
+	notice      *tui.Label
+	mode        *tui.Label
 	// input bar
 	nick  *tui.Label
+	lines *tui.Label
 	input *tui.Entry
 }
 
 // OnKeyEvent handles key presses.
 func (v *View) OnKeyEvent(ev tui.KeyEvent) {
-	if ev.Key == tui.KeyCtrlC && v.ui != nil {
-		v.ui.Quit()
+	defer v.updateMode()
+	v.paste.feed(ev)
+}
+
+// handleKey handles a key press that isn't part of a paste.
+func (v *View) handleKey(ev tui.KeyEvent, pasted bool) {
+	if pasted {
+		v.pasteKey(ev)
+		return
+	}
+	if v.confirm {
+		v.confirmKey(ev)
+		return
+	}
+	if v.search != nil && v.searchKey(ev) {
+		return
+	}
+	a, handled := v.keys.Handle(ev)
+	if v.search != nil && a != keymap.HistorySearch {
+		v.endSearch()
+	}
+	if handled {
+		v.do(a)
+		return
+	}
+	if v.keys.Mode() == keymap.Normal {
+		// In normal mode, keys are commands, not input.
+		return
+	}
+	if (ev.Key == tui.KeyBackspace || ev.Key == tui.KeyBackspace2) && v.input.Text() == "" && len(v.draft) > 0 {
+		v.joinLine()
+		return
 	}
 	v.Box.OnKeyEvent(ev)
 }
 
+// do performs an action bound to a key. Changes of mode have already been
+// made by the keymap.
+func (v *View) do(a keymap.Action) {
+	switch a {
+	case keymap.Quit:
+		if v.ui != nil {
+			v.ui.Quit()
+		}
+	case keymap.ShowClient:
+		if v.ui != nil {
+			v.ui.ActivateClient()
+		}
+	case keymap.Up:
+		v.events.Scroll(1)
+	case keymap.Down:
+		v.events.Scroll(-1)
+	case keymap.PageUp:
+		v.events.Scroll(v.events.Size().Y)
+	case keymap.PageDown:
+		v.events.Scroll(-v.events.Size().Y)
+	case keymap.Bottom:
+		v.events.ScrollToEnd()
+	case keymap.Complete:
+		v.complete(1)
+	case keymap.CompletePrevious:
+		v.complete(-1)
+	case keymap.HistoryPrevious:
+		v.historyPrevious()
+	case keymap.HistoryNext:
+		v.historyNext()
+	case keymap.HistorySearch:
+		v.searchHistory()
+	case keymap.Newline:
+		v.newline()
+	case keymap.Search:
+		v.startSearch()
+	case keymap.SearchNext:
+		v.nextMatch(1)
+	case keymap.SearchPrevious:
+		v.nextMatch(-1)
+	case keymap.ToggleFilter:
+		v.toggleFilter()
+	case keymap.CommandMode:
+		v.command = true
+		v.input.SetText("/")
+	case keymap.NormalMode:
+		if v.command {
+			// Cancel the command.
+			v.command = false
+			v.input.SetText("")
+		}
+	}
+}
+
+// updateMode shows the keymap's mode, and any keys pressed so far in a
+// sequence, in the status bar.
+func (v *View) updateMode() {
+	if ind := v.keys.Indicator(); ind != "" {
+		v.mode.SetText(" " + ind)
+	} else {
+		v.mode.SetText("")
+	}
+}
+
 // handleInput handles input from the user.
 func (v *View) handleInput(entry *tui.Entry) {
-	m := entry.Text()
-	defer entry.SetText("")
-	lower := strings.ToLower(m)
-
-	if strings.HasPrefix(lower, "/client") && v.ui != nil {
-		v.ui.ActivateClient()
+	m := v.text()
+	if len(v.draft) > 0 && isCommand(m) {
+		v.setNotice("commands can't have several lines")
 		return
 	}
-	if strings.HasPrefix(lower, "/quit") && v.ui != nil {
-		v.ui.Quit()
+	defer v.setText("")
+	v.setNotice("")
+	v.completion = nil
+	v.histCursor = nil
+	v.search = nil
+	if v.command {
+		v.command = false
+		v.keys.SetMode(keymap.Normal)
+		v.updateMode()
+		if m == "/" {
+			// An empty command.
+			return
+		}
+	}
+
+	if err := v.history.Add(v.scope, m); err != nil {
+		v.setNotice(fmt.Sprintf("history not saved: %v", err))
+	}
+	if isCommand(m) && v.runCommand(m) {
 		return
 	}
 	if v.sender != nil {
@@ -73,6 +227,17 @@ func (v *View) handleInput(entry *tui.Entry) {
 	}
 }
 
+// setNotice shows a short message, e.g. the result of a command, in the status
+// bar.
+func (v *View) setNotice(msg string) {
+	v.notice.SetText(msg)
+}
+
+// SetMembership sets how joins, parts, and quits are shown.
+func (v *View) SetMembership(m config.Membership) {
+	v.events.SetMembership(m)
+}
+
 // SetRenderer sets the function that turns Events into Widgets.
 func (v *View) SetRenderer(e EventRenderer) {
 	v.events.Renderer = e
@@ -101,14 +266,39 @@ func (v *View) updateNetwork(n data.NetworkState) {
 
 func (v *View) updateChannel(d data.ChannelState) {
 	update := func() {
-		v.topic.SetText(d.Topic)
+		v.topic.SetText(data.StripFormatting(d.Topic))
 		v.channelMode.SetText(d.Mode)
 		v.events.SetLast(d.LastMessage)
+		v.showFilter()
+		v.showPending()
+		v.ui.SetTitle(Title(v.scope, d.Unread))
 	}
 	v.ui.Update(update)
 
 }
 
+// showPending shows the messages waiting to be sent to the channel.
+func (v *View) showPending() {
+	if v.outbox != nil {
+		v.events.SetPending(v.outbox.Pending(v.scope))
+	}
+}
+
+// ShowEvent scrolls the view back to the Event, e.g. one found in the filter
+// view.
+func (v *View) ShowEvent(s data.Seq) {
+	v.events.ScrollTo(s)
+}
+
+// Title returns the window title for a view of the channel, e.g.
+// "Barnetic #discoirc (3)" for a channel with 3 unread messages.
+func Title(s data.Scope, unread int) string {
+	if unread > 0 {
+		return fmt.Sprintf("%s %s (%d)", s.Net, s.Name, unread)
+	}
+	return s.Net + " " + s.Name
+}
+
 // Filter returns the match rule for this view.
 func (v *View) Filter() data.Filter {
 	return data.Filter{
@@ -122,21 +312,41 @@ func (v *View) Filter() data.Filter {
 func New(s data.Scope, ui UIController, backend backend.Backend) *View {
 	// construct V
 	v := &View{
-		ui:     ui,
-		sender: backend,
-		scope:  s,
+		ui:        ui,
+		sender:    backend,
+		outbox:    backend,
+		notify:    backend,
+		manager:   backend,
+		saver:     backend,
+		directory: backend,
+		ignorer:   backend,
+		scope:     s,
 
 		topic:       tui.NewLabel(""),
 		events:      NewEventsWidget(s, backend),
 		connState:   widgets.NewConnState(),
 		channelMode: tui.NewLabel(""),
+		filtered:    tui.NewLabel(""),
+		notice:      tui.NewLabel(""),
+		mode:        tui.NewLabel(""),
 		nick:        tui.NewLabel(""),
+		lines:       tui.NewLabel(""),
 		input:       tui.NewEntry(),
 	}
+	v.paste = &pasteDetector{key: v.handleKey, end: v.endPaste}
 	v.topic.SetSizePolicy(tui.Expanding, tui.Minimum)
 	v.events.SetSizePolicy(tui.Expanding, tui.Expanding)
 	v.input.SetSizePolicy(tui.Expanding, tui.Minimum)
 
+	keys := keymap.Default()
+	v.history = history.New(history.DefaultSize)
+	if ui != nil {
+		keys = ui.Keymap()
+		v.history = ui.History()
+	}
+	v.keys = keys.NewState(keymap.Channel)
+	v.updateMode()
+
 	v.input.OnSubmit(v.handleInput)
 	v.input.SetFocused(true)
 
@@ -146,6 +356,7 @@ func New(s data.Scope, ui UIController, backend backend.Backend) *View {
 		tui.NewLabel("<"),
 		v.nick,
 		tui.NewLabel("> "),
+		v.lines,
 		v.input,
 	)
 
@@ -163,7 +374,10 @@ func New(s data.Scope, ui UIController, backend backend.Backend) *View {
 				tui.NewLabel(s.Name),
 				tui.NewLabel(": "),
 				v.channelMode,
+				v.filtered,
 				rspacer,
+				v.notice,
+				v.mode,
 			),
 		},
 		inputBar,
@@ -171,6 +385,7 @@ func New(s data.Scope, ui UIController, backend backend.Backend) *View {
 
 	if ui != nil {
 		ui.SetWidget(v)
+		ui.SetTitle(Title(s, 0))
 	}
 
 	if backend != nil {
@@ -3,10 +3,17 @@ package channel_test
 import (
 	"fmt"
 	"image"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cceckman/discoirc/backend/outbox"
+	"github.com/cceckman/discoirc/config"
 	"github.com/cceckman/discoirc/data"
 	"github.com/cceckman/discoirc/ui/channel"
+	"github.com/cceckman/discoirc/ui/keymap"
 	"github.com/cceckman/discoirc/ui/testhelper"
 
 	"github.com/marcusolsson/tui-go"
@@ -40,11 +47,14 @@ HamNet: ✓ #hamlet: +v
 var theme = func() *tui.Theme {
 	t := tui.NewTheme()
 	t.SetStyle("reversed", tui.Style{Reverse: tui.DecorationOn})
+	t.SetStyle("match", tui.Style{Reverse: tui.DecorationOn})
 	return t
 }()
 
 var renderTests = []struct {
-	test            string
+	test string
+	// preset is the keymap to use, if not the default.
+	preset          string
 	setup           func(c *channel.View)
 	wantContents    string
 	wantDecorations string
@@ -147,6 +157,119 @@ HamNet: ✓ #hamlet: +v
 `,
 		wantDecorations: wantDecor40x10,
 	},
+	{
+		test: "command notice",
+		setup: func(c *channel.View) {
+			c.Receive(&data.NetworkStateEvent{
+				EventID: data.EventID{Scope: data.Scope{Net: "HamNet"}},
+				NetworkState: data.NetworkState{
+					State: data.Connected,
+					Nick:  "yorick",
+				}})
+			c.Receive(&data.ChannelStateEvent{
+				EventID: data.EventID{Scope: data.Scope{
+					Net:  "HamNet",
+					Name: "#hamlet",
+				}},
+				ChannelState: data.ChannelState{
+					Presence:    data.Joined,
+					Mode:        "+v",
+					Topic:       "Act I, Scene 1",
+					LastMessage: testhelper.Events[len(testhelper.Events)-1].ID().Seq,
+				},
+			})
+			for _, r := range "/nick\n" {
+				ev := tui.KeyEvent{Key: tui.KeyRune, Rune: r}
+				if r == '\n' {
+					ev = tui.KeyEvent{Key: tui.KeyEnter}
+				}
+				c.OnKeyEvent(ev)
+			}
+		},
+		wantContents: `
+Act I, Scene 1                          
+vnfold your selfe                       
+6 <barnardo> Long liue the King         
+7 <claudius> Welcome, dear Rosencrantz  
+and Guildenstern!                       
+8 <gertrude> Good gentlemen, he hath    
+much talk'd of you;                     
+9 <rosencrantz> Both your majesties     
+HamNet: ✓ #hamlet: +v  usage: /nick nick
+<yorick>                                
+`,
+		wantDecorations: wantDecor40x10,
+	},
+	{
+		test:   "vim normal mode",
+		preset: keymap.Vim,
+		setup: func(c *channel.View) {
+			joinHamlet(c)
+			c.OnKeyEvent(tui.KeyEvent{Key: tui.KeyEsc})
+		},
+		wantContents: `
+Act I, Scene 1                          
+vnfold your selfe                       
+6 <barnardo> Long liue the King         
+7 <claudius> Welcome, dear Rosencrantz  
+and Guildenstern!                       
+8 <gertrude> Good gentlemen, he hath    
+much talk'd of you;                     
+9 <rosencrantz> Both your majesties     
+HamNet: ✓ #hamlet: +v             NORMAL
+<yorick>                                
+`,
+		wantDecorations: wantDecor40x10,
+	},
+	{
+		test:   "scrolled back",
+		preset: keymap.Vim,
+		setup: func(c *channel.View) {
+			joinHamlet(c)
+			for _, ev := range []tui.KeyEvent{
+				{Key: tui.KeyEsc},
+				{Key: tui.KeyRune, Rune: 'k'},
+				{Key: tui.KeyRune, Rune: 'k'},
+			} {
+				c.OnKeyEvent(ev)
+			}
+		},
+		wantContents: `
+Act I, Scene 1                          
+3 JOIN francisco                        
+4 <barnardo> Who's there?               
+5 <francisco> Nay answer me: Stand &    
+vnfold your selfe                       
+6 <barnardo> Long liue the King         
+7 <claudius> Welcome, dear Rosencrantz  
+and Guildenstern!                       
+HamNet: ✓ #hamlet: +v             NORMAL
+<yorick>                                
+`,
+		wantDecorations: wantDecor40x10,
+	},
+}
+
+// joinHamlet sets the view's network and channel state.
+func joinHamlet(c *channel.View) {
+	c.Receive(&data.NetworkStateEvent{
+		EventID: data.EventID{Scope: data.Scope{Net: "HamNet"}},
+		NetworkState: data.NetworkState{
+			State: data.Connected,
+			Nick:  "yorick",
+		}})
+	c.Receive(&data.ChannelStateEvent{
+		EventID: data.EventID{Scope: data.Scope{
+			Net:  "HamNet",
+			Name: "#hamlet",
+		}},
+		ChannelState: data.ChannelState{
+			Presence:    data.Joined,
+			Mode:        "+v",
+			Topic:       "Act I, Scene 1",
+			LastMessage: testhelper.Events[len(testhelper.Events)-1].ID().Seq,
+		},
+	})
 }
 
 func TestRender(t *testing.T) {
@@ -158,6 +281,9 @@ func TestRender(t *testing.T) {
 			p := tui.NewPainter(surface, theme)
 
 			ui := testhelper.NewController()
+			if tt.preset != "" {
+				ui.Keys, _ = keymap.Preset(tt.preset)
+			}
 			d := testhelper.NewBackend()
 
 			// Root creation must happen in the main thread
@@ -179,6 +305,329 @@ func TestRender(t *testing.T) {
 	}
 }
 
+func TestRender_Pending(t *testing.T) {
+	t.Parallel()
+	surface := tui.NewTestSurface(40, 10)
+	p := tui.NewPainter(surface, theme)
+
+	ui := testhelper.NewController()
+	d := testhelper.NewBackend()
+	hamlet := data.Scope{Net: "HamNet", Name: "#hamlet"}
+	d.Outbox = map[data.Scope][]outbox.Message{
+		hamlet: {
+			{ID: 1, Text: "Alas, poor Yorick!"},
+			{ID: 2, Text: "I knew him, Horatio"},
+		},
+	}
+
+	w := channel.New(hamlet, ui, d)
+	w.SetRenderer(testRenderer)
+	joinHamlet(w)
+	p.Repaint(w)
+
+	want := `
+Act I, Scene 1                          
+7 <claudius> Welcome, dear Rosencrantz  
+and Guildenstern!                       
+8 <gertrude> Good gentlemen, he hath    
+much talk'd of you;                     
+9 <rosencrantz> Both your majesties     
+⏲ Alas, poor Yorick!                    
+⏲ I knew him, Horatio                   
+HamNet: ✓ #hamlet: +v                   
+<yorick>                                
+`
+	if got := surface.String(); got != want {
+		t.Errorf("unexpected contents:\ngot = \n%s\n--\nwant = \n%s\n--", got, want)
+	}
+}
+
+func TestRender_Search(t *testing.T) {
+	t.Parallel()
+	surface := tui.NewTestSurface(40, 10)
+	p := tui.NewPainter(surface, theme)
+
+	ui := testhelper.NewController()
+	d := testhelper.NewBackend()
+	w := channel.New(data.Scope{Net: "HamNet", Name: "#hamlet"}, ui, d)
+	w.SetRenderer(testRenderer)
+	joinHamlet(w)
+
+	ui.Type("/search YOU\n")
+	w.OnKeyEvent(tui.KeyEvent{Key: tui.KeyF3})
+	p.Repaint(w)
+
+	wantContents := `
+Act I, Scene 1                          
+5 <francisco> Nay answer me: Stand &    
+vnfold your selfe                       
+6 <barnardo> Long liue the King         
+7 <claudius> Welcome, dear Rosencrantz  
+and Guildenstern!                       
+8 <gertrude> Good gentlemen, he hath    
+much talk'd of you;                     
+HamNet: ✓ #hamlet: +v       match 2 of 3
+<yorick>                                
+`
+	wantDecorations := `
+1111111111111111111111111111111111111111
+0000000000000000000000000000000000000000
+0000000111000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000000000000000000000000000000
+0000000000000001110000000000000000000000
+1111111111111111111111111111111111111111
+0000000000000000000000000000000000000000
+`
+	if got := surface.String(); got != wantContents {
+		t.Errorf("unexpected contents:\ngot = \n%s\n--\nwant = \n%s\n--", got, wantContents)
+	}
+	if got := surface.Decorations(); got != wantDecorations {
+		t.Errorf("unexpected decorations:\ngot = \n%s\n--\nwant = \n%s\n--", got, wantDecorations)
+	}
+}
+
+func TestRender_Membership(t *testing.T) {
+	t.Parallel()
+	surface := tui.NewTestSurface(40, 10)
+	p := tui.NewPainter(surface, theme)
+
+	ui := testhelper.NewController()
+	d := testhelper.NewBackend()
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	hamlet := data.Scope{Net: "HamNet", Name: "#hamlet"}
+	id := func(seq data.Seq) data.EventID { return data.EventID{Scope: hamlet, Seq: seq} }
+	d.SetEvents(data.EventList{
+		&data.MessageEvent{EventID: id(1), Nick: "alice", Text: "hi", Time: at},
+		&data.MembershipEvent{EventID: id(2), Nick: "bob", Change: data.Join, Time: at},
+		&data.MembershipEvent{EventID: id(3), Nick: "carol", Change: data.Quit, Reason: "a.net b.net", Time: at},
+		&data.MembershipEvent{EventID: id(4), Nick: "dave", Change: data.Quit, Reason: "a.net b.net", Time: at},
+		&data.MembershipEvent{EventID: id(5), Nick: "erin", Change: data.Part, Time: at},
+		&data.MembershipEvent{EventID: id(6), Nick: "frank", Change: data.Join, Time: at},
+		&data.MessageEvent{EventID: id(7), Nick: "frank", Text: "hello", Time: at},
+	})
+	w := channel.New(hamlet, ui, d)
+	w.SetRenderer(testRenderer)
+	w.SetMembership(config.Membership{Collapse: true, FoldNetsplits: true})
+	joinHamlet(w)
+	p.Repaint(w)
+
+	want := `
+Act I, Scene 1                          
+                                        
+                                        
+1 <alice> hi                            
+2 JOIN bob                              
+4 netsplit a.net b.net: 2 quit          
+6 +1 joined, -1 left                    
+7 <frank> hello                         
+HamNet: ✓ #hamlet: +v                   
+<yorick>                                
+`
+	if got := surface.String(); got != want {
+		t.Errorf("unexpected contents:\ngot = \n%s\n--\nwant = \n%s\n--", got, want)
+	}
+
+	ui.Type("/expand\n")
+	p.Repaint(w)
+	want = `
+Act I, Scene 1                          
+                                        
+1 <alice> hi                            
+2 JOIN bob                              
+4 netsplit a.net b.net: 2 quit: carol,  
+dave                                    
+6 +1 joined, -1 left                    
+7 <frank> hello                         
+HamNet: ✓ #hamlet: +v                   
+<yorick>                                
+`
+	if got := surface.String(); got != want {
+		t.Errorf("unexpected contents after /expand:\ngot = \n%s\n--\nwant = \n%s\n--", got, want)
+	}
+}
+
+func TestRender_Ignored(t *testing.T) {
+	t.Parallel()
+	surface := tui.NewTestSurface(40, 10)
+	p := tui.NewPainter(surface, theme)
+
+	ui := testhelper.NewController()
+	d := testhelper.NewBackend()
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	hamlet := data.Scope{Net: "HamNet", Name: "#hamlet"}
+	id := func(seq data.Seq) data.EventID { return data.EventID{Scope: hamlet, Seq: seq} }
+	d.SetEvents(data.EventList{
+		&data.MessageEvent{EventID: id(1), Nick: "hamlet", Text: "Who's there?", Time: at},
+		&data.MessageEvent{EventID: id(2), Nick: "polonius", Text: "My lord!", Time: at, Ignored: true},
+		&data.MessageEvent{EventID: id(3), Nick: "hamlet", Text: "A rat!", Time: at},
+	})
+	w := channel.New(hamlet, ui, d)
+	w.SetRenderer(testRenderer)
+	joinHamlet(w)
+
+	// The status line shows notices; only compare the messages.
+	messages := func() string {
+		return strings.Join(strings.Split(surface.String(), "\n")[:9], "\n")
+	}
+	p.Repaint(w)
+	want := `
+Act I, Scene 1                          
+                                        
+                                        
+                                        
+                                        
+                                        
+1 <hamlet> Who's there?                 
+3 <hamlet> A rat!                       `
+	if got := messages(); got != want {
+		t.Errorf("unexpected contents:\ngot = \n%s\n--\nwant = \n%s\n--", got, want)
+	}
+
+	ui.Type("/reveal\n")
+	p.Repaint(w)
+	want = `
+Act I, Scene 1                          
+                                        
+                                        
+                                        
+                                        
+1 <hamlet> Who's there?                 
+2 <polonius> My lord!                   
+3 <hamlet> A rat!                       `
+	if got := messages(); got != want {
+		t.Errorf("unexpected contents after /reveal:\ngot = \n%s\n--\nwant = \n%s\n--", got, want)
+	}
+}
+
+func TestInput_Search(t *testing.T) {
+	t.Parallel()
+	ui := testhelper.NewController()
+	ui.Keys, _ = keymap.Preset(keymap.Vim)
+	d := testhelper.NewBackend()
+	w := channel.New(data.Scope{Net: "HamNet", Name: "#hamlet"}, ui, d)
+	joinHamlet(w)
+	surface := tui.NewTestSurface(40, 10)
+	p := tui.NewPainter(surface, theme)
+
+	for _, tt := range []struct {
+		keys string
+		want string
+	}{
+		{keys: "/you\n", want: "match 1 of 3"},
+		{keys: "n", want: "match 2 of 3"},
+		{keys: "nn", want: "match 1 of 3"},
+		{keys: "N", want: "match 3 of 3"},
+		{keys: "/ghost\n", want: `no match for "ghost"`},
+	} {
+		ui.Root.OnKeyEvent(tui.KeyEvent{Key: tui.KeyEsc})
+		ui.Type(tt.keys)
+		p.Repaint(w)
+		// The status bar is the second-last line.
+		lines := strings.Split(surface.String(), "\n")
+		if got := lines[len(lines)-3]; !strings.HasSuffix(got, tt.want+" NORMAL") {
+			t.Errorf("after %q: unexpected status: got: %q want: %q", tt.keys, got, tt.want)
+		}
+	}
+	if len(d.Sent) != 0 {
+		t.Errorf("search unexpectedly sent: got: %v want: none", d.Sent)
+	}
+}
+
+func TestInput_SearchText(t *testing.T) {
+	t.Parallel()
+	ui := testhelper.NewController()
+	d := testhelper.NewBackend()
+	hamlet := data.Scope{Net: "HamNet", Name: "#hamlet"}
+	d.SetEvents(data.EventList{
+		&data.MessageEvent{EventID: data.EventID{Scope: hamlet, Seq: 1}, Nick: "hamlet", Text: "Alas, poor Yorick!"},
+		&data.MessageEvent{EventID: data.EventID{Scope: hamlet, Seq: 2}, Nick: "yorick", Text: "I knew him, Horatio"},
+	})
+	w := channel.New(hamlet, ui, d)
+	joinHamlet(w)
+	surface := tui.NewTestSurface(40, 10)
+	p := tui.NewPainter(surface, theme)
+
+	// Only the text matches, not the nick or its brackets.
+	for _, tt := range []struct {
+		query, want string
+	}{
+		{query: "yorick", want: "match 1 of 1"},
+		{query: "<", want: `no match for "<"`},
+	} {
+		ui.Type("/search " + tt.query + "\n")
+		p.Repaint(w)
+		lines := strings.Split(surface.String(), "\n")
+		if got := strings.TrimSpace(lines[len(lines)-3]); !strings.HasSuffix(got, tt.want) {
+			t.Errorf("%q: unexpected status: got: %q want: %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestInput_Only(t *testing.T) {
+	t.Parallel()
+	ui := testhelper.NewController()
+	d := testhelper.NewBackend()
+	w := channel.New(data.Scope{Net: "HamNet", Name: "#hamlet"}, ui, d)
+	w.SetRenderer(testRenderer)
+	joinHamlet(w)
+	surface := tui.NewTestSurface(60, 10)
+	p := tui.NewPainter(surface, theme)
+
+	filtered := []string{"4 <barnardo> Who's there?", "6 <barnardo> Long liue the King"}
+	for _, tt := range []struct {
+		do func()
+		// wantEvents are the Events shown, or the last of them if the
+		// view is full.
+		wantEvents []string
+		wantStatus string
+	}{
+		{
+			do:         func() { ui.Type("/only barnardo -join\n") },
+			wantEvents: filtered,
+			wantStatus: "+v only barnardo -join (7 hidden)",
+		},
+		{
+			do:         func() { w.OnKeyEvent(tui.KeyEvent{Key: tui.KeyF4}) },
+			wantEvents: []string{"9 <rosencrantz> Both your majesties"},
+			wantStatus: "+v",
+		},
+		{
+			do:         func() { w.OnKeyEvent(tui.KeyEvent{Key: tui.KeyF4}) },
+			wantEvents: filtered,
+			wantStatus: "+v only barnardo -join (7 hidden)",
+		},
+		{
+			do:         func() { ui.Type("/only\n") },
+			wantEvents: []string{"9 <rosencrantz> Both your majesties"},
+			wantStatus: "+v",
+		},
+	} {
+		tt.do()
+		p.Repaint(w)
+		lines := strings.Split(surface.String(), "\n")
+		// The events are between the topic and the status bar, which is
+		// the second-last line.
+		var events []string
+		for _, l := range lines[2 : len(lines)-3] {
+			if l := strings.TrimSpace(l); l != "" {
+				events = append(events, l)
+			}
+		}
+		if len(events) == len(lines)-5 {
+			events = events[len(events)-len(tt.wantEvents):]
+		}
+		if diff := cmp.Diff(events, tt.wantEvents); diff != "" {
+			t.Errorf("%q: unexpected events: (-got +want)\n%s", tt.wantStatus, diff)
+		}
+		if got := strings.TrimSpace(lines[len(lines)-3]); !strings.HasSuffix(got, tt.wantStatus) {
+			t.Errorf("unexpected status: got: %q want: %q", got, tt.wantStatus)
+		}
+	}
+}
+
 func testRenderer(e data.Event) tui.Widget {
 	r := tui.NewLabel(fmt.Sprintf("%d %s", e.ID().Seq, e.String()))
 	r.SetWordWrap(true)
@@ -203,6 +652,23 @@ func TestInput_Message(t *testing.T) {
 	}
 }
 
+func TestInput_BackendCommands(t *testing.T) {
+	t.Parallel()
+	ui := testhelper.NewController()
+	d := testhelper.NewBackend()
+	_ = channel.New(data.Scope{Net: "HamNet", Name: "#hamlet"}, ui, d)
+
+	// Commands the view doesn't know, and escaped slashes, go to the
+	// backend as they are.
+	ui.Type("/me draws\n//etc\n")
+	if diff := cmp.Diff(d.Sent, []string{"/me draws", "//etc"}); diff != "" {
+		t.Errorf("unexpected messages sent: (-got +want)\n%s", diff)
+	}
+	if ui.HasQuit {
+		t.Errorf("unexpected state: have quit")
+	}
+}
+
 func TestInput_QuitMessage(t *testing.T) {
 	t.Parallel()
 	ui := testhelper.NewController()
@@ -238,6 +704,39 @@ func TestInput_QuitKeybind(t *testing.T) {
 	}
 }
 
+func TestInput_Vim(t *testing.T) {
+	t.Parallel()
+	ui := testhelper.NewController()
+	ui.Keys, _ = keymap.Preset(keymap.Vim)
+	d := testhelper.NewBackend()
+	_ = channel.New(data.Scope{Net: "HamNet", Name: "#hamlet"}, ui, d)
+	esc := func() { ui.Root.OnKeyEvent(tui.KeyEvent{Key: tui.KeyEsc}) }
+
+	// The view starts in insert mode.
+	ui.Type("hello\n")
+	// Normal mode ignores unbound keys, including Enter.
+	esc()
+	ui.Type("xyz\n")
+	ui.Type("i")
+	ui.Type("again\n")
+	if diff := cmp.Diff(d.Sent, []string{"hello", "again"}); diff != "" {
+		t.Errorf("unexpected messages sent: (-got +want)\n%s", diff)
+	}
+
+	// Commands start with ':'. Esc cancels one.
+	esc()
+	ui.Type(":quit")
+	esc()
+	ui.Type("\n")
+	if ui.HasQuit {
+		t.Errorf("unexpected state: quit after command was cancelled")
+	}
+	ui.Type(":client\n")
+	if ui.V != testhelper.ClientView {
+		t.Errorf("unexpected root state: got: %v want: %v", ui.V, testhelper.ClientView)
+	}
+}
+
 func TestInput_ActivateClient(t *testing.T) {
 	t.Parallel()
 	ui := testhelper.NewController()
@@ -254,3 +753,508 @@ func TestInput_ActivateClient(t *testing.T) {
 		t.Errorf("unexpected root state: got: %v want: %v", ui.V, testhelper.ClientView)
 	}
 }
+
+var commandTests = []struct {
+	test  string
+	input string
+	// wantManaged are the expected calls to the backend.Manager.
+	wantManaged []string
+	wantSaved   int
+	wantView    testhelper.ActiveView
+	wantChannel string
+}{
+	{
+		test:        "join this channel",
+		input:       "/join #hamlet\n",
+		wantManaged: []string{"join HamNet #hamlet "},
+		wantView:    testhelper.ChannelView,
+		wantChannel: "#hamlet",
+	},
+	{
+		test:        "join with key",
+		input:       "/JOIN #battlements rosemary\n",
+		wantManaged: []string{"join HamNet #battlements rosemary"},
+		wantView:    testhelper.ChannelView,
+		wantChannel: "#battlements",
+	},
+	{
+		test:        "join without channel",
+		input:       "/join\n",
+		wantView:    testhelper.ChannelView,
+		wantChannel: "#hamlet",
+	},
+	{
+		test:        "part",
+		input:       "/part\n/part #battlements\n",
+		wantManaged: []string{"part HamNet #hamlet", "part HamNet #battlements"},
+		wantView:    testhelper.ChannelView,
+		wantChannel: "#hamlet",
+	},
+	{
+		test:        "nick",
+		input:       "/nick yorick_\n",
+		wantManaged: []string{"nick HamNet yorick_"},
+		wantView:    testhelper.ChannelView,
+		wantChannel: "#hamlet",
+	},
+	{
+		test:        "save",
+		input:       "/save\n",
+		wantSaved:   1,
+		wantView:    testhelper.ChannelView,
+		wantChannel: "#hamlet",
+	},
+}
+
+func TestInput_Commands(t *testing.T) {
+	for _, tt := range commandTests {
+		tt := tt
+		t.Run(tt.test, func(t *testing.T) {
+			t.Parallel()
+			ui := testhelper.NewController()
+			d := testhelper.NewBackend()
+			ui.ActivateChannel("HamNet", "#hamlet")
+			_ = channel.New(data.Scope{Net: "HamNet", Name: "#hamlet"}, ui, d)
+
+			ui.Type(tt.input)
+
+			if len(d.Sent) != 0 {
+				t.Errorf("message unexpectedly sent: got: %v want: none", d.Sent)
+			}
+			if diff := cmp.Diff(d.Managed, tt.wantManaged); diff != "" {
+				t.Errorf("unexpected backend calls: (-got +want)\n%s", diff)
+			}
+			if d.Saved != tt.wantSaved {
+				t.Errorf("unexpected saves: got: %d want: %d", d.Saved, tt.wantSaved)
+			}
+			if ui.V != tt.wantView || ui.Channel != tt.wantChannel {
+				t.Errorf("unexpected view: got: %v %q want: %v %q", ui.V, ui.Channel, tt.wantView, tt.wantChannel)
+			}
+		})
+	}
+}
+
+func TestInput_Cancel(t *testing.T) {
+	t.Parallel()
+	ui := testhelper.NewController()
+	d := testhelper.NewBackend()
+	hamlet := data.Scope{Net: "HamNet", Name: "#hamlet"}
+	d.Outbox = map[data.Scope][]outbox.Message{
+		hamlet: {
+			{ID: 1, Text: "To be, or not to be"},
+			{ID: 2, Text: "that is the question"},
+			{ID: 3, Text: "Whether 'tis nobler in the mind to suffer"},
+		},
+	}
+	_ = channel.New(hamlet, ui, d)
+
+	ui.Type("/cancel\n")
+	want := []outbox.Message{
+		{ID: 1, Text: "To be, or not to be"},
+		{ID: 2, Text: "that is the question"},
+	}
+	if diff := cmp.Diff(d.Outbox[hamlet], want); diff != "" {
+		t.Errorf("unexpected pending messages: (-got +want)\n%s", diff)
+	}
+
+	ui.Type("/cancel all\n")
+	if got := d.Outbox[hamlet]; len(got) != 0 {
+		t.Errorf("unexpected pending messages: got: %v want: none", got)
+	}
+	if len(d.Sent) != 0 {
+		t.Errorf("message unexpectedly sent: got: %v want: none", d.Sent)
+	}
+}
+
+func TestInput_DoNotDisturb(t *testing.T) {
+	t.Parallel()
+	ui := testhelper.NewController()
+	d := testhelper.NewBackend()
+	_ = channel.New(data.Scope{Net: "HamNet", Name: "#hamlet"}, ui, d)
+
+	for _, tt := range []struct {
+		input string
+		want  bool
+	}{
+		{input: "/dnd\n", want: true},
+		{input: "/dnd\n", want: false},
+		{input: "/dnd on\n", want: true},
+		{input: "/dnd on\n", want: true},
+		{input: "/dnd off\n", want: false},
+		{input: "/dnd maybe\n", want: false},
+	} {
+		ui.Type(tt.input)
+		if d.DND != tt.want {
+			t.Errorf("after %q: unexpected do not disturb: got: %v want: %v", tt.input, d.DND, tt.want)
+		}
+	}
+}
+
+func TestInput_Ignore(t *testing.T) {
+	t.Parallel()
+	ui := testhelper.NewController()
+	d := testhelper.NewBackend()
+	_ = channel.New(data.Scope{Net: "HamNet", Name: "#hamlet"}, ui, d)
+
+	for _, tt := range []struct {
+		input string
+		want  []string
+	}{
+		{input: "/ignore polonius\n", want: []string{"polonius!*@* in HamNet/#hamlet"}},
+		{input: "/ignore -network account:rosencrantz\n", want: []string{
+			"polonius!*@* in HamNet/#hamlet",
+			"account:rosencrantz on HamNet",
+		}},
+		{input: "/ignore -global *@spam.example.net\n", want: []string{
+			"polonius!*@* in HamNet/#hamlet",
+			"account:rosencrantz on HamNet",
+			"*!*@spam.example.net",
+		}},
+		{input: "/ignore -for soon polonius\n", want: []string{
+			"polonius!*@* in HamNet/#hamlet",
+			"account:rosencrantz on HamNet",
+			"*!*@spam.example.net",
+		}},
+		{input: "/unignore polonius\n", want: []string{
+			"account:rosencrantz on HamNet",
+			"*!*@spam.example.net",
+		}},
+		{input: "/unignore account:rosencrantz\n", want: []string{
+			"account:rosencrantz on HamNet",
+			"*!*@spam.example.net",
+		}},
+		{input: "/unignore -network account:rosencrantz\n", want: []string{
+			"*!*@spam.example.net",
+		}},
+		{input: "/unignore -global *@spam.example.net\n"},
+	} {
+		ui.Type(tt.input)
+		var got []string
+		for _, r := range d.Rules {
+			got = append(got, r.String())
+		}
+		if diff := cmp.Diff(got, tt.want); diff != "" {
+			t.Errorf("after %q: unexpected rules: (-got +want)\n%s", tt.input, diff)
+		}
+	}
+
+	ui.Type("/ignore -for 1h polonius\n")
+	if len(d.Rules) != 1 || d.Rules[0].Expires.IsZero() {
+		t.Errorf("unexpected rules after /ignore -for: got: %v want: one that expires", d.Rules)
+	}
+}
+
+var completeTests = []struct {
+	test        string
+	input       string
+	wantSent    []string
+	wantManaged []string
+}{
+	{
+		test:     "nick at line start",
+		input:    "g\t\n",
+		wantSent: []string{"guildenstern: "},
+	},
+	{
+		test:     "cycle nicks",
+		input:    "g\t\t\n",
+		wantSent: []string{"gertrude: "},
+	},
+	{
+		test:     "cycle wraps around",
+		input:    "G\t\t\t\n",
+		wantSent: []string{"guildenstern: "},
+	},
+	{
+		test:     "nick within line",
+		input:    "thanks ro\t\n",
+		wantSent: []string{"thanks rosencrantz "},
+	},
+	{
+		test:     "own nick isn't completed",
+		input:    "yo\t\n",
+		wantSent: []string{"yo"},
+	},
+	{
+		test:     "typing starts a new completion",
+		input:    "g\tand g\t\n",
+		wantSent: []string{"guildenstern: and guildenstern "},
+	},
+	{
+		test:     "channel",
+		input:    "see #b\t\n",
+		wantSent: []string{"see #battlements "},
+	},
+	{
+		test:        "command and argument",
+		input:       "/jo\t#h\t\n",
+		wantManaged: []string{"join HamNet #hamlet "},
+	},
+	{
+		test:        "argument without completion",
+		input:       "/nick g\t\n",
+		wantManaged: []string{"nick HamNet g"},
+	},
+	{
+		test:     "no completions",
+		input:    "zounds\t\n",
+		wantSent: []string{"zounds"},
+	},
+}
+
+func TestInput_Complete(t *testing.T) {
+	for _, tt := range completeTests {
+		tt := tt
+		t.Run(tt.test, func(t *testing.T) {
+			t.Parallel()
+			ui := testhelper.NewController()
+			d := testhelper.NewBackend()
+			s := data.Scope{Net: "HamNet", Name: "#hamlet"}
+			d.Known = map[string][]string{"HamNet": {"#battlements", "#hamlet"}}
+			d.Nicks = map[data.Scope][]string{
+				s: {"guildenstern", "yorick", "rosencrantz", "gertrude"},
+			}
+			ui.ActivateChannel(s.Net, s.Name)
+			v := channel.New(s, ui, d)
+			v.Receive(&data.NetworkStateEvent{
+				EventID:      data.EventID{Scope: data.Scope{Net: s.Net}},
+				NetworkState: data.NetworkState{Nick: "yorick"},
+			})
+
+			ui.Type(tt.input)
+
+			if diff := cmp.Diff(d.Sent, tt.wantSent); diff != "" {
+				t.Errorf("unexpected messages sent: (-got +want)\n%s", diff)
+			}
+			if diff := cmp.Diff(d.Managed, tt.wantManaged); diff != "" {
+				t.Errorf("unexpected backend calls: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestInput_CompletePrevious(t *testing.T) {
+	t.Parallel()
+	ui := testhelper.NewController()
+	d := testhelper.NewBackend()
+	s := data.Scope{Net: "HamNet", Name: "#hamlet"}
+	d.Nicks = map[data.Scope][]string{s: {"guildenstern", "gertrude"}}
+	_ = channel.New(s, ui, d)
+
+	backtab := func() { ui.Root.OnKeyEvent(tui.KeyEvent{Key: tui.KeyBacktab}) }
+	ui.Type("g")
+	backtab()
+	ui.Type("\n")
+	ui.Type("g\t")
+	backtab()
+	backtab()
+	ui.Type("\n")
+
+	if diff := cmp.Diff(d.Sent, []string{"gertrude: ", "guildenstern: "}); diff != "" {
+		t.Errorf("unexpected messages sent: (-got +want)\n%s", diff)
+	}
+}
+
+func TestInput_History(t *testing.T) {
+	t.Parallel()
+	ui := testhelper.NewController()
+	d := testhelper.NewBackend()
+	s := data.Scope{Net: "HamNet", Name: "#hamlet"}
+	_ = channel.New(s, ui, d)
+	press := func(keys ...tui.Key) {
+		for _, k := range keys {
+			ui.Root.OnKeyEvent(tui.KeyEvent{Key: k})
+		}
+	}
+
+	ui.Type("!deploy staging\nhello\n!deploy prod\n")
+	// Up and Down move through the history.
+	press(tui.KeyUp, tui.KeyUp, tui.KeyDown, tui.KeyEnter)
+	// Moving past the newest line returns to the line being edited.
+	ui.Type("draft")
+	press(tui.KeyUp, tui.KeyDown, tui.KeyEnter)
+	// Ctrl+R searches; the first Enter accepts the match, the second sends it.
+	press(tui.KeyCtrlR)
+	ui.Type("stag\n\n")
+
+	want := []string{"!deploy staging", "hello", "!deploy prod", "!deploy prod", "draft", "!deploy staging"}
+	if diff := cmp.Diff(d.Sent, want); diff != "" {
+		t.Errorf("unexpected messages sent: (-got +want)\n%s", diff)
+	}
+	wantHistory := []string{"!deploy staging", "hello", "!deploy prod", "draft", "!deploy staging"}
+	if diff := cmp.Diff(ui.History().Lines(s), wantHistory); diff != "" {
+		t.Errorf("unexpected history: (-got +want)\n%s", diff)
+	}
+
+	// Each channel has its own history.
+	other := data.Scope{Net: "HamNet", Name: "#battlements"}
+	_ = channel.New(other, ui, d)
+	press(tui.KeyUp)
+	ui.Type("'tis bitter cold\n")
+	if got, want := d.Sent[len(d.Sent)-1], "'tis bitter cold"; got != want {
+		t.Errorf("unexpected message sent: got: %q want: %q", got, want)
+	}
+}
+
+func TestInput_HistorySearchCancel(t *testing.T) {
+	t.Parallel()
+	ui := testhelper.NewController()
+	d := testhelper.NewBackend()
+	s := data.Scope{Net: "HamNet", Name: "#hamlet"}
+	ui.History().Add(s, "!deploy prod")
+	_ = channel.New(s, ui, d)
+
+	ui.Type("draft")
+	ui.Root.OnKeyEvent(tui.KeyEvent{Key: tui.KeyCtrlR})
+	ui.Type("dep")
+	ui.Root.OnKeyEvent(tui.KeyEvent{Key: tui.KeyEsc})
+	ui.Type("!\n")
+
+	if diff := cmp.Diff(d.Sent, []string{"draft!"}); diff != "" {
+		t.Errorf("unexpected messages sent: (-got +want)\n%s", diff)
+	}
+}
+
+func TestInput_Multiline(t *testing.T) {
+	t.Parallel()
+	ui := testhelper.NewController()
+	d := testhelper.NewBackend()
+	s := data.Scope{Net: "HamNet", Name: "#hamlet"}
+	_ = channel.New(s, ui, d)
+	newline := func() { ui.Root.OnKeyEvent(tui.KeyEvent{Key: tui.KeyEnter, Modifiers: tui.ModAlt}) }
+
+	ui.Type("to be")
+	newline()
+	ui.Type("or not")
+	newline()
+	// Backspace on an empty line joins it to the one before.
+	ui.Root.OnKeyEvent(tui.KeyEvent{Key: tui.KeyBackspace2})
+	ui.Type(" to be\n")
+	// Commands can't have several lines; they're kept to edit.
+	ui.Type("/join #battlements")
+	newline()
+	ui.Type("/join #hamlet\n")
+
+	if diff := cmp.Diff(d.Sent, []string{"to be\nor not to be"}); diff != "" {
+		t.Errorf("unexpected messages sent: (-got +want)\n%s", diff)
+	}
+	if len(d.Managed) != 0 {
+		t.Errorf("unexpected backend calls: got: %v want: none", d.Managed)
+	}
+
+	// Messages recalled from the history keep their lines.
+	ui.Root.OnKeyEvent(tui.KeyEvent{Key: tui.KeyUp})
+	ui.Type("?\n")
+	if got, want := d.Sent[len(d.Sent)-1], "to be\nor not to be?"; got != want {
+		t.Errorf("unexpected message sent: got: %q want: %q", got, want)
+	}
+}
+
+// paste types the text as a terminal does in bracketed paste mode.
+func paste(ui *testhelper.Controller, text string) {
+	ui.Root.OnKeyEvent(tui.KeyEvent{Key: tui.KeyRune, Rune: '[', Modifiers: tui.ModAlt})
+	ui.Type("200~")
+	ui.Type(text)
+	ui.Root.OnKeyEvent(tui.KeyEvent{Key: tui.KeyRune, Rune: '[', Modifiers: tui.ModAlt})
+	ui.Type("201~")
+}
+
+func TestInput_Paste(t *testing.T) {
+	for _, tt := range []struct {
+		test     string
+		preset   string
+		paste    string
+		then     string
+		wantSent []string
+	}{
+		{
+			test:     "confirmed",
+			paste:    "Who's there?\nNay, answer me.\n",
+			then:     "y",
+			wantSent: []string{"Who's there?\nNay, answer me."},
+		},
+		{
+			test:     "declined",
+			paste:    "Who's there?\nNay, answer me.\n",
+			then:     "n!\n",
+			wantSent: []string{"Who's there?\nNay, answer me.!"},
+		},
+		{
+			test:     "other keys wait for confirmation",
+			paste:    "one\ntwo",
+			then:     "\nxy",
+			wantSent: []string{"one\ntwo"},
+		},
+		{
+			test:     "single line",
+			paste:    "/quit",
+			then:     " now",
+			wantSent: nil,
+		},
+		{
+			test:     "vim normal mode",
+			preset:   keymap.Vim,
+			paste:    "ZZ\nG",
+			then:     "y",
+			wantSent: []string{"ZZ\nG"},
+		},
+	} {
+		tt := tt
+		t.Run(tt.test, func(t *testing.T) {
+			t.Parallel()
+			ui := testhelper.NewController()
+			if tt.preset != "" {
+				ui.Keys, _ = keymap.Preset(tt.preset)
+			}
+			d := testhelper.NewBackend()
+			_ = channel.New(data.Scope{Net: "HamNet", Name: "#hamlet"}, ui, d)
+			if tt.preset == keymap.Vim {
+				ui.Root.OnKeyEvent(tui.KeyEvent{Key: tui.KeyEsc})
+			}
+
+			paste(ui, tt.paste)
+			ui.Type(tt.then)
+
+			if diff := cmp.Diff(d.Sent, tt.wantSent); diff != "" {
+				t.Errorf("unexpected messages sent: (-got +want)\n%s", diff)
+			}
+			if ui.HasQuit {
+				t.Errorf("unexpected state: pasted text quit")
+			}
+		})
+	}
+}
+
+func TestInput_NotPaste(t *testing.T) {
+	t.Parallel()
+	ui := testhelper.NewController()
+	d := testhelper.NewBackend()
+	_ = channel.New(data.Scope{Net: "HamNet", Name: "#hamlet"}, ui, d)
+
+	// Keys that start like a paste marker, but aren't one, are typed.
+	ui.Root.OnKeyEvent(tui.KeyEvent{Key: tui.KeyRune, Rune: '[', Modifiers: tui.ModAlt})
+	ui.Type("20x\n")
+
+	if diff := cmp.Diff(d.Sent, []string{"[20x"}); diff != "" {
+		t.Errorf("unexpected messages sent: (-got +want)\n%s", diff)
+	}
+}
+
+func TestTitle(t *testing.T) {
+	t.Parallel()
+	ui := testhelper.NewController()
+	d := testhelper.NewBackend()
+	s := data.Scope{Net: "HamNet", Name: "#hamlet"}
+	v := channel.New(s, ui, d)
+	if want := "HamNet #hamlet"; ui.Title != want {
+		t.Errorf("unexpected title: got: %q want: %q", ui.Title, want)
+	}
+
+	v.Receive(&data.ChannelStateEvent{
+		EventID:      data.EventID{Scope: s},
+		ChannelState: data.ChannelState{Unread: 3},
+	})
+	if want := "HamNet #hamlet (3)"; ui.Title != want {
+		t.Errorf("unexpected title: got: %q want: %q", ui.Title, want)
+	}
+}
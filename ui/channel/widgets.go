@@ -5,6 +5,8 @@ import (
 
 	"github.com/marcusolsson/tui-go"
 
+	"github.com/cceckman/discoirc/backend/outbox"
+	"github.com/cceckman/discoirc/config"
 	"github.com/cceckman/discoirc/data"
 	"github.com/cceckman/discoirc/ui/widgets"
 )
@@ -35,34 +37,312 @@ type EventsWidget struct {
 
 	source EventsProvider
 	last   data.Seq
+	// end is the last Event shown when the view is scrolled back, or 0 if
+	// the newest Events are shown.
+	end data.Seq
 
 	scope data.Scope
+	// pending are the messages waiting to be sent, shown after the newest
+	// Events.
+	pending []outbox.Message
+	// highlight is the text of a search, highlighted where it appears in
+	// the Events shown.
+	highlight string
+	// filter, if set, hides the Events that don't match it. hidden is how
+	// many Events, up to counted, it hides.
+	filter  *data.Filter
+	hidden  int
+	counted data.Seq
+	// membership configures how joins, parts, and quits are shown; expanded
+	// is set to list the nicks in folded netsplits.
+	membership config.Membership
+	expanded   bool
+	// reveal is set to show the Events from ignored users, which are hidden
+	// otherwise.
+	reveal bool
 
 	Renderer EventRenderer
 }
 
-// SetLast sets the last Event displayed. If it's newer than the previous value,
-// it may cause request a backfill of its contents.
+// SetLast sets the newest Event. If it's newer than the previous value,
+// it may cause request a backfill of its contents. If the view is scrolled
+// back, it stays in place.
 func (v *EventsWidget) SetLast(new data.Seq) {
 	if v.last != new && v.source != nil {
 		v.last = new
+		if v.filter != nil {
+			v.countHidden()
+		}
+		if v.end == 0 {
+			v.refreshContents()
+		}
+	}
+}
+
+// SetPending sets the messages waiting to be sent. They're shown, dimmed, after
+// the newest Events.
+func (v *EventsWidget) SetPending(msgs []outbox.Message) {
+	if samePending(v.pending, msgs) {
+		return
+	}
+	v.pending = msgs
+	if v.end == 0 && v.source != nil {
+		v.refreshContents()
+	}
+}
+
+func samePending(a, b []outbox.Message) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Scroll scrolls the view n Events back, or forward if n is negative, skipping
+// those the filter hides. Scrolling forward past the newest Event returns to
+// following new Events.
+func (v *EventsWidget) Scroll(n int) {
+	end := v.shown() - data.Seq(n)
+	if v.hiding() && v.source != nil {
+		end = v.filteredEnd(n)
+	}
+	if end < 1 {
+		end = 1
+	}
+	if end >= v.last {
+		end = 0
+	}
+	if end != v.end && v.source != nil {
+		v.end = end
+		v.refreshContents()
+	}
+}
+
+// ScrollTo scrolls the view so the Event is the last shown. If the newest
+// Event isn't known yet, the view stays there once it is.
+func (v *EventsWidget) ScrollTo(s data.Seq) {
+	if v.last != 0 && s >= v.last {
+		s = 0
+	}
+	if s != v.end && v.source != nil {
+		v.end = s
+		v.refreshContents()
+	}
+}
+
+// SetHighlight sets the text to highlight in the Events shown, or "" for none.
+func (v *EventsWidget) SetHighlight(text string) {
+	if text != v.highlight {
+		v.highlight = text
+		if v.source != nil {
+			v.refreshContents()
+		}
+	}
+}
+
+// SetFilter sets the filter of the Events shown, or nil to show them all.
+func (v *EventsWidget) SetFilter(f *data.Filter) {
+	if f == v.filter {
+		return
+	}
+	v.filter, v.hidden, v.counted = f, 0, 0
+	if v.source != nil {
+		if f != nil {
+			v.countHidden()
+		}
+		v.refreshContents()
+	}
+}
+
+// SetMembership sets how joins, parts, and quits are shown.
+func (v *EventsWidget) SetMembership(m config.Membership) {
+	if m != v.membership {
+		v.membership = m
+		if v.source != nil {
+			v.refreshContents()
+		}
+	}
+}
+
+// SetExpanded sets whether folded netsplits list the nicks that quit.
+func (v *EventsWidget) SetExpanded(expanded bool) {
+	if expanded != v.expanded {
+		v.expanded = expanded
+		if v.source != nil {
+			v.refreshContents()
+		}
+	}
+}
+
+// SetReveal sets whether the Events from ignored users are shown.
+func (v *EventsWidget) SetReveal(reveal bool) {
+	if reveal != v.reveal {
+		v.reveal = reveal
+		if v.source != nil {
+			v.refreshContents()
+		}
+	}
+}
+
+// hiding reports whether any Events are hidden: those the filter doesn't
+// match, or those from ignored users.
+func (v *EventsWidget) hiding() bool {
+	return v.filter != nil || !v.reveal
+}
+
+// Hidden returns how many of the channel's Events the filter hides.
+func (v *EventsWidget) Hidden() int {
+	return v.hidden
+}
+
+// countHidden counts the Events the filter hides, from the last counted to
+// the newest.
+func (v *EventsWidget) countHidden() {
+	for last := v.last; last > v.counted; {
+		events := v.source.EventsBefore(v.scope, searchPage, last)
+		if len(events) == 0 {
+			break
+		}
+		for _, e := range events {
+			if e.ID().Seq > v.counted && !v.filter.MatchEvent(e) {
+				v.hidden++
+			}
+		}
+		last = events[0].ID().Seq - 1
+	}
+	v.counted = v.last
+}
+
+// matching returns the newest n Events that match the filter, if any, and
+// aren't from ignored users unless they're revealed, or all of them if n is 0,
+// of those after 'after' and up to 'last'; oldest first.
+func (v *EventsWidget) matching(n int, after, last data.Seq) data.EventList {
+	var found data.EventList
+	page := n
+	if page <= 0 || page > searchPage {
+		page = searchPage
+	}
+	for last > after && (n <= 0 || len(found) < n) {
+		events := v.source.EventsBefore(v.scope, page, last)
+		if len(events) == 0 {
+			break
+		}
+		for i := len(events) - 1; i >= 0 && (n <= 0 || len(found) < n); i-- {
+			e := events[i]
+			if e.ID().Seq <= after {
+				break
+			}
+			if data.Ignored(e) && !v.reveal {
+				continue
+			}
+			if v.filter == nil || v.filter.MatchEvent(e) {
+				found = append(found, e)
+			}
+		}
+		last = events[0].ID().Seq - 1
+	}
+	// Found newest first.
+	for i, j := 0, len(found)-1; i < j; i, j = i+1, j-1 {
+		found[i], found[j] = found[j], found[i]
+	}
+	return found
+}
+
+// visible returns the last n Events to show, up to 'last': those that match
+// the filter, if any, and aren't ignored, with joins, parts, and quits folded.
+func (v *EventsWidget) visible(n int, last data.Seq) data.EventList {
+	if v.membership == (config.Membership{}) {
+		return v.matching(n, 0, last)
+	}
+	// Folding shows fewer Events than are fetched; fetch more until there
+	// are enough, and the oldest, which may be part of a run, isn't shown.
+	var shown data.EventList
+	for want := n; ; want *= 2 {
+		events := v.matching(want, 0, last)
+		shown = foldMembership(events, v.membership, v.expanded)
+		if len(shown) > n || len(events) < want {
+			break
+		}
+	}
+	if len(shown) > n {
+		shown = shown[len(shown)-n:]
+	}
+	return shown
+}
+
+// filteredEnd returns the last Event to show after scrolling n matching Events
+// back, or forward if n is negative.
+func (v *EventsWidget) filteredEnd(n int) data.Seq {
+	if n >= 0 {
+		events := v.matching(n+1, 0, v.shown())
+		if len(events) == 0 {
+			return v.shown()
+		}
+		return events[0].ID().Seq
+	}
+	events := v.matching(0, v.shown(), v.last)
+	if -n >= len(events) {
+		return v.last
+	}
+	return events[-n-1].ID().Seq
+}
+
+// ScrollToEnd scrolls the view to the newest Events.
+func (v *EventsWidget) ScrollToEnd() {
+	if v.end != 0 && v.source != nil {
+		v.end = 0
 		v.refreshContents()
 	}
 }
 
+// shown returns the last Event shown.
+func (v *EventsWidget) shown() data.Seq {
+	if v.end != 0 {
+		return v.end
+	}
+	return v.last
+}
+
 // refreshContents redraws the contents of the EventsWidget,
 func (v *EventsWidget) refreshContents() {
 	// TODO:
 	// 1. Assume EventsSince may take a long time; handle it in a non-blocking way.
 	// 2. Handle single-new-message more gracefully, i.e. without redrawing
 	//    all of the widgets.
-	events := v.source.EventsBefore(
-		v.scope,
-		v.TailBox.Size().Y, v.last)
+	var events data.EventList
+	if v.hiding() || v.membership != (config.Membership{}) {
+		// Fetch until there are enough Events to fill the view.
+		if n := v.TailBox.Size().Y; n > 0 {
+			events = v.visible(n, v.shown())
+		}
+	} else {
+		events = v.source.EventsBefore(
+			v.scope,
+			v.TailBox.Size().Y, v.shown())
+	}
 
 	w := make([]tui.Widget, len(events))
 	for i, e := range events {
 		w[i] = v.Renderer(e)
+		if l, ok := w[i].(*tui.Label); ok && v.highlight != "" && containsFold(l.Text(), v.highlight) {
+			w[i] = &matchLabel{Label: l, query: v.highlight}
+		}
+		if l, ok := w[i].(*eventLine); ok {
+			l.query = v.highlight
+		}
+		if data.Ignored(e) {
+			w[i] = &ignoredWidget{Widget: w[i]}
+		}
+	}
+	if v.end == 0 {
+		for _, m := range v.pending {
+			w = append(w, renderPending(m))
+		}
 	}
 	v.SetContents(w...)
 }
@@ -87,3 +367,37 @@ func (rb *reversedBox) Draw(p *tui.Painter) {
 		rb.Box.Draw(p)
 	})
 }
+
+// pendingMarker marks messages waiting to be sent.
+const pendingMarker = "⏲ "
+
+// renderPending renders a message waiting to be sent.
+func renderPending(m outbox.Message) tui.Widget {
+	l := tui.NewLabel(pendingMarker + m.Text)
+	l.SetWordWrap(true)
+	l.SetSizePolicy(tui.Expanding, tui.Minimum)
+	return &pendingLabel{Label: l}
+}
+
+// pendingLabel is a Label that applies the "pending" style to its text.
+type pendingLabel struct {
+	*tui.Label
+}
+
+func (pl *pendingLabel) Draw(p *tui.Painter) {
+	p.WithStyle("pending", func(p *tui.Painter) {
+		pl.Label.Draw(p)
+	})
+}
+
+// ignoredWidget is a Widget that applies the "ignored" style to an Event from
+// an ignored user, shown because ignored Events are revealed.
+type ignoredWidget struct {
+	tui.Widget
+}
+
+func (iw *ignoredWidget) Draw(p *tui.Painter) {
+	p.WithStyle("ignored", func(p *tui.Painter) {
+		iw.Widget.Draw(p)
+	})
+}
@@ -41,6 +41,7 @@ type Channel struct {
 	name    string
 
 	focus           bool
+	unread          int
 	indicatorWidget *indicator
 	nameWidget      *tui.Label
 	modeWidget      *tui.Label
@@ -50,8 +51,13 @@ type Channel struct {
 
 // UpdateChannel updates the view with the provided channel state.
 func (c *Channel) UpdateChannel(ch data.ChannelState) {
+	c.unread = ch.Unread
 	c.modeWidget.SetText(ch.Mode)
-	c.unreadWidget.SetText(fmt.Sprintf("✉ %d", ch.Unread))
+	if ch.Highlights > 0 {
+		c.unreadWidget.SetText(fmt.Sprintf("✉ %d ★ %d", ch.Unread, ch.Highlights))
+	} else {
+		c.unreadWidget.SetText(fmt.Sprintf("✉ %d", ch.Unread))
+	}
 	c.membersWidget.SetText(fmt.Sprintf("%d ☺", ch.Members))
 }
 
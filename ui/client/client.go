@@ -3,6 +3,8 @@ package client
 
 import (
 	"github.com/marcusolsson/tui-go"
+
+	"github.com/cceckman/discoirc/ui/keymap"
 )
 
 // View is a top-level view of the client state.
@@ -16,6 +18,12 @@ type View interface {
 type UIController interface {
 	Update(func())
 	ActivateChannel(network, channel string)
+	// ActivateFilter shows the filter view of the messages matching the
+	// query.
+	ActivateFilter(query string)
 	SetWidget(tui.Widget)
+	SetTitle(title string)
 	Quit()
+	// Keymap returns the key bindings to use.
+	Keymap() *keymap.Keymap
 }
@@ -0,0 +1,213 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/marcusolsson/tui-go"
+
+	"github.com/cceckman/discoirc/config"
+)
+
+// NetworkForm is a form for adding a network, or editing an existing one.
+type NetworkForm struct {
+	*tui.Box
+
+	// existing is the configuration being edited, or nil for a new network.
+	existing *config.Network
+
+	name, host, port, nick *tui.Entry
+	tls, save              *checkbox
+	status                 *tui.Label
+
+	fields  []tui.Widget
+	markers []*tui.Label
+	focus   int
+
+	onSubmit func(n *config.Network, save bool) error
+	onCancel func()
+}
+
+// NewNetworkForm returns a form for the existing network configuration, or for
+// a new network if existing is nil.
+// When the user submits the form, onSubmit is called with the new
+// configuration of the network, and whether the user asked for it to be saved.
+// If onSubmit returns an error, it's shown in the form.
+func NewNetworkForm(existing *config.Network, onSubmit func(n *config.Network, save bool) error, onCancel func()) *NetworkForm {
+	f := &NetworkForm{
+		existing: existing,
+		name:     tui.NewEntry(),
+		host:     tui.NewEntry(),
+		port:     tui.NewEntry(),
+		nick:     tui.NewEntry(),
+		tls:      newCheckbox("TLS"),
+		save:     newCheckbox("Save to configuration file"),
+		status:   tui.NewLabel(""),
+		onSubmit: onSubmit,
+		onCancel: onCancel,
+	}
+
+	title := "New network"
+	var rows []tui.Widget
+	if existing == nil {
+		rows = append(rows, f.row("Name: ", f.name))
+	} else {
+		title = fmt.Sprintf("Edit network %q", existing.Name)
+		if len(existing.Servers) > 0 {
+			f.host.SetText(existing.Servers[0].Host)
+			f.port.SetText(strconv.Itoa(existing.Servers[0].Port))
+		}
+		f.nick.SetText(existing.Nick)
+		f.tls.Set(existing.TLS)
+	}
+	rows = append(rows,
+		f.row("Host: ", f.host),
+		f.row("Port: ", f.port),
+		f.row("Nick: ", f.nick),
+		f.row("", f.tls),
+		f.row("", f.save),
+	)
+
+	f.Box = tui.NewVBox(tui.NewLabel(title))
+	for _, r := range rows {
+		f.Box.Append(r)
+	}
+	f.Box.Append(tui.NewLabel("Tab: next  Space: toggle  Enter: apply  Esc: cancel"))
+	f.Box.Append(f.status)
+	f.Box.Append(tui.NewSpacer())
+
+	f.setFocus(0)
+	return f
+}
+
+// row returns a row of the form for the field, and adds the field to the
+// focus order.
+func (f *NetworkForm) row(label string, field tui.Widget) tui.Widget {
+	marker := tui.NewLabel(" ")
+	f.fields = append(f.fields, field)
+	f.markers = append(f.markers, marker)
+	if e, ok := field.(*tui.Entry); ok {
+		e.SetSizePolicy(tui.Expanding, tui.Minimum)
+	}
+	if label == "" {
+		return tui.NewHBox(marker, field)
+	}
+	return tui.NewHBox(marker, tui.NewLabel(label), field)
+}
+
+func (f *NetworkForm) setFocus(i int) {
+	f.fields[f.focus].SetFocused(false)
+	f.markers[f.focus].SetText(" ")
+	f.focus = (i + len(f.fields)) % len(f.fields)
+	f.fields[f.focus].SetFocused(true)
+	f.markers[f.focus].SetText(">")
+}
+
+// SetError shows the error message in the form.
+func (f *NetworkForm) SetError(msg string) {
+	f.status.SetText(msg)
+}
+
+// OnKeyEvent handles key presses.
+func (f *NetworkForm) OnKeyEvent(ev tui.KeyEvent) {
+	switch ev.Key {
+	case tui.KeyEsc:
+		if f.onCancel != nil {
+			f.onCancel()
+		}
+	case tui.KeyTab, tui.KeyDown:
+		f.setFocus(f.focus + 1)
+	case tui.KeyBacktab, tui.KeyUp:
+		f.setFocus(f.focus - 1)
+	case tui.KeyEnter:
+		f.submit()
+	default:
+		f.fields[f.focus].OnKeyEvent(ev)
+	}
+}
+
+func (f *NetworkForm) submit() {
+	n, err := f.network()
+	if err == nil && f.onSubmit != nil {
+		err = f.onSubmit(n, f.save.checked)
+	}
+	if err != nil {
+		f.SetError(err.Error())
+	}
+}
+
+// network returns the network configuration described by the form.
+func (f *NetworkForm) network() (*config.Network, error) {
+	var n *config.Network
+	if f.existing != nil {
+		n = f.existing.Clone()
+	} else {
+		name := strings.TrimSpace(f.name.Text())
+		if name == "" {
+			return nil, fmt.Errorf("name must not be empty")
+		}
+		n = &config.Network{Name: name}
+	}
+
+	host := strings.TrimSpace(f.host.Text())
+	if host == "" || strings.ContainsAny(host, " /:") {
+		return nil, fmt.Errorf("invalid host %q", host)
+	}
+	port := config.DefaultPort
+	if f.tls.checked {
+		port = config.DefaultTLSPort
+	}
+	if p := strings.TrimSpace(f.port.Text()); p != "" {
+		var err error
+		port, err = strconv.Atoi(p)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %q", p)
+		}
+	}
+	nick := strings.TrimSpace(f.nick.Text())
+	if err := config.CheckNick(nick); err != nil {
+		return nil, err
+	}
+
+	// The form edits only the first server; others are kept.
+	s := config.Server{Host: host, Port: port}
+	if len(n.Servers) == 0 {
+		n.Servers = []config.Server{s}
+	} else {
+		n.Servers[0] = s
+	}
+	n.TLS = f.tls.checked
+	n.Nick = nick
+	return n, nil
+}
+
+// checkbox is a Label showing whether an option is set.
+// Space toggles it.
+type checkbox struct {
+	*tui.Label
+	text    string
+	checked bool
+}
+
+func newCheckbox(text string) *checkbox {
+	c := &checkbox{Label: tui.NewLabel(""), text: text}
+	c.Set(false)
+	return c
+}
+
+// Set sets whether the option is checked.
+func (c *checkbox) Set(checked bool) {
+	c.checked = checked
+	mark := ' '
+	if checked {
+		mark = 'x'
+	}
+	c.SetText(fmt.Sprintf("[%c] %s", mark, c.text))
+}
+
+func (c *checkbox) OnKeyEvent(ev tui.KeyEvent) {
+	if ev.Key == tui.KeyRune && ev.Rune == ' ' {
+		c.Set(!c.checked)
+	}
+}
@@ -1,11 +1,14 @@
 package client
 
 import (
+	"fmt"
 	"sort"
 	"sync"
 
 	"github.com/cceckman/discoirc/backend"
+	"github.com/cceckman/discoirc/config"
 	"github.com/cceckman/discoirc/data"
+	"github.com/cceckman/discoirc/ui/keymap"
 
 	"github.com/marcusolsson/tui-go"
 )
@@ -14,18 +17,30 @@ var _ View = &Client{}
 
 // New sets the root view of the UIController to a new Client, with data
 // drawn and updated from the backend.
+// If the provider is also a backend.Manager, the user can manage networks and
+// channels from the view; if it's a backend.Saver, they can save the changes.
 func New(ctl UIController, provider backend.DataPublisher) *Client {
 	c := &Client{
 		networksBox: tui.NewVBox(tui.NewSpacer()),
 		controller:  ctl,
 	}
+	c.manager, _ = provider.(backend.Manager)
+	c.saver, _ = provider.(backend.Saver)
 
 	c.Widget = c.networksBox
 	c.focused = c
 
+	keys := keymap.Default()
+	// Allow nil for tests.
+	if c.controller != nil {
+		keys = c.controller.Keymap()
+	}
+	c.keys = keys.NewState(keymap.Client)
+
 	// Allow nil for tests.
 	if c.controller != nil {
 		c.controller.SetWidget(c)
+		c.controller.SetTitle(Title(0))
 	}
 	// Allow nil for tests.
 	if provider != nil {
@@ -42,7 +57,14 @@ type Client struct {
 
 	networksBox *tui.Box
 	controller  UIController
+	manager     backend.Manager
+	saver       backend.Saver
+	keys        *keymap.State
 	focused     tui.Widget
+	// form is the open network form, if any.
+	form *NetworkForm
+	// errorWidget shows the most recent error, once there has been one.
+	errorWidget *tui.Label
 
 	// RW of networks already only be run from the UI thread- but this allows
 	// test operations to be safely run from another thread.
@@ -52,25 +74,144 @@ type Client struct {
 
 // OnKeyEvent handles keypress events for the Client's root view.
 func (c *Client) OnKeyEvent(ev tui.KeyEvent) {
-	switch ev.Key {
-	case tui.KeyCtrlC:
+	if c.form != nil {
+		// Keys are the form's input, e.g. "q" in a nick, but Ctrl+C still
+		// quits.
+		if ev.Key == tui.KeyCtrlC {
+			c.controller.Quit()
+			return
+		}
+		c.form.OnKeyEvent(ev)
+		return
+	}
+	a, handled := c.keys.Handle(ev)
+	if a == keymap.Quit {
 		c.controller.Quit()
-	case tui.KeyDown:
+		return
+	}
+	if !handled {
+		c.Widget.OnKeyEvent(ev)
+		return
+	}
+	switch a {
+	case "":
+		// A sequence of keys is in progress.
+	case keymap.Down:
 		c.moveFocus(true)
-	case tui.KeyUp:
+	case keymap.Up:
 		c.moveFocus(false)
-	case tui.KeyRune:
-		switch ev.Rune {
-		case 'j':
-			c.moveFocus(true)
-		case 'k':
-			c.moveFocus(false)
-		default:
+	case keymap.NewNetwork:
+		c.openForm(nil)
+	case keymap.ShowFilter:
+		c.controller.ActivateFilter("")
+	default:
+		if !c.manage(a) {
 			c.Widget.OnKeyEvent(ev)
 		}
+	}
+}
+
+// manage applies an action to the focused Network or Channel:
+//
+//	connect: connect / join
+//	disconnect: disconnect / part
+//	edit: edit (networks only)
+//	close: close, i.e. remove the network or channel, and remove it from the
+//	   view.
+//
+// It reports whether the action was handled.
+func (c *Client) manage(a keymap.Action) bool {
+	if c.manager == nil {
+		return false
+	}
+	switch w := c.focused.(type) {
+	case *Network:
+		switch a {
+		case keymap.Connect:
+			c.manager.Connect(w.name)
+		case keymap.Disconnect:
+			c.manager.Disconnect(w.name)
+		case keymap.Edit:
+			cfg := c.manager.Configuration(w.name)
+			if cfg == nil {
+				c.SetError(fmt.Sprintf("network %q isn't configured", w.name))
+				break
+			}
+			c.openForm(cfg)
+		case keymap.Close:
+			c.manager.Remove(w.name)
+			c.resetFocus()
+			c.RemoveNetwork(w.name)
+		default:
+			return false
+		}
+	case *Channel:
+		s := data.Scope{Net: w.network.name, Name: w.name}
+		switch a {
+		case keymap.Connect:
+			key := ""
+			if cfg := c.manager.Configuration(s.Net); cfg != nil {
+				if ch := cfg.Channel(s.Name); ch != nil {
+					key = ch.Key
+				}
+			}
+			c.manager.Join(s, key)
+		case keymap.Disconnect:
+			c.manager.Part(s)
+		case keymap.Close:
+			c.manager.RemoveChannel(s)
+			c.resetFocus()
+			w.network.RemoveChannel(w.name)
+		default:
+			return false
+		}
 	default:
-		c.Widget.OnKeyEvent(ev)
+		return false
+	}
+	return true
+}
+
+// openForm shows a form to edit the network configuration, or to add a new
+// network if existing is nil.
+func (c *Client) openForm(existing *config.Network) {
+	if c.manager == nil {
+		return
+	}
+	c.form = NewNetworkForm(existing, func(n *config.Network, save bool) error {
+		return c.applyForm(existing, n, save)
+	}, c.closeForm)
+	c.Widget = c.form
+}
+
+func (c *Client) closeForm() {
+	c.form = nil
+	c.Widget = c.networksBox
+}
+
+// applyForm applies the changes from the existing network configuration (or
+// nil) to the new one, and saves them if requested.
+func (c *Client) applyForm(existing, n *config.Network, save bool) error {
+	old := &config.Config{}
+	if existing != nil {
+		old.Networks = []*config.Network{existing}
+	} else if c.manager.Configuration(n.Name) != nil {
+		return fmt.Errorf("network %q already exists", n.Name)
 	}
+	backend.Apply(c.manager, config.Diff(old, &config.Config{Networks: []*config.Network{n}}))
+	c.closeForm()
+
+	if save && c.saver != nil {
+		if err := c.saver.Save(); err != nil {
+			c.SetError(fmt.Sprintf("configuration not saved: %v", err))
+		}
+	}
+	return nil
+}
+
+// resetFocus returns the focus to the Client itself.
+func (c *Client) resetFocus() {
+	c.focused.SetFocused(false)
+	c.focused = c
 }
 
 // Filter returns a data.Filter that matches all Scopes.
@@ -88,10 +229,24 @@ func (c *Client) Receive(e data.Event) {
 		c.updateNetwork(e)
 	case *data.ChannelStateEvent:
 		c.updateChannel(e)
+	case *data.ErrorEvent:
+		c.controller.Update(func() {
+			c.SetError(e.Line)
+		})
 	}
 
 }
 
+// SetError shows the error message at the bottom of the view.
+func (c *Client) SetError(msg string) {
+	if c.errorWidget == nil {
+		c.errorWidget = tui.NewLabel("")
+		// Below the spacer, i.e. at the bottom of the view.
+		c.networksBox.Append(c.errorWidget)
+	}
+	c.errorWidget.SetText(msg)
+}
+
 func (c *Client) updateNetwork(n *data.NetworkStateEvent) {
 	c.controller.Update(func() {
 		c.GetNetwork(n.ID().Net).UpdateNetwork(n.NetworkState)
@@ -102,9 +257,35 @@ func (c *Client) updateChannel(ch *data.ChannelStateEvent) {
 	id := ch.ID()
 	c.controller.Update(func() {
 		c.GetNetwork(id.Net).GetChannel(id.Name).UpdateChannel(ch.ChannelState)
+		c.controller.SetTitle(Title(c.unread()))
 	})
 }
 
+// unread returns the total number of unread messages in all channels.
+func (c *Client) unread() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	total := 0
+	for _, n := range c.networks {
+		n.mu.Lock()
+		for _, ch := range n.channels {
+			total += ch.unread
+		}
+		n.mu.Unlock()
+	}
+	return total
+}
+
+// Title returns the window title for the client view, e.g. "discoirc (12)"
+// when there are 12 unread messages.
+func Title(unread int) string {
+	if unread > 0 {
+		return fmt.Sprintf("discoirc (%d)", unread)
+	}
+	return "discoirc"
+}
+
 func (c *Client) moveFocus(fwd bool) {
 	c.focused.SetFocused(false)
 	var next tui.Widget
@@ -8,8 +8,10 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/marcusolsson/tui-go"
 
+	"github.com/cceckman/discoirc/config"
 	"github.com/cceckman/discoirc/data"
 	"github.com/cceckman/discoirc/ui/client"
+	"github.com/cceckman/discoirc/ui/keymap"
 	discomocks "github.com/cceckman/discoirc/ui/testhelper"
 )
 
@@ -105,6 +107,29 @@ var clientTests = []struct {
                          
                          
                          
+`,
+	},
+	{
+		test: "error message",
+		setup: func(w *client.Client) {
+			w.Receive(&data.NetworkStateEvent{
+				EventID:      data.EventID{Scope: data.Scope{Net: "AlphaNet"}},
+				NetworkState: data.NetworkState{Nick: "edward"},
+			})
+			w.Receive(&data.ErrorEvent{Line: "old error"})
+			w.Receive(&data.ErrorEvent{Line: "config:3: unknown key"})
+		},
+		want: `
+ AlphaNet: ∅       edward
+                         
+                         
+                         
+                         
+                         
+                         
+                         
+                         
+config:3: unknown key    
 `,
 	},
 	{
@@ -614,7 +639,9 @@ func TestNetwork_Focus(t *testing.T) {
 
 // ActivationTests test response to keypress events.
 var ActivationTests = []struct {
-	Test              string
+	Test string
+	// Preset is the keymap to use, if not the default.
+	Preset            string
 	Input             []tui.KeyEvent
 	WantView          discomocks.ActiveView
 	WantNet, WantChan string
@@ -657,6 +684,30 @@ var ActivationTests = []struct {
 		WantView: discomocks.ChannelView,
 		WantNet:  "zetanet", WantChan: "#bar",
 	},
+	{
+		Test:   "emacs Ctrl+N, activate",
+		Preset: keymap.Emacs,
+		Input: []tui.KeyEvent{
+			{Key: tui.KeyCtrlN},
+			{Key: tui.KeyCtrlN},
+			{Key: tui.KeyEnter},
+		},
+		WantView: discomocks.ChannelView,
+		WantNet:  "gonet", WantChan: "#discoirc",
+	},
+	{
+		Test:   "emacs broken sequence",
+		Preset: keymap.Emacs,
+		Input: []tui.KeyEvent{
+			{Key: tui.KeyCtrlX},
+			{Key: tui.KeyRune, Rune: 'j'},
+			{Key: tui.KeyRune, Rune: 'j'},
+			{Key: tui.KeyCtrlN},
+			{Key: tui.KeyEnter},
+		},
+		WantView: discomocks.ChannelView,
+		WantNet:  "gonet", WantChan: "#discoirc",
+	},
 	{
 		Test: "no activation on root",
 		Input: []tui.KeyEvent{
@@ -682,6 +733,9 @@ func TestNetwork_ActivateChannel(t *testing.T) {
 			t.Parallel()
 			ui := discomocks.NewController()
 			ui.V = discomocks.ClientView
+			if tt.Preset != "" {
+				ui.Keys, _ = keymap.Preset(tt.Preset)
+			}
 
 			root := client.New(ui, nil)
 
@@ -725,6 +779,22 @@ func TestNetwork_Quit(t *testing.T) {
 	}
 }
 
+func TestNetwork_QuitSequence(t *testing.T) {
+	t.Parallel()
+	ui := discomocks.NewController()
+	ui.Keys, _ = keymap.Preset(keymap.Emacs)
+	root := client.New(ui, nil)
+
+	root.OnKeyEvent(tui.KeyEvent{Key: tui.KeyCtrlX})
+	if ui.HasQuit {
+		t.Fatalf("client quit before the sequence was complete")
+	}
+	root.OnKeyEvent(tui.KeyEvent{Key: tui.KeyCtrlC})
+	if !ui.HasQuit {
+		t.Errorf("client hasn't quit")
+	}
+}
+
 func Test_Issue18(t *testing.T) {
 	t.Parallel()
 	// https://github.com/cceckman/discoirc/issues/18
@@ -786,3 +856,195 @@ func Test_Issue18(t *testing.T) {
 	}
 
 }
+
+// keys returns the key events for typing s. '\t' is Tab, and '\n' is Enter.
+func keys(s string) []tui.KeyEvent {
+	var r []tui.KeyEvent
+	for _, rn := range s {
+		switch rn {
+		case '\t':
+			r = append(r, tui.KeyEvent{Key: tui.KeyTab})
+		case '\n':
+			r = append(r, tui.KeyEvent{Key: tui.KeyEnter})
+		default:
+			r = append(r, tui.KeyEvent{Key: tui.KeyRune, Rune: rn})
+		}
+	}
+	return r
+}
+
+// ManagementTests test network and channel management from the client view.
+var ManagementTests = []struct {
+	Test string
+	// Preset is the keymap to use, if not the default.
+	Preset      string
+	Input       []tui.KeyEvent
+	WantManaged []string
+	WantSaved   int
+	WantQuit    bool
+	// WantRows are the names of the networks and channels shown after the
+	// backend next updates the view, if not all of them.
+	WantRows []string
+}{
+	{
+		Test:        "connect and disconnect network",
+		Input:       append([]tui.KeyEvent{{Key: tui.KeyDown}}, keys("cd")...),
+		WantManaged: []string{"connect gonet", "disconnect gonet"},
+	},
+	{
+		Test:        "join and part channel with key",
+		Input:       append([]tui.KeyEvent{{Key: tui.KeyDown}, {Key: tui.KeyDown}}, keys("cd")...),
+		WantManaged: []string{"join gonet #discoirc sesame", "part gonet #discoirc"},
+	},
+	{
+		Test:        "close channel",
+		Input:       keys("jjx"),
+		WantManaged: []string{"remove gonet #discoirc"},
+		WantRows:    []string{"gonet", "zetanet"},
+	},
+	{
+		Test:        "close channel and network",
+		Input:       keys("jjxjx"),
+		WantManaged: []string{"remove gonet #discoirc", "remove gonet"},
+		WantRows:    []string{"zetanet"},
+	},
+	{
+		Test:        "no actions on root",
+		Input:       keys("cdx"),
+		WantManaged: nil,
+	},
+	{
+		Test:        "add network",
+		Input:       keys("nElsinore\tirc.elsinore.dk\t\tyorick\t \t \n"),
+		WantManaged: []string{"configure Elsinore", "connect Elsinore"},
+		WantSaved:   1,
+	},
+	{
+		Test:        "add network with vim keys",
+		Preset:      keymap.Vim,
+		Input:       keys("nElsinore\tirc.elsinore.dk\t\tqueen\t \t \n"),
+		WantManaged: []string{"configure Elsinore", "connect Elsinore"},
+		WantSaved:   1,
+	},
+	{
+		Test:        "quit from form",
+		Preset:      keymap.Vim,
+		Input:       append(keys("nq"), tui.KeyEvent{Key: tui.KeyCtrlC}),
+		WantManaged: nil,
+		WantQuit:    true,
+	},
+	{
+		Test:        "add network with errors",
+		Input:       keys("n\tirc.elsinore.dk\t\tyorick\n"),
+		WantManaged: nil,
+	},
+	{
+		Test:        "add existing network",
+		Input:       keys("ngonet\tirc.gonet.example\t\tgopher\n"),
+		WantManaged: nil,
+	},
+	{
+		Test:        "cancel form",
+		Input:       append(keys("nElsinore"), tui.KeyEvent{Key: tui.KeyEsc}),
+		WantManaged: nil,
+	},
+	{
+		Test:        "edit network",
+		Input:       keys("je\t\t\t \n"),
+		WantManaged: []string{"configure gonet"},
+	},
+	{
+		Test:        "edit unconfigured network",
+		Input:       keys("jjje\n"),
+		WantManaged: nil,
+	},
+}
+
+func TestClient_Manage(t *testing.T) {
+	for _, tt := range ManagementTests {
+		tt := tt
+		t.Run(tt.Test, func(t *testing.T) {
+			t.Parallel()
+			ui := discomocks.NewController()
+			ui.V = discomocks.ClientView
+			if tt.Preset != "" {
+				ui.Keys, _ = keymap.Preset(tt.Preset)
+			}
+			d := discomocks.NewBackend()
+			d.Configs = map[string]*config.Network{
+				"gonet": {
+					Name:     "gonet",
+					Servers:  []config.Server{{Host: "irc.gonet.example", Port: config.DefaultPort}},
+					Nick:     "gopher",
+					Channels: []*config.Channel{{Name: "#discoirc", Key: "sesame", AutoJoin: true}},
+				},
+			}
+
+			d.Known = map[string][]string{
+				"gonet":   {"#discoirc"},
+				"zetanet": nil,
+			}
+
+			root := client.New(ui, d)
+			d.Update()
+
+			for _, ev := range tt.Input {
+				root.OnKeyEvent(ev)
+			}
+			d.Update()
+
+			if tt.WantRows != nil {
+				// The focus wraps around from the last row to the first.
+				var got []string
+				first := root.FocusNext(root)
+				for w := first; w != root; {
+					got = append(got, w.(namedWidget).Name())
+					if w = root.FocusNext(w); w == first {
+						break
+					}
+				}
+				if diff := cmp.Diff(got, tt.WantRows); diff != "" {
+					t.Errorf("unexpected rows: (-got +want)\n%s", diff)
+				}
+			}
+
+			if diff := cmp.Diff(d.Managed, tt.WantManaged); diff != "" {
+				t.Errorf("unexpected backend calls: (-got +want)\n%s", diff)
+			}
+			if d.Saved != tt.WantSaved {
+				t.Errorf("unexpected saves: got: %d want: %d", d.Saved, tt.WantSaved)
+			}
+			if ui.HasQuit != tt.WantQuit {
+				t.Errorf("unexpected quit: got: %v want: %v", ui.HasQuit, tt.WantQuit)
+			}
+			if ui.V != discomocks.ClientView {
+				t.Errorf("unexpected active view: got: %v want: %v", ui.V, discomocks.ClientView)
+			}
+		})
+	}
+}
+
+func TestClient_Title(t *testing.T) {
+	t.Parallel()
+	ui := discomocks.NewController()
+	c := client.New(ui, nil)
+	if ui.Title != "discoirc" {
+		t.Errorf("unexpected title: got: %q want: %q", ui.Title, "discoirc")
+	}
+
+	for _, ev := range []*data.ChannelStateEvent{
+		{
+			EventID:      data.EventID{Scope: data.Scope{Net: "AlphaNet", Name: "#discoirc"}},
+			ChannelState: data.ChannelState{Unread: 99},
+		},
+		{
+			EventID:      data.EventID{Scope: data.Scope{Net: "Charlienet", Name: "#badpuns"}},
+			ChannelState: data.ChannelState{Unread: 3},
+		},
+	} {
+		c.Receive(ev)
+	}
+	if want := "discoirc (102)"; ui.Title != want {
+		t.Errorf("unexpected title: got: %q want: %q", ui.Title, want)
+	}
+}
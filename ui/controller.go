@@ -1,12 +1,21 @@
 package ui
 
 import (
+	"fmt"
+
+	"github.com/golang/glog"
 	"github.com/marcusolsson/tui-go"
 
 	"github.com/cceckman/discoirc/backend"
+	"github.com/cceckman/discoirc/config"
 	"github.com/cceckman/discoirc/data"
 	"github.com/cceckman/discoirc/ui/channel"
 	"github.com/cceckman/discoirc/ui/client"
+	"github.com/cceckman/discoirc/ui/filter"
+	"github.com/cceckman/discoirc/ui/history"
+	"github.com/cceckman/discoirc/ui/keymap"
+	"github.com/cceckman/discoirc/ui/widgets"
+	"github.com/cceckman/discoirc/ui/wm"
 )
 
 // UI is the subset of the tui.UI interface that the Controller uses directly or passes through
@@ -31,16 +40,129 @@ func New(ui UI, be backend.Backend) *Controller {
 type Controller struct {
 	UI
 
-	backend backend.Backend
+	backend  backend.Backend
+	launcher wm.Launcher
+	titler   Titler
+	keys     *keymap.Keymap
+	history  *history.Store
+	// membership configures how channel views show joins, parts, and quits.
+	membership config.Membership
+	// renderer draws the Events in channel views.
+	renderer channel.EventRenderer
 }
 
-// ActivateChannel closes the current view, and replaces it with a view of the
-// given channel in the given network.
+// SetHistory sets the store of input history used by channel views.
+func (c *Controller) SetHistory(h *history.Store) {
+	c.history = h
+}
+
+// History returns the store of input history for channel views to use: the
+// one given to SetHistory, or one that isn't saved.
+func (c *Controller) History() *history.Store {
+	if c.history == nil {
+		c.history = history.New(history.DefaultSize)
+	}
+	return c.history
+}
+
+// SetKeymap sets the key bindings used by views opened after it's called.
+func (c *Controller) SetKeymap(k *keymap.Keymap) {
+	c.keys = k
+}
+
+// Keymap returns the key bindings for views to use: those given to SetKeymap,
+// or the default keymap.
+func (c *Controller) Keymap() *keymap.Keymap {
+	if c.keys == nil {
+		c.keys = keymap.Default()
+	}
+	return c.keys
+}
+
+// SetMembership sets how channel views opened after it's called show joins,
+// parts, and quits.
+func (c *Controller) SetMembership(m config.Membership) {
+	c.membership = m
+}
+
+// SetDisplay sets how channel views opened after it's called show events.
+func (c *Controller) SetDisplay(d config.Display) {
+	c.renderer = channel.NewRenderer(d)
+}
+
+// Titler sets the terminal window's title.
+type Titler interface {
+	SetTitle(title string)
+	// Restore restores the title from before the first SetTitle.
+	Restore()
+}
+
+// SetTitler sets the Titler used to show the current view in the window title.
+func (c *Controller) SetTitler(t Titler) {
+	c.titler = t
+}
+
+// SetTitle sets the window title, if there is a Titler.
+func (c *Controller) SetTitle(title string) {
+	if c.titler != nil {
+		c.titler.SetTitle(title)
+	}
+}
+
+// Quit restores the window title, and quits the UI.
+func (c *Controller) Quit() {
+	if c.titler != nil {
+		c.titler.Restore()
+	}
+	c.UI.Quit()
+}
+
+// SetLauncher sets the Launcher used to open channels in new windows.
+// If it's nil (the default), channels replace the current view.
+func (c *Controller) SetLauncher(l wm.Launcher) {
+	c.launcher = l
+}
+
+// ActivateChannel shows a view of the given channel in the given network.
+// If there's a Launcher, the view is opened in its own window; otherwise, or
+// if launching fails, it replaces the current view.
 func (c *Controller) ActivateChannel(network, target string) {
-	channel.New(
+	if c.launcher != nil {
+		err := c.launcher.Open(network, target)
+		if err == nil {
+			return
+		}
+		glog.Warningf("can't open %s/%s in a new window: %v", network, target, err)
+	}
+	c.showChannel(network, target)
+}
+
+// showChannel closes the current view, and replaces it with a view of the
+// given channel in the given network.
+func (c *Controller) showChannel(network, target string) *channel.View {
+	v := channel.New(
 		data.Scope{Net: network, Name: target},
 		c, c.backend,
 	)
+	v.SetMembership(c.membership)
+	if c.renderer != nil {
+		v.SetRenderer(c.renderer)
+	}
+	return v
+}
+
+// ActivateEvent closes the current view, and replaces it with a view of the
+// Event's channel, scrolled back to the Event.
+// Must be run from the UI thread.
+func (c *Controller) ActivateEvent(id data.EventID) {
+	c.showChannel(id.Net, id.Name).ShowEvent(id.Seq)
+}
+
+// ActivateFilter closes the current view, and replaces it with a view of the
+// messages in every channel that match the query.
+// Must be run from the UI thread.
+func (c *Controller) ActivateFilter(query string) {
+	filter.New(query, c, c.backend)
 }
 
 // ActivateClient closes the current view, and replaces it with a view of all
@@ -49,3 +171,20 @@ func (c *Controller) ActivateChannel(network, target string) {
 func (c *Controller) ActivateClient() {
 	client.New(c, c.backend)
 }
+
+// Open activates the view of the Target: its channel, or the client view if it
+// has no channel. If the Target doesn't exist in the backend, it shows an error
+// screen, from which the user can continue to the client view.
+// Must be run from the UI thread.
+func (c *Controller) Open(t Target) {
+	if err := t.Check(c.backend); err != nil {
+		c.SetWidget(widgets.NewErrorScreen(c, fmt.Sprintf("can't open %s: %v", t, err), c.ActivateClient))
+		return
+	}
+	if t.Channel != "" {
+		// This is the window for the target; don't launch another.
+		c.showChannel(t.Network, t.Channel)
+		return
+	}
+	c.ActivateClient()
+}
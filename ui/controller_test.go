@@ -1,6 +1,7 @@
 package ui_test
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/marcusolsson/tui-go"
@@ -135,3 +136,115 @@ HamNet: ? #hamlet:
 	}
 
 }
+
+func TestOpen(t *testing.T) {
+	for _, tt := range []struct {
+		test   string
+		target ui.Target
+		want   string
+	}{
+		{test: "default", target: ui.Target{}, want: "client"},
+		{test: "network", target: ui.Target{Network: "HamNet"}, want: "client"},
+		{test: "channel", target: ui.Target{Network: "HamNet", Channel: "#hamlet"}, want: "channel"},
+		{test: "unknown network", target: ui.Target{Network: "Globe"}, want: "error"},
+		{test: "unknown channel", target: ui.Target{Network: "HamNet", Channel: "#globe"}, want: "error"},
+	} {
+		tt := tt
+		t.Run(tt.test, func(t *testing.T) {
+			t.Parallel()
+			u := testhelper.NewUI()
+			be := testhelper.NewBackend()
+			be.Known = map[string][]string{"HamNet": {"#hamlet"}}
+
+			ctl := ui.New(u, be)
+			ctl.Open(tt.target)
+
+			got := "error"
+			switch u.Root.(type) {
+			case *channel.View:
+				got = "channel"
+			case client.View:
+				got = "client"
+			}
+			if got != tt.want {
+				t.Errorf("unexpected view at UI root: got: %s (%T) want: %s", got, u.Root, tt.want)
+			}
+
+			if tt.want == "error" {
+				// Continue to the client view.
+				u.Type("\n")
+				if _, ok := u.Root.(client.View); !ok {
+					t.Errorf("unexpected view after error: got: %T want: client.View", u.Root)
+				}
+			}
+		})
+	}
+}
+
+// fakeLauncher records the channels it opens.
+type fakeLauncher struct {
+	err    error
+	opened []string
+}
+
+func (l *fakeLauncher) Open(network, channel string) error {
+	l.opened = append(l.opened, network+"/"+channel)
+	return l.err
+}
+
+func TestActivateChannel_Launcher(t *testing.T) {
+	t.Parallel()
+	u := testhelper.NewUI()
+	ctl := ui.New(u, testhelper.NewBackend())
+	ctl.ActivateClient()
+	l := &fakeLauncher{}
+	ctl.SetLauncher(l)
+
+	ctl.ActivateChannel("foonet", "#barchan")
+	if _, ok := u.Root.(client.View); !ok {
+		t.Errorf("unexpected view at UI root: got: %+v want: client.View", u.Root)
+	}
+
+	// Falls back to replacing the view.
+	l.err = errors.New("no server running")
+	ctl.ActivateChannel("foonet", "#bazchan")
+	if _, ok := u.Root.(*channel.View); !ok {
+		t.Errorf("unexpected view at UI root: got: %+v want: *channel.View", u.Root)
+	}
+
+	want := []string{"foonet/#barchan", "foonet/#bazchan"}
+	if len(l.opened) != 2 || l.opened[0] != want[0] || l.opened[1] != want[1] {
+		t.Errorf("unexpected launches: got: %q want: %q", l.opened, want)
+	}
+}
+
+// fakeTitler records the window title.
+type fakeTitler struct {
+	title    string
+	restored bool
+}
+
+func (t *fakeTitler) SetTitle(title string) { t.title = title }
+func (t *fakeTitler) Restore()              { t.restored = true }
+
+func TestTitle(t *testing.T) {
+	t.Parallel()
+	u := testhelper.NewUI()
+	ctl := ui.New(u, testhelper.NewBackend())
+	title := &fakeTitler{}
+	ctl.SetTitler(title)
+
+	ctl.ActivateChannel("foonet", "#barchan")
+	if want := "foonet #barchan"; title.title != want {
+		t.Errorf("unexpected title: got: %q want: %q", title.title, want)
+	}
+	ctl.ActivateClient()
+	if want := "discoirc"; title.title != want {
+		t.Errorf("unexpected title: got: %q want: %q", title.title, want)
+	}
+
+	ctl.Quit()
+	if !title.restored || !u.HasQuit {
+		t.Errorf("unexpected state after quit: restored: %v quit: %v", title.restored, u.HasQuit)
+	}
+}
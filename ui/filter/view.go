@@ -0,0 +1,346 @@
+// Package filter implements the filter view: the messages matching a query,
+// from every network and channel, in one stream.
+package filter
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/marcusolsson/tui-go"
+
+	"github.com/cceckman/discoirc/backend"
+	"github.com/cceckman/discoirc/data"
+	"github.com/cceckman/discoirc/ui/keymap"
+	"github.com/cceckman/discoirc/ui/widgets"
+)
+
+// Limit is the most matching messages the view shows.
+const Limit = 500
+
+// UIController provides an interface to a global control layer.
+type UIController interface {
+	Update(func())
+	SetWidget(tui.Widget)
+	SetTitle(title string)
+	Quit()
+
+	ActivateClient()
+	// ActivateEvent shows the Event in a view of its channel.
+	ActivateEvent(id data.EventID)
+	// Keymap returns the key bindings to use.
+	Keymap() *keymap.Keymap
+}
+
+// Backend is the part of a backend.Backend the filter view uses.
+type Backend interface {
+	backend.DataPublisher
+	backend.Searcher
+}
+
+// View shows the messages matching a query, oldest first, with one of them
+// selected.
+type View struct {
+	ui      UIController
+	backend Backend
+	keys    *keymap.State
+
+	// query is the query whose results are shown.
+	query string
+	// filter is the query, parsed. The backend may read it from any
+	// goroutine.
+	mu     sync.Mutex
+	filter data.Filter
+	// last is the newest Event in each scope that's been searched, or
+	// added since.
+	last map[data.Scope]data.Seq
+
+	// results are the matching messages, oldest first.
+	results data.EventList
+	// selected is the index of the selected message in results; it's the
+	// last shown.
+	selected int
+
+	// root element
+	*tui.Box
+
+	events *widgets.TailBox
+	// status bar
+	count  *tui.Label
+	notice *tui.Label
+	// input bar
+	input *tui.Entry
+}
+
+// New returns a new View of the messages matching the query, and makes it the
+// UI's root. It must be run from the main (UI) thread.
+func New(query string, ui UIController, be Backend) *View {
+	v := &View{
+		ui:      ui,
+		backend: be,
+		last:    make(map[data.Scope]data.Seq),
+
+		events: widgets.NewTailBox(),
+		count:  tui.NewLabel(""),
+		notice: tui.NewLabel(""),
+		input:  tui.NewEntry(),
+	}
+	v.events.SetSizePolicy(tui.Expanding, tui.Expanding)
+	v.input.SetSizePolicy(tui.Expanding, tui.Minimum)
+	v.input.SetFocused(true)
+	v.input.SetText(query)
+
+	keys := keymap.Default()
+	if ui != nil {
+		keys = ui.Keymap()
+	}
+	v.keys = keys.NewState(keymap.Filter)
+
+	v.Box = tui.NewVBox(
+		v.events,
+		&reversedBox{
+			Box: tui.NewHBox(
+				tui.NewLabel("filter: "),
+				v.count,
+				tui.NewSpacer(),
+				v.notice,
+			),
+		},
+		tui.NewHBox(tui.NewLabel("/ "), v.input),
+	)
+	v.setQuery(query)
+
+	if ui != nil {
+		ui.SetWidget(v)
+	}
+	if be != nil {
+		go be.Subscribe(v)
+	}
+	return v
+}
+
+// Title returns the window title for a filter view of the query.
+func Title(query string) string {
+	if query == "" {
+		return "filter"
+	}
+	return "filter: " + query
+}
+
+// setQuery checks the query, and shows its results.
+func (v *View) setQuery(query string) {
+	f, err := data.ParseFilter(query)
+	if err != nil {
+		v.notice.SetText(err.Error())
+		return
+	}
+	v.notice.SetText("")
+	v.query = query
+	v.mu.Lock()
+	v.filter = f
+	v.mu.Unlock()
+	if v.ui != nil {
+		v.ui.SetTitle(Title(query))
+	}
+	v.search()
+	v.selected = len(v.results) - 1
+	v.refresh()
+}
+
+// search finds the messages matching the query, other than those from ignored
+// users, keeping the selected message selected if it still matches.
+func (v *View) search() {
+	var selected *data.EventID
+	if v.selected >= 0 && v.selected < len(v.results) {
+		selected = v.results[v.selected].ID()
+	}
+	v.results = nil
+	v.last = make(map[data.Scope]data.Seq)
+	if v.backend == nil {
+		return
+	}
+	v.selected = -1
+	// Newest first, and shown oldest first.
+	events := v.backend.Search(v.Filter(), Limit)
+	for i := len(events) - 1; i >= 0; i-- {
+		e := events[i]
+		v.saw(e)
+		if data.Ignored(e) {
+			continue
+		}
+		if selected != nil && *selected == *e.ID() {
+			v.selected = len(v.results)
+		}
+		v.results = append(v.results, e)
+	}
+}
+
+// add adds messages that have arrived since the search, oldest first, other
+// than those from ignored users. Only the newest Limit results are kept.
+func (v *View) add(events data.EventList) {
+	for _, e := range events {
+		v.saw(e)
+		if data.Ignored(e) {
+			continue
+		}
+		// The search left out messages older than those shown.
+		if len(v.results) >= Limit && data.Time(e).Before(data.Time(v.results[0])) {
+			continue
+		}
+		v.results = append(v.results, e)
+	}
+	if n := len(v.results) - Limit; n > 0 {
+		v.results = v.results[n:]
+		v.selected -= n
+		if v.selected < 0 {
+			v.selected = 0
+		}
+	}
+}
+
+// saw records that the Event has been shown, or left out, by the view.
+func (v *View) saw(e data.Event) {
+	if id := e.ID(); id.Seq > v.last[id.Scope] {
+		v.last[id.Scope] = id.Seq
+	}
+}
+
+// refresh redraws the results, ending with the selected one.
+func (v *View) refresh() {
+	if v.selected < 0 || v.selected >= len(v.results) {
+		v.selected = len(v.results) - 1
+	}
+	w := make([]tui.Widget, 0, v.selected+1)
+	for i, e := range v.results[:v.selected+1] {
+		w = append(w, render(e, i == v.selected))
+	}
+	v.events.SetContents(w...)
+	if len(v.results) == 0 {
+		v.count.SetText("no matches")
+	} else {
+		v.count.SetText(fmt.Sprintf("%d of %d", v.selected+1, len(v.results)))
+	}
+}
+
+// render renders a message, prefixed with its network and channel.
+func render(e data.Event, selected bool) tui.Widget {
+	id := e.ID()
+	l := tui.NewLabel(fmt.Sprintf("%s/%s %s", id.Net, id.Name, e.String()))
+	l.SetWordWrap(true)
+	l.SetSizePolicy(tui.Expanding, tui.Minimum)
+	if selected {
+		return &reversedLabel{Label: l}
+	}
+	return l
+}
+
+// move moves the selection n messages newer, or older if n is negative.
+func (v *View) move(n int) {
+	sel := v.selected + n
+	if sel >= len(v.results) {
+		sel = len(v.results) - 1
+	}
+	if sel < 0 {
+		sel = 0
+	}
+	if sel != v.selected {
+		v.selected = sel
+		v.refresh()
+	}
+}
+
+// OnKeyEvent handles key presses. Keys that aren't bound edit the query.
+func (v *View) OnKeyEvent(ev tui.KeyEvent) {
+	a, handled := v.keys.Handle(ev)
+	if !handled {
+		v.Box.OnKeyEvent(ev)
+		return
+	}
+	switch a {
+	case keymap.Quit:
+		if v.ui != nil {
+			v.ui.Quit()
+		}
+	case keymap.ShowClient:
+		if v.ui != nil {
+			v.ui.ActivateClient()
+		}
+	case keymap.Up:
+		v.move(-1)
+	case keymap.Down:
+		v.move(1)
+	case keymap.PageUp:
+		v.move(-v.events.Size().Y)
+	case keymap.PageDown:
+		v.move(v.events.Size().Y)
+	case keymap.Bottom:
+		v.move(len(v.results))
+	case keymap.Open:
+		v.open()
+	}
+}
+
+// open applies the query being typed, if it's changed, or shows the selected
+// message in its channel.
+func (v *View) open() {
+	if query := v.input.Text(); query != v.query {
+		v.setQuery(query)
+		return
+	}
+	if v.selected >= 0 && v.selected < len(v.results) && v.ui != nil {
+		v.ui.ActivateEvent(*v.results[v.selected].ID())
+	}
+}
+
+// Receive handles an update from the backend: if there are new Events in a
+// channel, those matching the query are added to the results.
+func (v *View) Receive(e data.Event) {
+	ev, ok := e.(*data.ChannelStateEvent)
+	if !ok {
+		return
+	}
+	v.ui.Update(func() {
+		s := ev.Scope
+		if ev.LastMessage <= v.last[s] || v.backend == nil {
+			return
+		}
+		// Keep following the newest message, if it's selected.
+		newest := v.selected == len(v.results)-1
+		v.add(v.backend.MessagesAfter(s, v.last[s], v.Filter()))
+		if ev.LastMessage > v.last[s] {
+			v.last[s] = ev.LastMessage
+		}
+		if newest {
+			v.selected = len(v.results) - 1
+		}
+		v.refresh()
+	})
+}
+
+// Filter returns the match rule for this view.
+func (v *View) Filter() data.Filter {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.filter
+}
+
+// reversedBox is a Box that applies the "reversed" style to its contents.
+type reversedBox struct {
+	*tui.Box
+}
+
+func (rb *reversedBox) Draw(p *tui.Painter) {
+	p.WithStyle("reversed", func(p *tui.Painter) {
+		rb.Box.Draw(p)
+	})
+}
+
+// reversedLabel is a Label that applies the "reversed" style to its text.
+type reversedLabel struct {
+	*tui.Label
+}
+
+func (rl *reversedLabel) Draw(p *tui.Painter) {
+	p.WithStyle("reversed", func(p *tui.Painter) {
+		rl.Label.Draw(p)
+	})
+}
@@ -0,0 +1,117 @@
+package filter_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/marcusolsson/tui-go"
+
+	"github.com/cceckman/discoirc/data"
+	"github.com/cceckman/discoirc/ui/filter"
+	"github.com/cceckman/discoirc/ui/testhelper"
+)
+
+var hamlet = data.Scope{Net: "Shaxnet", Name: "#hamlet"}
+
+// newView returns a filter view of "you", which matches two of the
+// testhelper's Events.
+func newView() (*testhelper.Controller, *testhelper.Backend, *filter.View) {
+	ui := testhelper.NewController()
+	d := testhelper.NewBackend()
+	d.Found = []data.EventID{{Scope: hamlet, Seq: 8}, {Scope: hamlet, Seq: 5}}
+	v := filter.New("you", ui, d)
+	return ui, d, v
+}
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+	_, _, v := newView()
+	surface := tui.NewTestSurface(40, 6)
+	p := tui.NewPainter(surface, tui.NewTheme())
+	p.Repaint(v)
+
+	want := `
+Shaxnet/#hamlet <francisco> Nay answer  
+me: Stand & vnfold your selfe           
+Shaxnet/#hamlet <gertrude> Good         
+gentlemen, he hath much talk'd of you;  
+filter: 2 of 2                          
+/ you                                   
+`
+	if got := surface.String(); got != want {
+		t.Errorf("unexpected contents:\ngot = \n%s\n--\nwant = \n%s\n--", got, want)
+	}
+}
+
+func TestInput_Open(t *testing.T) {
+	t.Parallel()
+	ui, _, v := newView()
+	surface := tui.NewTestSurface(40, 6)
+	p := tui.NewPainter(surface, tui.NewTheme())
+
+	v.OnKeyEvent(tui.KeyEvent{Key: tui.KeyUp})
+	p.Repaint(v)
+	lines := strings.Split(surface.String(), "\n")
+	if got, want := strings.TrimSpace(lines[5]), "filter: 1 of 2"; got != want {
+		t.Errorf("unexpected status: got: %q want: %q", got, want)
+	}
+
+	ui.Type("\n")
+	if ui.V != testhelper.ChannelView || ui.Network != hamlet.Net || ui.Channel != hamlet.Name || ui.Seq != 5 {
+		t.Errorf("unexpected view: got: %v %s %s at %d want: channel view of %v at 5", ui.V, ui.Network, ui.Channel, ui.Seq, hamlet)
+	}
+}
+
+func TestInput_Query(t *testing.T) {
+	t.Parallel()
+	ui, d, v := newView()
+
+	ui.Type(" from:gertrude\n")
+	// An invalid query isn't searched for.
+	ui.Type(" after:yesterday\n")
+	if diff := cmp.Diff(d.Searches, []string{"you", "from:gertrude you"}); diff != "" {
+		t.Errorf("unexpected searches: (-got +want)\n%s", diff)
+	}
+	if ui.V != testhelper.UnknownView {
+		t.Errorf("unexpected view: got: %v want: none", ui.V)
+	}
+	if got, want := ui.Title, "filter: you from:gertrude"; got != want {
+		t.Errorf("unexpected title: got: %q want: %q", got, want)
+	}
+
+	v.OnKeyEvent(tui.KeyEvent{Key: tui.KeyEsc})
+	if ui.V != testhelper.ClientView {
+		t.Errorf("unexpected view: got: %v want: %v", ui.V, testhelper.ClientView)
+	}
+}
+
+func TestReceive(t *testing.T) {
+	t.Parallel()
+	ui, d, v := newView()
+	d.SetEvents(append(testhelper.Events[:8:8],
+		&data.MessageEvent{EventID: data.EventID{Scope: hamlet, Seq: 10}, Nick: "barnardo", Text: "Tis now strook twelue, get thee to bed Francisco"},
+		&data.MessageEvent{EventID: data.EventID{Scope: hamlet, Seq: 11}, Nick: "francisco", Text: "Giue you good night"},
+	))
+	update := &data.ChannelStateEvent{
+		EventID:      data.EventID{Scope: hamlet},
+		ChannelState: data.ChannelState{LastMessage: 11},
+	}
+	v.Receive(update)
+	// The same update again adds nothing.
+	v.Receive(update)
+
+	if diff := cmp.Diff(d.Searches, []string{"you"}); diff != "" {
+		t.Errorf("unexpected searches: (-got +want)\n%s", diff)
+	}
+	// The newest message was selected, so the new one is.
+	ui.Type("\n")
+	if ui.Seq != 11 {
+		t.Errorf("unexpected event opened: got: %d want: 11", ui.Seq)
+	}
+	v.OnKeyEvent(tui.KeyEvent{Key: tui.KeyUp})
+	ui.Type("\n")
+	if ui.Seq != 8 {
+		t.Errorf("unexpected event opened: got: %d want: 8", ui.Seq)
+	}
+}
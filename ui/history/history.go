@@ -0,0 +1,230 @@
+// Package history keeps the lines the user has entered in each channel view,
+// so they can be recalled and searched, and saves them across restarts.
+package history
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cceckman/discoirc/data"
+	"github.com/cceckman/discoirc/internal/files"
+)
+
+// DefaultSize is the default number of lines kept for each scope.
+const DefaultSize = 500
+
+// DefaultPath returns the location of the history file:
+// $XDG_DATA_HOME/discoirc/history, or ~/.local/share/discoirc/history.
+func DefaultPath() string {
+	return files.DataPath("history")
+}
+
+// Store is the input history of each scope.
+// It's safe for concurrent use.
+type Store struct {
+	mu sync.Mutex
+	// path is the file the history is saved to, or "" if it isn't saved.
+	path  string
+	size  int
+	lines map[data.Scope][]string
+}
+
+// New returns an empty Store, which keeps up to size lines for each scope, and
+// doesn't save them.
+func New(size int) *Store {
+	return &Store{
+		size:  size,
+		lines: make(map[data.Scope][]string),
+	}
+}
+
+// scopeLines is the saved form of one scope's history.
+type scopeLines struct {
+	Net   string   `json:"net"`
+	Name  string   `json:"name"`
+	Lines []string `json:"lines"`
+}
+
+// Open returns a Store that keeps up to size lines for each scope, saved to
+// the file at path. If the file exists, the history is loaded from it.
+// Other processes, e.g. those of other windows, may keep their history in the
+// same file; each line added is saved along with theirs.
+func Open(path string, size int) (*Store, error) {
+	s := New(size)
+	s.path = path
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.lines, err = s.parse(contents)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// parse returns the history saved in contents.
+func (s *Store) parse(contents []byte) (map[data.Scope][]string, error) {
+	lines := make(map[data.Scope][]string)
+	if len(contents) == 0 {
+		return lines, nil
+	}
+	var saved []scopeLines
+	if err := json.Unmarshal(contents, &saved); err != nil {
+		return nil, err
+	}
+	for _, sl := range saved {
+		lines[data.Scope{Net: sl.Net, Name: sl.Name}] = s.trim(sl.Lines)
+	}
+	return lines, nil
+}
+
+// trim returns the last size lines.
+func (s *Store) trim(lines []string) []string {
+	if len(lines) > s.size {
+		lines = lines[len(lines)-s.size:]
+	}
+	return lines
+}
+
+// Lines returns the scope's history, oldest first.
+func (s *Store) Lines(sc data.Scope) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.lines[sc]...)
+}
+
+// Add adds a line to the scope's history, and saves the history, along with
+// any lines other processes have saved since.
+// Empty lines, and repeats of the previous line, aren't added.
+func (s *Store) Add(sc data.Scope, line string) error {
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.add(s.lines, sc, line)
+	if s.path == "" {
+		return nil
+	}
+	return files.Update(s.path, 0600, func(contents []byte) ([]byte, error) {
+		saved, err := s.parse(contents)
+		if err != nil {
+			return nil, err
+		}
+		s.add(saved, sc, line)
+		s.lines = saved
+		return s.marshal()
+	})
+}
+
+// add adds a line to the scope's lines, unless it repeats the previous one.
+func (s *Store) add(lines map[data.Scope][]string, sc data.Scope, line string) {
+	l := lines[sc]
+	if len(l) > 0 && l[len(l)-1] == line {
+		return
+	}
+	lines[sc] = s.trim(append(l, line))
+}
+
+// marshal returns the saved form of the history.
+// It must be called with mu held.
+func (s *Store) marshal() ([]byte, error) {
+	var saved []scopeLines
+	for sc, lines := range s.lines {
+		saved = append(saved, scopeLines{Net: sc.Net, Name: sc.Name, Lines: lines})
+	}
+	sort.Slice(saved, func(i, j int) bool {
+		if saved[i].Net != saved[j].Net {
+			return saved[i].Net < saved[j].Net
+		}
+		return saved[i].Name < saved[j].Name
+	})
+	return json.MarshalIndent(saved, "", "\t")
+}
+
+// Cursor moves through a scope's history, as it was when the Cursor was made.
+type Cursor struct {
+	lines []string
+	// i is the index of the line shown, or len(lines) if the line being
+	// edited is shown.
+	i int
+	// draft is the line being edited before moving into the history.
+	draft string
+}
+
+// Cursor returns a Cursor positioned after the newest line of the scope's
+// history.
+func (s *Store) Cursor(sc data.Scope) *Cursor {
+	lines := s.Lines(sc)
+	return &Cursor{lines: lines, i: len(lines)}
+}
+
+// Previous moves to the next older line, and returns it. current is the line
+// being edited; it's kept, to be returned by Next after the newest line.
+// If there's no older line, Previous returns false.
+func (c *Cursor) Previous(current string) (string, bool) {
+	if c.i == 0 {
+		return "", false
+	}
+	if c.i == len(c.lines) {
+		c.draft = current
+	}
+	c.i--
+	return c.lines[c.i], true
+}
+
+// Next moves to the next newer line, and returns it; after the newest line, it
+// returns the line that was being edited. If the Cursor is already there, Next
+// returns false.
+func (c *Cursor) Next() (string, bool) {
+	if c.i == len(c.lines) {
+		return "", false
+	}
+	c.i++
+	if c.i == len(c.lines) {
+		return c.draft, true
+	}
+	return c.lines[c.i], true
+}
+
+// Reset moves the Cursor back after the newest line, and returns the line
+// that was being edited before moving into the history; or current, if the
+// Cursor is already there.
+func (c *Cursor) Reset(current string) string {
+	if c.i == len(c.lines) {
+		return current
+	}
+	c.i = len(c.lines)
+	return c.draft
+}
+
+// Search returns the newest line containing query, starting from the line
+// before the Cursor, or at the Cursor if again is false. It moves the Cursor
+// to the line found. If no line matches, Search returns false and the Cursor
+// doesn't move.
+func (c *Cursor) Search(query string, current string, again bool) (string, bool) {
+	start := c.i
+	if again || start == len(c.lines) {
+		start--
+	}
+	for i := start; i >= 0; i-- {
+		if strings.Contains(c.lines[i], query) {
+			if c.i == len(c.lines) {
+				c.draft = current
+			}
+			c.i = i
+			return c.lines[i], true
+		}
+	}
+	return "", false
+}
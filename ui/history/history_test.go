@@ -0,0 +1,181 @@
+package history_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cceckman/discoirc/data"
+	"github.com/cceckman/discoirc/ui/history"
+)
+
+var (
+	hamlet      = data.Scope{Net: "HamNet", Name: "#hamlet"}
+	battlements = data.Scope{Net: "HamNet", Name: "#battlements"}
+)
+
+func TestStore_Add(t *testing.T) {
+	t.Parallel()
+	s := history.New(3)
+	for _, line := range []string{"who's there?", "", "   ", "nay, answer me", "nay, answer me", "long live the king", "barnardo?"} {
+		if err := s.Add(hamlet, line); err != nil {
+			t.Fatalf("unexpected error adding %q: %v", line, err)
+		}
+	}
+	want := []string{"nay, answer me", "long live the king", "barnardo?"}
+	if diff := cmp.Diff(s.Lines(hamlet), want); diff != "" {
+		t.Errorf("unexpected history: (-got +want)\n%s", diff)
+	}
+	if got := s.Lines(battlements); len(got) != 0 {
+		t.Errorf("unexpected history for another scope: got: %q want: none", got)
+	}
+}
+
+func TestStore_Persist(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "discoirc-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "discoirc", "history")
+
+	s, err := history.Open(path, 10)
+	if err != nil {
+		t.Fatalf("unexpected error opening missing file: %v", err)
+	}
+	s.Add(hamlet, "/msg deploybot deploy elsinore")
+	s.Add(battlements, "'tis bitter cold")
+	s.Add(hamlet, "who's there?")
+
+	// A smaller size keeps only the newest lines.
+	reopened, err := history.Open(path, 1)
+	if err != nil {
+		t.Fatalf("unexpected error reopening: %v", err)
+	}
+	if diff := cmp.Diff(reopened.Lines(hamlet), []string{"who's there?"}); diff != "" {
+		t.Errorf("unexpected history: (-got +want)\n%s", diff)
+	}
+	if diff := cmp.Diff(reopened.Lines(battlements), []string{"'tis bitter cold"}); diff != "" {
+		t.Errorf("unexpected history: (-got +want)\n%s", diff)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("unexpected mode: got: %v want: %v", info.Mode().Perm(), os.FileMode(0600))
+	}
+
+	if err := ioutil.WriteFile(path, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := history.Open(path, 10); err == nil {
+		t.Errorf("unexpected success opening a corrupt file")
+	}
+}
+
+func TestStore_Shared(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "discoirc-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "discoirc", "history")
+
+	// Each window's process has its own Store, saved to the same file.
+	first, err := history.Open(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := history.Open(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Add(hamlet, "who's there?")
+	second.Add(battlements, "'tis bitter cold")
+	first.Add(hamlet, "long live the king")
+
+	// Lines saved by one are kept, and seen by the other once it adds one.
+	if diff := cmp.Diff(second.Lines(hamlet), []string{"who's there?"}); diff != "" {
+		t.Errorf("unexpected history: (-got +want)\n%s", diff)
+	}
+	reopened, err := history.Open(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(reopened.Lines(hamlet), []string{"who's there?", "long live the king"}); diff != "" {
+		t.Errorf("unexpected history: (-got +want)\n%s", diff)
+	}
+	if diff := cmp.Diff(reopened.Lines(battlements), []string{"'tis bitter cold"}); diff != "" {
+		t.Errorf("unexpected history: (-got +want)\n%s", diff)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	t.Parallel()
+	s := history.New(10)
+	for _, line := range []string{"one", "two", "three"} {
+		s.Add(hamlet, line)
+	}
+	c := s.Cursor(hamlet)
+
+	// Each step is "<" for Previous, or ">" for Next; want is the result.
+	for i, step := range []struct {
+		move string
+		want string
+		ok   bool
+	}{
+		{">", "", false},
+		{"<", "three", true},
+		{"<", "two", true},
+		{"<", "one", true},
+		{"<", "", false},
+		{">", "two", true},
+		{">", "three", true},
+		{">", "draft", true},
+		{">", "", false},
+	} {
+		var got string
+		var ok bool
+		if step.move == "<" {
+			got, ok = c.Previous("draft")
+		} else {
+			got, ok = c.Next()
+		}
+		if got != step.want || ok != step.ok {
+			t.Errorf("step %d (%s): got: %q, %t want: %q, %t", i, step.move, got, ok, step.want, step.ok)
+		}
+	}
+}
+
+func TestCursor_Search(t *testing.T) {
+	t.Parallel()
+	s := history.New(10)
+	for _, line := range []string{"!deploy staging", "hello", "!deploy prod", "bye"} {
+		s.Add(hamlet, line)
+	}
+	c := s.Cursor(hamlet)
+
+	if got, ok := c.Search("!dep", "draft", false); !ok || got != "!deploy prod" {
+		t.Errorf("unexpected first match: got: %q, %t want: %q", got, ok, "!deploy prod")
+	}
+	// Extending the query keeps the current match, if it still matches.
+	if got, ok := c.Search("!deploy p", "draft", false); !ok || got != "!deploy prod" {
+		t.Errorf("unexpected extended match: got: %q, %t want: %q", got, ok, "!deploy prod")
+	}
+	if got, ok := c.Search("!dep", "draft", true); !ok || got != "!deploy staging" {
+		t.Errorf("unexpected next match: got: %q, %t want: %q", got, ok, "!deploy staging")
+	}
+	if got, ok := c.Search("!dep", "draft", true); ok {
+		t.Errorf("unexpected match past the oldest line: got: %q", got)
+	}
+	if got := c.Reset("ignored"); got != "draft" {
+		t.Errorf("unexpected line after reset: got: %q want: %q", got, "draft")
+	}
+}
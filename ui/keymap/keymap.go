@@ -0,0 +1,367 @@
+// Package keymap maps keys pressed in discoirc's views to named actions.
+//
+// A Keymap binds sequences of keys to actions in each view, optionally only
+// in one of the view's modes (e.g. vim-style "normal" and "insert" modes).
+// Keymaps start from a built-in preset, and may be changed by the [keys]
+// section of the configuration file.
+package keymap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/marcusolsson/tui-go"
+
+	"github.com/cceckman/discoirc/config"
+)
+
+// Action is something a key binding does, e.g. "quit".
+type Action string
+
+// Actions that keys can be bound to.
+const (
+	// None unbinds keys, e.g. to remove a binding from a preset.
+	None = Action("none")
+
+	Quit = Action("quit")
+	// Up and Down move the selection in the client view, or scroll the
+	// channel view by one message.
+	Up   = Action("up")
+	Down = Action("down")
+	// PageUp and PageDown scroll the channel view by a screenful.
+	PageUp   = Action("page-up")
+	PageDown = Action("page-down")
+	// Bottom scrolls the channel view to the newest message.
+	Bottom = Action("bottom")
+	// ShowClient switches from the channel or filter view to the client
+	// view.
+	ShowClient = Action("client")
+	// ShowFilter opens the filter view from the client view. Open, in the
+	// filter view, applies the query being typed, or opens the selected
+	// message in its channel.
+	ShowFilter = Action("filter")
+	Open       = Action("open")
+	// Complete and CompletePrevious complete the word being typed, cycling
+	// forward or back through the possible completions.
+	Complete         = Action("complete")
+	CompletePrevious = Action("complete-previous")
+	// HistoryPrevious and HistoryNext recall older and newer lines from the
+	// channel's input history. HistorySearch starts an incremental search of
+	// it, or finds the next older match.
+	HistoryPrevious = Action("history-previous")
+	HistoryNext     = Action("history-next")
+	HistorySearch   = Action("history-search")
+	// Newline starts a new line of the message being typed, rather than
+	// sending it.
+	Newline = Action("newline")
+	// Search starts a search of the channel's messages, in insert mode.
+	// SearchNext and SearchPrevious scroll to the next older and newer
+	// match.
+	Search         = Action("search")
+	SearchNext     = Action("search-next")
+	SearchPrevious = Action("search-previous")
+	// ToggleFilter turns the channel view's filter, set by "/only", off, or
+	// back on.
+	ToggleFilter = Action("toggle-filter")
+
+	// Insert, normal, and command modes of a modal view.
+	InsertMode  = Action("insert-mode")
+	NormalMode  = Action("normal-mode")
+	CommandMode = Action("command-mode")
+
+	// Management of the selected network or channel in the client view.
+	NewNetwork = Action("new-network")
+	Connect    = Action("connect")
+	Disconnect = Action("disconnect")
+	Edit       = Action("edit")
+	Close      = Action("close")
+
+	// Toggles for the demo backend.
+	DemoNetwork  = Action("demo-network")
+	DemoChannel  = Action("demo-channel")
+	DemoMessages = Action("demo-messages")
+)
+
+// Views that have key bindings.
+const (
+	Client  = "client"
+	Channel = "channel"
+	Filter  = "filter"
+	// Global bindings apply in every view. They may only be single keys.
+	Global = "global"
+)
+
+// Modes of a modal view.
+const (
+	// Insert mode sends keys to the input line, as in a modeless view.
+	Insert = "insert"
+	// Normal mode treats keys as commands; unbound keys are ignored.
+	Normal = "normal"
+)
+
+// views lists the modes and actions available in each view.
+var views = map[string]struct {
+	modes   []string
+	actions []Action
+}{
+	Client: {
+		actions: []Action{Quit, Up, Down, ShowFilter, NewNetwork, Connect, Disconnect, Edit, Close},
+	},
+	Channel: {
+		modes: []string{Insert, Normal},
+		actions: []Action{
+			Quit, Up, Down, PageUp, PageDown, Bottom, ShowClient,
+			Complete, CompletePrevious,
+			HistoryPrevious, HistoryNext, HistorySearch, Newline,
+			Search, SearchNext, SearchPrevious, ToggleFilter,
+			InsertMode, NormalMode, CommandMode,
+		},
+	},
+	Filter: {
+		actions: []Action{Quit, Up, Down, PageUp, PageDown, Bottom, ShowClient, Open},
+	},
+	Global: {
+		actions: []Action{DemoNetwork, DemoChannel, DemoMessages},
+	},
+}
+
+// scope is a view, and a mode of it; mode is empty for bindings that apply in
+// every mode.
+type scope struct {
+	view, mode string
+}
+
+// Keymap binds sequences of keys to actions.
+type Keymap struct {
+	// Name is the name of the preset the Keymap started from.
+	Name string
+
+	// bindings maps a sequence of key names, separated by spaces, to the
+	// action it's bound to.
+	bindings map[scope]map[string]Action
+	// initial is the mode each view starts in, if it's modal.
+	initial map[string]string
+}
+
+// New returns an empty Keymap with the given name.
+func New(name string) *Keymap {
+	return &Keymap{
+		Name:     name,
+		bindings: make(map[scope]map[string]Action),
+		initial:  make(map[string]string),
+	}
+}
+
+// Bind binds the sequence of keys to the action in the view's mode, or in
+// every mode of the view if mode is empty. Keys must be named as KeyName names
+// them. Binding to None removes any existing binding.
+func (k *Keymap) Bind(view, mode string, a Action, keys ...string) {
+	sc := scope{view, mode}
+	seq := strings.Join(keys, " ")
+	if a == None {
+		delete(k.bindings[sc], seq)
+		return
+	}
+	if k.bindings[sc] == nil {
+		k.bindings[sc] = make(map[string]Action)
+	}
+	k.bindings[sc][seq] = a
+}
+
+// SetInitialMode sets the mode the view starts in. If it's empty (the
+// default), the view is modeless.
+func (k *Keymap) SetInitialMode(view, mode string) {
+	k.initial[view] = mode
+}
+
+// InitialMode returns the mode the view starts in, or "" if it's modeless.
+func (k *Keymap) InitialMode(view string) string {
+	return k.initial[view]
+}
+
+// Keys returns the key sequences bound to the action in every mode of the
+// view, in sorted order.
+func (k *Keymap) Keys(view string, a Action) []string {
+	var keys []string
+	for seq, b := range k.bindings[scope{view: view}] {
+		if b == a {
+			keys = append(keys, seq)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// lookup returns the action bound to the sequence in the view's mode.
+// Bindings for the mode take precedence over those for every mode.
+func (k *Keymap) lookup(view, mode, seq string) (Action, bool) {
+	if mode != "" {
+		if a, ok := k.bindings[scope{view, mode}][seq]; ok {
+			return a, true
+		}
+	}
+	a, ok := k.bindings[scope{view: view}][seq]
+	return a, ok
+}
+
+// isPrefix reports whether the sequence is the start of a longer sequence
+// bound in the view's mode.
+func (k *Keymap) isPrefix(view, mode, seq string) bool {
+	seq += " "
+	for _, sc := range []scope{{view, mode}, {view: view}} {
+		for s := range k.bindings[sc] {
+			if strings.HasPrefix(s, seq) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Load returns the Keymap described by the configuration: its preset, with
+// its bindings added.
+func Load(cfg config.Keys) (*Keymap, error) {
+	name := cfg.Preset
+	if name == "" {
+		name = Legacy
+	}
+	k, ok := Preset(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown keymap preset %q; want one of %s", name, strings.Join(Presets, ", "))
+	}
+
+	var errs []string
+	for _, b := range cfg.Bindings {
+		keys, err := check(b)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("bind = %s: %v", b, err))
+			continue
+		}
+		k.Bind(b.View, b.Mode, Action(b.Action), keys...)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return k, nil
+}
+
+// check checks that the binding's view, mode, and action exist, and returns
+// its keys' names as KeyName names them.
+func check(b config.Binding) ([]string, error) {
+	v, ok := views[b.View]
+	if !ok {
+		return nil, fmt.Errorf("unknown view %q", b.View)
+	}
+	if b.Mode != "" && !containsMode(v.modes, b.Mode) {
+		return nil, fmt.Errorf("unknown mode %q in %s view", b.Mode, b.View)
+	}
+	if a := Action(b.Action); a != None && !containsAction(v.actions, a) {
+		return nil, fmt.Errorf("unknown action %q in %s view", b.Action, b.View)
+	}
+	if b.View == Global && len(b.Keys) != 1 {
+		return nil, fmt.Errorf("global bindings must be a single key")
+	}
+	keys := make([]string, len(b.Keys))
+	for i, key := range b.Keys {
+		var err error
+		if keys[i], err = ParseKey(key); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+func containsMode(modes []string, m string) bool {
+	for _, v := range modes {
+		if v == m {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAction(actions []Action, a Action) bool {
+	for _, v := range actions {
+		if v == a {
+			return true
+		}
+	}
+	return false
+}
+
+// State tracks the mode of a view, and the keys of a sequence pressed so far.
+type State struct {
+	keymap  *Keymap
+	view    string
+	mode    string
+	pending []string
+}
+
+// NewState returns the State of a new view, in its initial mode.
+func (k *Keymap) NewState(view string) *State {
+	return &State{
+		keymap: k,
+		view:   view,
+		mode:   k.InitialMode(view),
+	}
+}
+
+// Mode returns the current mode, or "" if the view is modeless.
+func (s *State) Mode() string {
+	return s.mode
+}
+
+// SetMode sets the current mode.
+func (s *State) SetMode(mode string) {
+	s.mode = mode
+}
+
+// Indicator returns a short description of the mode, and of the keys pressed
+// so far in a sequence, e.g. "NORMAL Ctrl+X". It's empty for a modeless view
+// with no keys pending.
+func (s *State) Indicator() string {
+	return strings.TrimSpace(strings.ToUpper(s.mode) + " " + strings.Join(s.pending, " "))
+}
+
+// Handle handles a key press.
+// If it completes a bound sequence, Handle returns the bound action. Actions
+// that change mode are applied to the State before they're returned.
+// handled is true if the key completes or continues a sequence, or breaks off
+// a sequence in progress; otherwise, the key is unbound, and should be
+// handled as it would be without a keymap.
+func (s *State) Handle(ev tui.KeyEvent) (a Action, handled bool) {
+	name := KeyName(ev)
+	if name == "" {
+		// Keys without names can't be bound.
+		handled = len(s.pending) > 0
+		s.pending = nil
+		return "", handled
+	}
+	seq := strings.Join(append(s.pending, name), " ")
+	if a, ok := s.keymap.lookup(s.view, s.mode, seq); ok {
+		s.pending = nil
+		switch a {
+		case InsertMode, CommandMode:
+			s.mode = Insert
+		case Search:
+			// The query is typed in the input line.
+			if s.mode != "" {
+				s.mode = Insert
+			}
+		case NormalMode:
+			s.mode = Normal
+		}
+		return a, true
+	}
+	if s.keymap.isPrefix(s.view, s.mode, seq) {
+		s.pending = append(s.pending, name)
+		return "", true
+	}
+	if len(s.pending) > 0 {
+		// The sequence is broken off; drop it, and the key.
+		s.pending = nil
+		return "", true
+	}
+	return "", false
+}
@@ -0,0 +1,262 @@
+package keymap_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/marcusolsson/tui-go"
+
+	"github.com/cceckman/discoirc/config"
+	"github.com/cceckman/discoirc/ui/keymap"
+)
+
+func TestKeyName(t *testing.T) {
+	for _, tt := range []struct {
+		ev   tui.KeyEvent
+		want string
+	}{
+		{tui.KeyEvent{Key: tui.KeyRune, Rune: 'j'}, "j"},
+		{tui.KeyEvent{Key: tui.KeyRune, Rune: ' '}, "Space"},
+		{tui.KeyEvent{Key: tui.KeyRune, Rune: 'v', Modifiers: tui.ModAlt}, "Alt+v"},
+		{tui.KeyEvent{Key: tui.KeyCtrlX, Modifiers: tui.ModCtrl}, "Ctrl+X"},
+		{tui.KeyEvent{Key: tui.KeyEsc}, "Esc"},
+		{tui.KeyEvent{Key: tui.KeyBackspace2}, "Backspace"},
+		{tui.KeyEvent{Key: tui.KeyUp, Modifiers: tui.ModShift}, "Shift+Up"},
+		{tui.KeyEvent{Key: tui.KeyF5}, "F5"},
+		{tui.KeyEvent{Key: tui.KeyHelp}, ""},
+	} {
+		if got := keymap.KeyName(tt.ev); got != tt.want {
+			t.Errorf("unexpected name for %+v: got: %q want: %q", tt.ev, got, tt.want)
+		}
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	for _, tt := range []struct {
+		key, want string
+		err       bool
+	}{
+		{key: "j", want: "j"},
+		{key: "G", want: "G"},
+		{key: "space", want: "Space"},
+		{key: "ctrl+x", want: "Ctrl+X"},
+		{key: "C-x", want: "Ctrl+X"},
+		{key: "M-v", want: "Alt+v"},
+		{key: "Alt+>", want: "Alt+>"},
+		{key: "Ctrl+I", want: "Tab"},
+		{key: "pageup", want: "PgUp"},
+		{key: "S-up", want: "Shift+Up"},
+		{key: "Shift+a", err: true},
+		{key: "Shift+Ctrl+A", err: true},
+		{key: "Ctrl+F5", err: true},
+		{key: "Hyper+x", err: true},
+	} {
+		got, err := keymap.ParseKey(tt.key)
+		if (err != nil) != tt.err {
+			t.Errorf("unexpected error for %q: got: %v want error: %t", tt.key, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("unexpected name for %q: got: %q want: %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+// press returns the key events for the named keys.
+func press(names ...string) []tui.KeyEvent {
+	evs := make([]tui.KeyEvent, len(names))
+	for i, n := range names {
+		switch n {
+		case "Esc":
+			evs[i] = tui.KeyEvent{Key: tui.KeyEsc}
+		case "Ctrl+C":
+			evs[i] = tui.KeyEvent{Key: tui.KeyCtrlC}
+		case "Ctrl+X":
+			evs[i] = tui.KeyEvent{Key: tui.KeyCtrlX}
+		default:
+			evs[i] = tui.KeyEvent{Key: tui.KeyRune, Rune: []rune(n)[0]}
+		}
+	}
+	return evs
+}
+
+// step is the result of handling one key.
+type step struct {
+	Action  keymap.Action
+	Handled bool
+	Mode    string
+}
+
+var stateTests = []struct {
+	test   string
+	preset string
+	view   string
+	keys   []string
+	want   []step
+}{
+	{
+		test:   "legacy is modeless",
+		preset: keymap.Legacy,
+		view:   keymap.Channel,
+		keys:   []string{"j", "Esc", "Ctrl+C"},
+		want: []step{
+			{},
+			{},
+			{Action: keymap.Quit, Handled: true},
+		},
+	},
+	{
+		test:   "vim modes",
+		preset: keymap.Vim,
+		view:   keymap.Channel,
+		keys:   []string{"j", "Esc", "j", "x", "i", "j", "Esc", ":"},
+		want: []step{
+			{Mode: keymap.Insert},
+			{Action: keymap.NormalMode, Handled: true, Mode: keymap.Normal},
+			{Action: keymap.Down, Handled: true, Mode: keymap.Normal},
+			{Mode: keymap.Normal},
+			{Action: keymap.InsertMode, Handled: true, Mode: keymap.Insert},
+			{Mode: keymap.Insert},
+			{Action: keymap.NormalMode, Handled: true, Mode: keymap.Normal},
+			{Action: keymap.CommandMode, Handled: true, Mode: keymap.Insert},
+		},
+	},
+	{
+		test:   "vim search",
+		preset: keymap.Vim,
+		view:   keymap.Channel,
+		keys:   []string{"Esc", "/", "n", "Esc", "n", "N"},
+		want: []step{
+			{Action: keymap.NormalMode, Handled: true, Mode: keymap.Normal},
+			{Action: keymap.Search, Handled: true, Mode: keymap.Insert},
+			{Mode: keymap.Insert},
+			{Action: keymap.NormalMode, Handled: true, Mode: keymap.Normal},
+			{Action: keymap.SearchNext, Handled: true, Mode: keymap.Normal},
+			{Action: keymap.SearchPrevious, Handled: true, Mode: keymap.Normal},
+		},
+	},
+	{
+		test:   "vim sequence",
+		preset: keymap.Vim,
+		view:   keymap.Channel,
+		keys:   []string{"Esc", "Z", "x", "Z", "Z"},
+		want: []step{
+			{Action: keymap.NormalMode, Handled: true, Mode: keymap.Normal},
+			{Handled: true, Mode: keymap.Normal},
+			{Handled: true, Mode: keymap.Normal},
+			{Handled: true, Mode: keymap.Normal},
+			{Action: keymap.Quit, Handled: true, Mode: keymap.Normal},
+		},
+	},
+	{
+		test:   "emacs sequence",
+		preset: keymap.Emacs,
+		view:   keymap.Client,
+		keys:   []string{"Ctrl+X", "j", "j", "Ctrl+X", "Ctrl+C"},
+		want: []step{
+			{Handled: true},
+			{Handled: true},
+			{Action: keymap.Down, Handled: true},
+			{Handled: true},
+			{Action: keymap.Quit, Handled: true},
+		},
+	},
+}
+
+func TestState(t *testing.T) {
+	for _, tt := range stateTests {
+		tt := tt
+		t.Run(tt.test, func(t *testing.T) {
+			t.Parallel()
+			k, ok := keymap.Preset(tt.preset)
+			if !ok {
+				t.Fatalf("no preset %q", tt.preset)
+			}
+			s := k.NewState(tt.view)
+			var got []step
+			for _, ev := range press(tt.keys...) {
+				a, handled := s.Handle(ev)
+				got = append(got, step{Action: a, Handled: handled, Mode: s.Mode()})
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("unexpected steps: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIndicator(t *testing.T) {
+	t.Parallel()
+	k, _ := keymap.Preset(keymap.Vim)
+	s := k.NewState(keymap.Channel)
+	if got, want := s.Indicator(), "INSERT"; got != want {
+		t.Errorf("unexpected indicator: got: %q want: %q", got, want)
+	}
+	for _, ev := range press("Esc", "Z") {
+		s.Handle(ev)
+	}
+	if got, want := s.Indicator(), "NORMAL Z"; got != want {
+		t.Errorf("unexpected indicator: got: %q want: %q", got, want)
+	}
+
+	if got := keymap.Default().NewState(keymap.Channel).Indicator(); got != "" {
+		t.Errorf("unexpected indicator for modeless view: got: %q want: \"\"", got)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+	k, err := keymap.Load(config.Keys{
+		Preset: keymap.Vim,
+		Bindings: []config.Binding{
+			{View: "channel", Mode: "normal", Keys: []string{"C-d"}, Action: "page-down"},
+			{View: "channel", Mode: "normal", Keys: []string{"Z", "Z"}, Action: "none"},
+			{View: "global", Keys: []string{"F9"}, Action: "demo-messages"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := k.Name, keymap.Vim; got != want {
+		t.Errorf("unexpected name: got: %q want: %q", got, want)
+	}
+	if diff := cmp.Diff(k.Keys(keymap.Global, keymap.DemoMessages), []string{"F7", "F9"}); diff != "" {
+		t.Errorf("unexpected global keys: (-got +want)\n%s", diff)
+	}
+
+	s := k.NewState(keymap.Channel)
+	s.SetMode(keymap.Normal)
+	if a, _ := s.Handle(tui.KeyEvent{Key: tui.KeyCtrlD}); a != keymap.PageDown {
+		t.Errorf("unexpected action for Ctrl+D: got: %q want: %q", a, keymap.PageDown)
+	}
+	for _, ev := range press("Z", "Z") {
+		if a, _ := s.Handle(ev); a != "" {
+			t.Errorf("unexpected action for unbound Z Z: got: %q", a)
+		}
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	t.Parallel()
+	if _, err := keymap.Load(config.Keys{Preset: "nano"}); err == nil {
+		t.Errorf("unexpected success loading unknown preset")
+	}
+
+	_, err := keymap.Load(config.Keys{
+		Bindings: []config.Binding{
+			{View: "status", Keys: []string{"q"}, Action: "quit"},
+			{View: "client", Mode: "normal", Keys: []string{"q"}, Action: "quit"},
+			{View: "client", Keys: []string{"q"}, Action: "page-up"},
+			{View: "client", Keys: []string{"Hyper+q"}, Action: "quit"},
+			{View: "global", Keys: []string{"g", "g"}, Action: "demo-network"},
+		},
+	})
+	want := `bind = status q quit: unknown view "status"
+bind = client:normal q quit: unknown mode "normal" in client view
+bind = client q page-up: unknown action "page-up" in client view
+bind = client Hyper+q quit: unknown key "Hyper+q"
+bind = global g g demo-network: global bindings must be a single key`
+	if err == nil || err.Error() != want {
+		t.Errorf("unexpected error: got:\n%v\nwant:\n%s", err, want)
+	}
+}
@@ -0,0 +1,158 @@
+package keymap
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/marcusolsson/tui-go"
+)
+
+// keyNames are the names of keys other than runes.
+// Control characters that have their own keys (e.g. Ctrl+I, Tab) are named
+// for the key.
+var keyNames = map[tui.Key]string{
+	tui.KeyUp:         "Up",
+	tui.KeyDown:       "Down",
+	tui.KeyLeft:       "Left",
+	tui.KeyRight:      "Right",
+	tui.KeyPgUp:       "PgUp",
+	tui.KeyPgDn:       "PgDn",
+	tui.KeyHome:       "Home",
+	tui.KeyEnd:        "End",
+	tui.KeyInsert:     "Insert",
+	tui.KeyDelete:     "Delete",
+	tui.KeyBacktab:    "Backtab",
+	tui.KeyF1:         "F1",
+	tui.KeyF2:         "F2",
+	tui.KeyF3:         "F3",
+	tui.KeyF4:         "F4",
+	tui.KeyF5:         "F5",
+	tui.KeyF6:         "F6",
+	tui.KeyF7:         "F7",
+	tui.KeyF8:         "F8",
+	tui.KeyF9:         "F9",
+	tui.KeyF10:        "F10",
+	tui.KeyF11:        "F11",
+	tui.KeyF12:        "F12",
+	tui.KeyBackspace:  "Backspace",
+	tui.KeyBackspace2: "Backspace",
+	tui.KeyTab:        "Tab",
+	tui.KeyEnter:      "Enter",
+	tui.KeyEsc:        "Esc",
+
+	tui.KeyCtrlSpace: "Ctrl+Space",
+}
+
+// lookup maps the lower-case name of a key, or an alias for it, to its name.
+var lookup = map[string]string{
+	"space":    "Space",
+	"pageup":   "PgUp",
+	"pagedown": "PgDn",
+	"return":   "Enter",
+	"escape":   "Esc",
+	"ctrl+h":   "Backspace",
+	"ctrl+i":   "Tab",
+	"ctrl+m":   "Enter",
+	"ctrl+[":   "Esc",
+}
+
+func init() {
+	for k := tui.KeyCtrlA; k <= tui.KeyCtrlZ; k++ {
+		if _, ok := keyNames[k]; !ok {
+			keyNames[k] = fmt.Sprintf("Ctrl+%c", 'A'+rune(k-tui.KeyCtrlA))
+		}
+	}
+	for _, name := range keyNames {
+		lookup[strings.ToLower(name)] = name
+	}
+}
+
+// KeyName returns the name of the key pressed, e.g. "j", "Ctrl+X", "PgUp", or
+// "Alt+v"; or "" if the key has no name.
+func KeyName(ev tui.KeyEvent) string {
+	var name string
+	switch {
+	case ev.Key == tui.KeyRune && ev.Rune == ' ':
+		name = "Space"
+	case ev.Key == tui.KeyRune:
+		name = string(ev.Rune)
+	default:
+		name = keyNames[ev.Key]
+		if name == "" {
+			return ""
+		}
+		// Shift is only reported for keys that aren't characters; e.g.
+		// Ctrl+X and Ctrl+Shift+X are the same control character.
+		if ev.Key > tui.KeyRune && ev.Modifiers&tui.ModShift != 0 {
+			name = "Shift+" + name
+		}
+	}
+	if ev.Modifiers&tui.ModAlt != 0 {
+		name = "Alt+" + name
+	}
+	return name
+}
+
+// ParseKey returns the name of a key as KeyName names it, from a name given
+// by the user. Modifiers may be written as e.g. "Ctrl+x" or "C-x", and names
+// other than single characters are not case-sensitive.
+func ParseKey(s string) (string, error) {
+	var ctrl, alt, shift bool
+	mods := []struct {
+		prefix string
+		set    *bool
+	}{
+		{"ctrl+", &ctrl}, {"c-", &ctrl},
+		{"alt+", &alt}, {"meta+", &alt}, {"m-", &alt},
+		{"shift+", &shift}, {"s-", &shift},
+	}
+	rest := s
+	for found := true; found; {
+		found = false
+		for _, m := range mods {
+			if len(rest) > len(m.prefix) && strings.HasPrefix(strings.ToLower(rest), m.prefix) {
+				*m.set, rest, found = true, rest[len(m.prefix):], true
+			}
+		}
+	}
+
+	var name string
+	switch {
+	case rest == "":
+		return "", fmt.Errorf("invalid key %q", s)
+	case utf8.RuneCountInString(rest) == 1 && !ctrl:
+		if shift {
+			return "", fmt.Errorf("invalid key %q: use the shifted character instead", s)
+		}
+		name = rest
+	default:
+		if ctrl {
+			rest = "ctrl+" + rest
+		}
+		var ok bool
+		if name, ok = lookup[strings.ToLower(rest)]; !ok {
+			return "", fmt.Errorf("unknown key %q", s)
+		}
+		if shift {
+			if strings.HasPrefix(name, "Ctrl+") || isChar(name) {
+				return "", fmt.Errorf("invalid key %q: Shift can't be used with %s", s, name)
+			}
+			name = "Shift+" + name
+		}
+	}
+	if alt {
+		name = "Alt+" + name
+	}
+	return name, nil
+}
+
+// isChar reports whether the named key types a character, or is a control
+// character.
+func isChar(name string) bool {
+	switch name {
+	case "Space", "Backspace", "Tab", "Enter", "Esc":
+		return true
+	}
+	return false
+}
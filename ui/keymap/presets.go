@@ -0,0 +1,134 @@
+package keymap
+
+// Names of the built-in keymaps.
+const (
+	// Legacy is the default keymap. It's modeless; all commands in the
+	// channel view are "/"-prefixed messages.
+	Legacy = "legacy"
+	// Vim adds a normal mode to the channel view, for scrolling and for
+	// commands via ":".
+	Vim = "vim"
+	// Emacs uses Emacs-style control and meta keys.
+	Emacs = "emacs"
+)
+
+// Presets are the names of the built-in keymaps.
+var Presets = []string{Legacy, Vim, Emacs}
+
+// Default returns the default keymap.
+func Default() *Keymap {
+	k, _ := Preset(Legacy)
+	return k
+}
+
+// Preset returns a new copy of the named built-in keymap, or false if there's
+// no such preset.
+func Preset(name string) (*Keymap, bool) {
+	k := New(name)
+	switch name {
+	case Legacy:
+		bindCommon(k)
+		bindHistory(k, "")
+		k.Bind(Channel, "", HistorySearch, "Ctrl+R")
+		k.Bind(Channel, "", Search, "Ctrl+F")
+		bindDemo(k)
+	case Vim:
+		bindCommon(k)
+		k.Bind(Client, "", Quit, "q")
+
+		k.SetInitialMode(Channel, Insert)
+		k.Bind(Channel, Insert, NormalMode, "Esc")
+		bindHistory(k, Insert)
+		k.Bind(Channel, Insert, HistorySearch, "Ctrl+R")
+		for _, b := range []struct {
+			a    Action
+			keys []string
+		}{
+			{InsertMode, []string{"i"}},
+			{InsertMode, []string{"a"}},
+			{CommandMode, []string{":"}},
+			{Search, []string{"/"}},
+			{SearchNext, []string{"n"}},
+			{SearchPrevious, []string{"N"}},
+			{Down, []string{"j"}},
+			{Up, []string{"k"}},
+			{PageDown, []string{"Ctrl+F"}},
+			{PageUp, []string{"Ctrl+B"}},
+			{Bottom, []string{"G"}},
+			{Quit, []string{"Z", "Z"}},
+		} {
+			k.Bind(Channel, Normal, b.a, b.keys...)
+		}
+		bindDemo(k)
+	case Emacs:
+		bindCommon(k)
+		k.Bind(Client, "", Down, "Ctrl+N")
+		k.Bind(Client, "", Up, "Ctrl+P")
+		for _, view := range []string{Client, Channel} {
+			k.Bind(view, "", Quit, "Ctrl+X", "Ctrl+C")
+		}
+		k.Bind(Channel, "", PageDown, "Ctrl+V")
+		k.Bind(Channel, "", PageUp, "Alt+v")
+		k.Bind(Channel, "", Bottom, "Alt+>")
+		k.Bind(Channel, "", ShowClient, "Ctrl+X", "b")
+		bindHistory(k, "")
+		k.Bind(Channel, "", HistoryPrevious, "Alt+p")
+		k.Bind(Channel, "", HistoryNext, "Alt+n")
+		k.Bind(Channel, "", HistorySearch, "Ctrl+R")
+		k.Bind(Channel, "", Search, "Alt+s")
+		bindDemo(k)
+	default:
+		return nil, false
+	}
+	return k, true
+}
+
+// bindCommon binds the keys shared by all presets.
+func bindCommon(k *Keymap) {
+	k.Bind(Client, "", Quit, "Ctrl+C")
+	k.Bind(Client, "", Down, "Down")
+	k.Bind(Client, "", Down, "j")
+	k.Bind(Client, "", Up, "Up")
+	k.Bind(Client, "", Up, "k")
+	k.Bind(Client, "", NewNetwork, "n")
+	k.Bind(Client, "", Connect, "c")
+	k.Bind(Client, "", Disconnect, "d")
+	k.Bind(Client, "", Edit, "e")
+	k.Bind(Client, "", Close, "x")
+	k.Bind(Client, "", ShowFilter, "f")
+
+	k.Bind(Channel, "", Quit, "Ctrl+C")
+	k.Bind(Channel, "", PageUp, "PgUp")
+	k.Bind(Channel, "", PageDown, "PgDn")
+	k.Bind(Channel, "", Complete, "Tab")
+	k.Bind(Channel, "", CompletePrevious, "Backtab")
+	k.Bind(Channel, "", Newline, "Alt+Enter")
+	k.Bind(Channel, "", SearchNext, "F3")
+	k.Bind(Channel, "", SearchPrevious, "Shift+F3")
+	k.Bind(Channel, "", ToggleFilter, "F4")
+
+	// Keys other than these edit the filter view's query.
+	k.Bind(Filter, "", Quit, "Ctrl+C")
+	k.Bind(Filter, "", Up, "Up")
+	k.Bind(Filter, "", Down, "Down")
+	k.Bind(Filter, "", PageUp, "PgUp")
+	k.Bind(Filter, "", PageDown, "PgDn")
+	k.Bind(Filter, "", Bottom, "End")
+	k.Bind(Filter, "", ShowClient, "Esc")
+	k.Bind(Filter, "", Open, "Enter")
+}
+
+// bindHistory binds the arrow keys to the channel's input history, in the
+// given mode.
+func bindHistory(k *Keymap, mode string) {
+	k.Bind(Channel, mode, HistoryPrevious, "Up")
+	k.Bind(Channel, mode, HistoryNext, "Down")
+}
+
+// bindDemo binds the demo toggles to function keys, leaving control keys for
+// views.
+func bindDemo(k *Keymap) {
+	k.Bind(Global, "", DemoNetwork, "F5")
+	k.Bind(Global, "", DemoChannel, "F6")
+	k.Bind(Global, "", DemoMessages, "F7")
+}
@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/cceckman/discoirc/backend"
+)
+
+// TargetScheme is the URL scheme of view targets, e.g. "discoirc://net/#chan".
+const TargetScheme = "discoirc://"
+
+// Target is a view to open: a channel, or the client view if Channel is empty.
+type Target struct {
+	Network, Channel string
+}
+
+// String returns the Target as a URL.
+func (t Target) String() string {
+	if t.Channel == "" {
+		return TargetScheme + t.Network
+	}
+	return TargetScheme + t.Network + "/" + t.Channel
+}
+
+// ParseTarget returns the Target given by launch arguments: the -network and
+// -channel flags, or a single "discoirc://network/#channel" argument.
+// If none are given, it returns the zero Target.
+func ParseTarget(network, channel string, args []string) (Target, error) {
+	t := Target{Network: network, Channel: channel}
+	switch {
+	case len(args) > 1:
+		return Target{}, fmt.Errorf("too many targets: %q", args)
+	case len(args) == 1 && (network != "" || channel != ""):
+		return Target{}, fmt.Errorf("target %q given along with -network or -channel", args[0])
+	case len(args) == 1:
+		var err error
+		if t, err = parseTargetURL(args[0]); err != nil {
+			return Target{}, err
+		}
+	}
+	if t.Channel != "" && t.Network == "" {
+		return Target{}, fmt.Errorf("channel %q needs a network", t.Channel)
+	}
+	return t, nil
+}
+
+// parseTargetURL parses a "discoirc://network[/channel]" target.
+// '#' is not a URL fragment separator here; it's part of the channel name.
+// Either part may be percent-encoded.
+func parseTargetURL(arg string) (Target, error) {
+	if !strings.HasPrefix(arg, TargetScheme) {
+		return Target{}, fmt.Errorf("invalid target %q: want %snetwork/#channel", arg, TargetScheme)
+	}
+	rest := strings.TrimSuffix(strings.TrimPrefix(arg, TargetScheme), "/")
+	parts := strings.SplitN(rest, "/", 2)
+	var t Target
+	for i, dst := range []*string{&t.Network, &t.Channel} {
+		if i >= len(parts) {
+			break
+		}
+		v, err := url.PathUnescape(parts[i])
+		if err != nil {
+			return Target{}, fmt.Errorf("invalid target %q: %v", arg, err)
+		}
+		*dst = v
+	}
+	if t.Network == "" {
+		return Target{}, fmt.Errorf("invalid target %q: no network", arg)
+	}
+	return t, nil
+}
+
+// Check returns an error if the Target's network or channel isn't known to
+// the Directory.
+func (t Target) Check(d backend.Directory) error {
+	if t.Network == "" {
+		return nil
+	}
+	if !contains(d.Networks(), t.Network) {
+		return fmt.Errorf("no such network %q", t.Network)
+	}
+	if t.Channel != "" && !contains(d.Channels(t.Network), t.Channel) {
+		return fmt.Errorf("no channel %q on network %q", t.Channel, t.Network)
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
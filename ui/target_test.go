@@ -0,0 +1,91 @@
+package ui_test
+
+import (
+	"testing"
+
+	"github.com/cceckman/discoirc/ui"
+)
+
+var targetTests = []struct {
+	test             string
+	network, channel string
+	args             []string
+	want             ui.Target
+	wantErr          string
+}{
+	{test: "none"},
+	{
+		test:    "flags",
+		network: "HamNet",
+		channel: "#hamlet",
+		want:    ui.Target{Network: "HamNet", Channel: "#hamlet"},
+	},
+	{
+		test:    "network flag",
+		network: "HamNet",
+		want:    ui.Target{Network: "HamNet"},
+	},
+	{
+		test: "URL",
+		args: []string{"discoirc://HamNet/#hamlet"},
+		want: ui.Target{Network: "HamNet", Channel: "#hamlet"},
+	},
+	{
+		test: "escaped URL",
+		args: []string{"discoirc://Ham%20Net/%23hamlet"},
+		want: ui.Target{Network: "Ham Net", Channel: "#hamlet"},
+	},
+	{
+		test: "network URL",
+		args: []string{"discoirc://HamNet/"},
+		want: ui.Target{Network: "HamNet"},
+	},
+	{
+		test:    "channel without network",
+		channel: "#hamlet",
+		wantErr: `channel "#hamlet" needs a network`,
+	},
+	{
+		test:    "flags and URL",
+		network: "HamNet",
+		args:    []string{"discoirc://HamNet/#hamlet"},
+		wantErr: `target "discoirc://HamNet/#hamlet" given along with -network or -channel`,
+	},
+	{
+		test:    "not a URL",
+		args:    []string{"HamNet"},
+		wantErr: `invalid target "HamNet": want discoirc://network/#channel`,
+	},
+	{
+		test:    "empty URL",
+		args:    []string{"discoirc://"},
+		wantErr: `invalid target "discoirc://": no network`,
+	},
+	{
+		test:    "too many",
+		args:    []string{"discoirc://HamNet", "discoirc://Globe"},
+		wantErr: `too many targets: ["discoirc://HamNet" "discoirc://Globe"]`,
+	},
+}
+
+func TestParseTarget(t *testing.T) {
+	for _, tt := range targetTests {
+		tt := tt
+		t.Run(tt.test, func(t *testing.T) {
+			t.Parallel()
+			got, err := ui.ParseTarget(tt.network, tt.channel, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("unexpected error: got: %v want: %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("unexpected target: got: %+v want: %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,5 +1,11 @@
 package testhelper
 
+import (
+	"github.com/cceckman/discoirc/data"
+	"github.com/cceckman/discoirc/ui/history"
+	"github.com/cceckman/discoirc/ui/keymap"
+)
+
 // NewController returns a mock Controller.
 func NewController() *Controller {
 	return &Controller{
@@ -15,6 +21,7 @@ const (
 	UnknownView = ActiveView(iota)
 	ClientView
 	ChannelView
+	FilterView
 )
 
 // Controller is mock global-level controller.
@@ -25,6 +32,36 @@ type Controller struct {
 	V       ActiveView
 	Network string
 	Channel string
+	// Seq is the Event shown by ActivateEvent.
+	Seq data.Seq
+	// Query is the query shown by ActivateFilter.
+	Query string
+	Title string
+	// Keys are the key bindings views use; if nil, the default keymap.
+	Keys *keymap.Keymap
+	// Hist is the input history views use; if nil, an empty one.
+	Hist *history.Store
+}
+
+// History returns the input history for views to use.
+func (c *Controller) History() *history.Store {
+	if c.Hist == nil {
+		c.Hist = history.New(history.DefaultSize)
+	}
+	return c.Hist
+}
+
+// Keymap returns the key bindings for views to use.
+func (c *Controller) Keymap() *keymap.Keymap {
+	if c.Keys == nil {
+		c.Keys = keymap.Default()
+	}
+	return c.Keys
+}
+
+// SetTitle records the window title.
+func (c *Controller) SetTitle(title string) {
+	c.Title = title
 }
 
 // ActivateClient sets the Controller to the client view.
@@ -38,3 +75,16 @@ func (c *Controller) ActivateChannel(network, channel string) {
 	c.V = ChannelView
 	c.Network, c.Channel = network, channel
 }
+
+// ActivateEvent sets the Controller to the channel view, at the Event.
+func (c *Controller) ActivateEvent(id data.EventID) {
+	c.ActivateChannel(id.Net, id.Name)
+	c.Seq = id.Seq
+}
+
+// ActivateFilter sets the Controller to the filter view.
+func (c *Controller) ActivateFilter(query string) {
+	c.V = FilterView
+	c.Network, c.Channel = "", ""
+	c.Query = query
+}
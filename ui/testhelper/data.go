@@ -1,7 +1,13 @@
 package testhelper
 
 import (
+	"fmt"
+	"sort"
+
 	"github.com/cceckman/discoirc/backend"
+	"github.com/cceckman/discoirc/backend/ignore"
+	"github.com/cceckman/discoirc/backend/outbox"
+	"github.com/cceckman/discoirc/config"
 	"github.com/cceckman/discoirc/data"
 )
 
@@ -50,6 +56,35 @@ type Backend struct {
 	events data.EventList
 
 	Sent []string
+	// Outbox are the messages waiting to be sent to each scope, returned by
+	// Pending.
+	Outbox map[data.Scope][]outbox.Message
+
+	// Managed records calls to the backend.Manager methods.
+	Managed []string
+	// Configs are returned by Configuration.
+	Configs map[string]*config.Network
+	// Known are the networks, and their channels, returned by Networks and
+	// Channels.
+	Known map[string][]string
+	// Nicks are the members of each channel, returned by Members.
+	Nicks map[data.Scope][]string
+
+	// DND is set by SetDoNotDisturb, and returned by DoNotDisturb.
+	DND bool
+
+	// Searches records the Filters passed to Search, as text; it returns
+	// the Events with the IDs in Found.
+	Searches []string
+	Found    []data.EventID
+
+	// Rules are the rules added by Ignore, and removed by Unignore; Ignores
+	// returns those in the scope.
+	Rules []*ignore.Rule
+
+	// Saved counts calls to Save, which returns SaveErr.
+	Saved   int
+	SaveErr error
 }
 
 // Subscribe implements backend.Backend
@@ -62,11 +97,203 @@ func (b *Backend) EventsBefore(s data.Scope, n int, last data.Seq) data.EventLis
 	return b.events.SelectSizeMax(n, last)
 }
 
+// SetEvents replaces the Events returned by EventsBefore, which are Events by
+// default.
+func (b *Backend) SetEvents(events data.EventList) {
+	b.events = events
+}
+
 // Send implements backend.Backend
 func (b *Backend) Send(_ data.Scope, message string) {
 	b.Sent = append(b.Sent, message)
 }
 
+// Pending implements backend.Backend
+func (b *Backend) Pending(s data.Scope) []outbox.Message {
+	return b.Outbox[s]
+}
+
+// Cancel implements backend.Backend
+func (b *Backend) Cancel(s data.Scope, id int64) bool {
+	for i, m := range b.Outbox[s] {
+		if m.ID == id {
+			b.Outbox[s] = append(b.Outbox[s][:i:i], b.Outbox[s][i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// SetDoNotDisturb implements backend.Backend
+func (b *Backend) SetDoNotDisturb(on bool) {
+	b.DND = on
+}
+
+// DoNotDisturb implements backend.Backend
+func (b *Backend) DoNotDisturb() bool {
+	return b.DND
+}
+
+// Search implements backend.Backend
+func (b *Backend) Search(f data.Filter, limit int) data.EventList {
+	b.Searches = append(b.Searches, f.String())
+	var events data.EventList
+	for _, id := range b.Found {
+		if limit > 0 && len(events) == limit {
+			break
+		}
+		for _, e := range b.events {
+			if *e.ID() == id {
+				events = append(events, e)
+				break
+			}
+		}
+	}
+	return events
+}
+
+// MessagesAfter implements backend.Backend
+func (b *Backend) MessagesAfter(s data.Scope, after data.Seq, f data.Filter) data.EventList {
+	var events data.EventList
+	for _, e := range b.events {
+		if id := e.ID(); id.Scope == s && id.Seq > after && f.MatchEvent(e) {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Configure implements backend.Backend
+func (b *Backend) Configure(n *config.Network) {
+	b.Managed = append(b.Managed, fmt.Sprintf("configure %s", n.Name))
+}
+
+// Configuration implements backend.Backend
+func (b *Backend) Configuration(network string) *config.Network {
+	return b.Configs[network]
+}
+
+// Connect implements backend.Backend
+func (b *Backend) Connect(network string) {
+	b.Managed = append(b.Managed, fmt.Sprintf("connect %s", network))
+}
+
+// Disconnect implements backend.Backend
+func (b *Backend) Disconnect(network string) {
+	b.Managed = append(b.Managed, fmt.Sprintf("disconnect %s", network))
+}
+
+// Remove implements backend.Backend
+func (b *Backend) Remove(network string) {
+	b.Managed = append(b.Managed, fmt.Sprintf("remove %s", network))
+	delete(b.Known, network)
+}
+
+// SetNick implements backend.Backend
+func (b *Backend) SetNick(network, nick string) {
+	b.Managed = append(b.Managed, fmt.Sprintf("nick %s %s", network, nick))
+}
+
+// Join implements backend.Backend
+func (b *Backend) Join(s data.Scope, key string) {
+	b.Managed = append(b.Managed, fmt.Sprintf("join %s %s %s", s.Net, s.Name, key))
+}
+
+// Part implements backend.Backend
+func (b *Backend) Part(s data.Scope) {
+	b.Managed = append(b.Managed, fmt.Sprintf("part %s %s", s.Net, s.Name))
+}
+
+// RemoveChannel implements backend.Backend
+func (b *Backend) RemoveChannel(s data.Scope) {
+	b.Managed = append(b.Managed, fmt.Sprintf("remove %s %s", s.Net, s.Name))
+	for i, name := range b.Known[s.Net] {
+		if name == s.Name {
+			b.Known[s.Net] = append(b.Known[s.Net][:i:i], b.Known[s.Net][i+1:]...)
+			break
+		}
+	}
+}
+
+// Update sends the state of each Known network and channel to the Receiver,
+// as the backend does after a change.
+func (b *Backend) Update() {
+	for _, n := range b.Networks() {
+		b.Receiver.Receive(&data.NetworkStateEvent{
+			EventID: data.EventID{Scope: data.Scope{Net: n}},
+		})
+		for _, name := range b.Known[n] {
+			b.Receiver.Receive(&data.ChannelStateEvent{
+				EventID: data.EventID{Scope: data.Scope{Net: n, Name: name}},
+			})
+		}
+	}
+}
+
+// SetHighlights implements backend.Backend
+func (b *Backend) SetHighlights(rules []*config.Highlight) {
+	b.Managed = append(b.Managed, fmt.Sprintf("highlights %d", len(rules)))
+}
+
+// SetIgnores implements backend.Backend
+func (b *Backend) SetIgnores(rules []*config.Ignore) {
+	b.Managed = append(b.Managed, fmt.Sprintf("ignores %d", len(rules)))
+}
+
+// Ignore implements backend.Backend
+func (b *Backend) Ignore(r *ignore.Rule) {
+	b.Unignore(r.Scope, r.Target())
+	b.Rules = append(b.Rules, r)
+}
+
+// Unignore implements backend.Backend
+func (b *Backend) Unignore(s data.Scope, target string) bool {
+	for i, r := range b.Rules {
+		if r.Scope == s && r.Target() == target {
+			b.Rules = append(b.Rules[:i:i], b.Rules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Ignores implements backend.Backend
+func (b *Backend) Ignores(s data.Scope) []*ignore.Rule {
+	var r []*ignore.Rule
+	for _, rule := range b.Rules {
+		if rule.Scope.Net == "" || rule.Scope == s || (rule.Scope.Name == "" && rule.Scope.Net == s.Net) {
+			r = append(r, rule)
+		}
+	}
+	return r
+}
+
+// Save implements backend.Backend
+func (b *Backend) Save() error {
+	b.Saved++
+	return b.SaveErr
+}
+
+// Networks implements backend.Backend
+func (b *Backend) Networks() []string {
+	var r []string
+	for n := range b.Known {
+		r = append(r, n)
+	}
+	sort.Strings(r)
+	return r
+}
+
+// Channels implements backend.Backend
+func (b *Backend) Channels(network string) []string {
+	return b.Known[network]
+}
+
+// Members implements backend.Backend
+func (b *Backend) Members(s data.Scope) []string {
+	return b.Nicks[s]
+}
+
 // NewBackend returns a new, mock, Backend
 func NewBackend() *Backend {
 	return &Backend{
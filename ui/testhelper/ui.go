@@ -13,18 +13,24 @@ func NewUI() *UI {
 }
 
 // Type produces an appropriate keypress against its root for each character in the input.
+// '\n' and '\t' press Enter and Tab.
 // Each is a separate event in the UI thread.
 func (ui *UI) Type(s string) {
 	for _, rn := range s {
 		var ev tui.KeyEvent
-		if rn != '\n' {
+		switch rn {
+		case '\n':
 			ev = tui.KeyEvent{
-				Key:  tui.KeyRune,
-				Rune: rn,
+				Key: tui.KeyEnter,
 			}
-		} else {
+		case '\t':
 			ev = tui.KeyEvent{
-				Key: tui.KeyEnter,
+				Key: tui.KeyTab,
+			}
+		default:
+			ev = tui.KeyEvent{
+				Key:  tui.KeyRune,
+				Rune: rn,
 			}
 		}
 		if ui.Root == nil {
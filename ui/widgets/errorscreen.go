@@ -0,0 +1,42 @@
+package widgets
+
+import (
+	"github.com/marcusolsson/tui-go"
+)
+
+type errorScreen struct {
+	tui.Widget
+
+	ui   Quitter
+	next func()
+}
+
+// OnKeyEvent handles keypress events.
+func (s *errorScreen) OnKeyEvent(ev tui.KeyEvent) {
+	switch ev.Key {
+	case tui.KeyCtrlC:
+		s.ui.Quit()
+	case tui.KeyEnter:
+		if s.next != nil {
+			s.next()
+		}
+	}
+}
+
+// NewErrorScreen returns a full-screen widget showing the error message.
+// Enter continues by calling next; Ctrl+C quits.
+func NewErrorScreen(ui Quitter, msg string, next func()) tui.Widget {
+	label := tui.NewLabel(msg)
+	label.SetWordWrap(true)
+	return &errorScreen{
+		ui:   ui,
+		next: next,
+		Widget: tui.NewVBox(
+			tui.NewSpacer(),
+			label,
+			tui.NewLabel(""),
+			tui.NewLabel("Press Enter to continue, or Ctrl+C to quit."),
+			tui.NewSpacer(),
+		),
+	}
+}
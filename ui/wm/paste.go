@@ -0,0 +1,16 @@
+package wm
+
+import (
+	"fmt"
+	"io"
+)
+
+// EnablePaste turns on the terminal's bracketed paste mode, in which it marks
+// the start and end of pasted text, so it can be told apart from typing.
+// The returned function turns it off again.
+func EnablePaste(w io.Writer) (disable func()) {
+	fmt.Fprint(w, "\x1b[?2004h")
+	return func() {
+		fmt.Fprint(w, "\x1b[?2004l")
+	}
+}
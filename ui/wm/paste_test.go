@@ -0,0 +1,22 @@
+package wm_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cceckman/discoirc/ui/wm"
+)
+
+func TestEnablePaste(t *testing.T) {
+	t.Parallel()
+	var b bytes.Buffer
+	disable := wm.EnablePaste(&b)
+	if got, want := b.String(), "\x1b[?2004h"; got != want {
+		t.Errorf("unexpected output: got: %q want: %q", got, want)
+	}
+	b.Reset()
+	disable()
+	if got, want := b.String(), "\x1b[?2004l"; got != want {
+		t.Errorf("unexpected output when disabled: got: %q want: %q", got, want)
+	}
+}
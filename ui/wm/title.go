@@ -0,0 +1,96 @@
+package wm
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Titler sets the title of the terminal window, and the name of the tmux or
+// screen window, using terminal escape sequences.
+type Titler struct {
+	w io.Writer
+	// xterm indicates the terminal understands xterm's OSC title sequences.
+	xterm bool
+	// wm indicates the terminal is a tmux or screen window, which has a name.
+	wm bool
+	// original is the name of the tmux or screen window before it was
+	// changed.
+	original string
+	changed  bool
+}
+
+// NewTitler returns a Titler that writes escape sequences to w, suited to the
+// terminal indicated by the environment. It uses run to find the current
+// tmux or screen window name, so it can be restored later.
+func NewTitler(getenv func(string) string, w io.Writer, run Runner) *Titler {
+	t := &Titler{w: w}
+	term := getenv("TERM")
+	for _, prefix := range []string{"xterm", "rxvt", "alacritty", "kitty", "foot", "st-", "gnome", "konsole", "vte"} {
+		t.xterm = t.xterm || strings.HasPrefix(term, prefix)
+	}
+	switch {
+	case getenv("TMUX") != "":
+		// tmux passes OSC titles on as the pane title.
+		t.xterm = true
+		t.wm = true
+		t.original = query(run, "tmux", "display-message", "-p", "#{window_name}")
+	case getenv("STY") != "":
+		t.wm = true
+		t.original = query(run, "screen", "-S", getenv("STY"), "-Q", "title")
+	case strings.HasPrefix(term, "screen") || strings.HasPrefix(term, "tmux"):
+		t.wm = true
+	}
+	return t
+}
+
+// query returns the trimmed output of the command, or "" if it fails.
+func query(run Runner, name string, args ...string) string {
+	out, err := run(name, args...)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// SetTitle sets the window title.
+func (t *Titler) SetTitle(title string) {
+	title = sanitize(title)
+	if t.xterm {
+		if !t.changed {
+			// Save the current title on xterm's title stack.
+			fmt.Fprint(t.w, "\x1b[22;0t")
+		}
+		fmt.Fprintf(t.w, "\x1b]2;%s\x07", title)
+	}
+	if t.wm {
+		fmt.Fprintf(t.w, "\x1bk%s\x1b\\", title)
+	}
+	t.changed = true
+}
+
+// Restore restores the title and window name from before the first call to
+// SetTitle.
+func (t *Titler) Restore() {
+	if !t.changed {
+		return
+	}
+	if t.xterm {
+		fmt.Fprint(t.w, "\x1b[23;0t")
+	}
+	if t.wm && t.original != "" {
+		fmt.Fprintf(t.w, "\x1bk%s\x1b\\", sanitize(t.original))
+	}
+	t.changed = false
+}
+
+// sanitize removes control characters, which could end the escape sequence
+// early.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+}
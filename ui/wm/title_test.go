@@ -0,0 +1,74 @@
+package wm_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cceckman/discoirc/ui/wm"
+)
+
+var titleTests = []struct {
+	test        string
+	env         map[string]string
+	output      map[string]string
+	want        string
+	wantRestore string
+}{
+	{
+		test:        "xterm",
+		env:         map[string]string{"TERM": "xterm-256color"},
+		want:        "\x1b[22;0t\x1b]2;Barnetic #discoirc (3)\x07\x1b]2;discoirc\x07",
+		wantRestore: "\x1b[23;0t",
+	},
+	{
+		test: "tmux",
+		env:  map[string]string{"TERM": "screen", "TMUX": "/tmp/tmux-1000/default,1,0"},
+		output: map[string]string{
+			"tmux display-message -p #{window_name}": "zsh\n",
+		},
+		want: "\x1b[22;0t\x1b]2;Barnetic #discoirc (3)\x07\x1bkBarnetic #discoirc (3)\x1b\\" +
+			"\x1b]2;discoirc\x07\x1bkdiscoirc\x1b\\",
+		wantRestore: "\x1b[23;0t\x1bkzsh\x1b\\",
+	},
+	{
+		test: "screen",
+		env:  map[string]string{"TERM": "screen.xterm-256color", "STY": "1234.pts-0.elsinore"},
+		output: map[string]string{
+			"screen -S 1234.pts-0.elsinore -Q title": "bash",
+		},
+		want:        "\x1bkBarnetic #discoirc (3)\x1b\\\x1bkdiscoirc\x1b\\",
+		wantRestore: "\x1bkbash\x1b\\",
+	},
+	{
+		test: "unknown terminal",
+		env:  map[string]string{"TERM": "dumb"},
+	},
+}
+
+func TestTitler(t *testing.T) {
+	for _, tt := range titleTests {
+		tt := tt
+		t.Run(tt.test, func(t *testing.T) {
+			t.Parallel()
+			r := &fakeRunner{output: tt.output}
+			var b bytes.Buffer
+			title := wm.NewTitler(func(k string) string { return tt.env[k] }, &b, r.run)
+
+			// Control characters are dropped.
+			title.SetTitle("Barnetic #discoirc\a (3)")
+			title.SetTitle("discoirc")
+			if diff := cmp.Diff(b.String(), tt.want); diff != "" {
+				t.Errorf("unexpected output: (-got +want)\n%s", diff)
+			}
+
+			b.Reset()
+			title.Restore()
+			title.Restore()
+			if diff := cmp.Diff(b.String(), tt.wantRestore); diff != "" {
+				t.Errorf("unexpected output on restore: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+// Package wm opens discoirc views in new windows of a terminal window manager,
+// i.e. tmux or screen, rather than replacing the current view.
+package wm
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Runner runs a command and returns its combined output.
+type Runner func(name string, args ...string) ([]byte, error)
+
+// Exec is a Runner that runs commands with os/exec.
+func Exec(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).CombinedOutput()
+}
+
+// Launcher opens channel views in their own windows.
+type Launcher interface {
+	// Open shows the view of the channel: it selects the window already
+	// showing the channel, if any, or opens a new one.
+	Open(network, channel string) error
+}
+
+// Detect returns a Launcher for the terminal window manager discoirc is
+// running in, as indicated by the environment, or nil if there is none.
+// command is the discoirc command line to run in new windows, without the
+// -network and -channel flags.
+func Detect(getenv func(string) string, command []string, run Runner) Launcher {
+	switch {
+	case getenv("TMUX") != "":
+		return &Tmux{Command: command, Run: run}
+	case getenv("STY") != "":
+		return &Screen{Session: getenv("STY"), Command: command, Run: run}
+	}
+	return nil
+}
+
+// Tmux opens views in windows of the current tmux session.
+type Tmux struct {
+	Command []string
+	Run     Runner
+}
+
+// Open implements Launcher.
+func (t *Tmux) Open(network, channel string) error {
+	id, err := t.window(network, channel)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		return t.run("select-window", "-t", id)
+	}
+	// tmux runs the window's command with the shell.
+	return t.run("new-window", "-n", WindowName(network, channel), shellJoin(command(t.Command, network, channel)))
+}
+
+// Alert flags the window showing the channel, or the current window if none
+// does, by ringing the bell in its pane; tmux marks windows that ring the bell
+// in the status line.
+func (t *Tmux) Alert(network, channel string) error {
+	id, err := t.window(network, channel)
+	if err != nil {
+		return err
+	}
+	args := []string{"run-shell"}
+	if id != "" {
+		args = append(args, "-t", id)
+	}
+	// run-shell expands the pane's tty before running the command.
+	return t.run(append(args, `printf '\a' > '#{pane_tty}'`)...)
+}
+
+// window returns the ID of the window showing the channel, or "" if there is
+// none.
+func (t *Tmux) window(network, channel string) (string, error) {
+	name := WindowName(network, channel)
+	out, err := t.Run("tmux", "list-windows", "-F", "#{window_id} #{window_name}")
+	if err != nil {
+		return "", fmt.Errorf("tmux list-windows: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		f := strings.SplitN(line, " ", 2)
+		if len(f) == 2 && isWindow(f[1], name) {
+			return f[0], nil
+		}
+	}
+	return "", nil
+}
+
+func (t *Tmux) run(args ...string) error {
+	if out, err := t.Run("tmux", args...); err != nil {
+		return fmt.Errorf("tmux %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Screen opens views in windows of a screen session.
+type Screen struct {
+	// Session is the name of the session, as in $STY.
+	Session string
+	Command []string
+	Run     Runner
+}
+
+// Open implements Launcher.
+func (s *Screen) Open(network, channel string) error {
+	name := WindowName(network, channel)
+	out, err := s.Run("screen", "-S", s.Session, "-Q", "windows")
+	if err != nil {
+		return fmt.Errorf("screen windows: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	for _, title := range screenTitles(string(out)) {
+		if isWindow(title, name) {
+			return s.run("select", title)
+		}
+	}
+	args := append([]string{"screen", "-t", name}, command(s.Command, network, channel)...)
+	return s.run(args...)
+}
+
+func (s *Screen) run(args ...string) error {
+	args = append([]string{"-S", s.Session, "-X"}, args...)
+	if out, err := s.Run("screen", args...); err != nil {
+		return fmt.Errorf("screen %s: %v: %s", args[3], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// screenTitles parses the titles from the output of screen's "windows"
+// command, e.g. "0 bash  1*$ HamNet #hamlet (3)".
+func screenTitles(out string) []string {
+	var r []string
+	for _, w := range strings.Split(strings.TrimSpace(out), "  ") {
+		if f := strings.SplitN(strings.TrimSpace(w), " ", 2); len(f) == 2 {
+			r = append(r, f[1])
+		}
+	}
+	return r
+}
+
+// WindowName is the name of a new window showing the channel.
+// Once open, the channel view keeps the name up to date with its title, which
+// may add an unread count, e.g. "Barnetic #discoirc (3)".
+func WindowName(network, channel string) string {
+	return network + " " + channel
+}
+
+// isWindow reports whether the window title is that of the window named name.
+func isWindow(title, name string) bool {
+	return title == name || strings.HasPrefix(title, name+" (")
+}
+
+func command(base []string, network, channel string) []string {
+	r := append([]string(nil), base...)
+	return append(r, "-network", network, "-channel", channel)
+}
+
+// shellJoin quotes the arguments for the shell.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = "'" + strings.Replace(a, "'", `'\''`, -1) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,167 @@
+package wm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cceckman/discoirc/ui/wm"
+)
+
+// fakeRunner records commands, and returns canned output for them.
+type fakeRunner struct {
+	// output is returned for commands starting with the key.
+	output map[string]string
+	// fail causes commands starting with the key to fail.
+	fail string
+	ran  []string
+}
+
+func (f *fakeRunner) run(name string, args ...string) ([]byte, error) {
+	cmd := strings.Join(append([]string{name}, args...), " ")
+	f.ran = append(f.ran, cmd)
+	if f.fail != "" && strings.HasPrefix(cmd, f.fail) {
+		return []byte("no server running"), errors.New("exit status 1")
+	}
+	for k, v := range f.output {
+		if strings.HasPrefix(cmd, k) {
+			return []byte(v), nil
+		}
+	}
+	return nil, nil
+}
+
+var command = []string{"/usr/bin/discoirc", "-config", "/home/yorick/discoirc"}
+
+var launchTests = []struct {
+	test    string
+	env     map[string]string
+	output  map[string]string
+	fail    string
+	want    []string
+	wantErr string
+}{
+	{
+		test: "tmux new window",
+		env:  map[string]string{"TMUX": "/tmp/tmux-1000/default,1,0"},
+		output: map[string]string{
+			"tmux list-windows": "@0 bash\n@1 HamNet #battlements\n@2 HamNet #hamlets (3)\n",
+		},
+		want: []string{
+			"tmux list-windows -F #{window_id} #{window_name}",
+			`tmux new-window -n HamNet #hamlet '/usr/bin/discoirc' '-config' '/home/yorick/discoirc' '-network' 'HamNet' '-channel' '#hamlet'`,
+		},
+	},
+	{
+		test: "tmux existing window",
+		env:  map[string]string{"TMUX": "/tmp/tmux-1000/default,1,0"},
+		output: map[string]string{
+			"tmux list-windows": "@0 bash\n@3 HamNet #hamlet (3)\n",
+		},
+		want: []string{
+			"tmux list-windows -F #{window_id} #{window_name}",
+			"tmux select-window -t @3",
+		},
+	},
+	{
+		test:    "tmux failure",
+		env:     map[string]string{"TMUX": "/tmp/tmux-1000/default,1,0"},
+		fail:    "tmux list-windows",
+		want:    []string{"tmux list-windows -F #{window_id} #{window_name}"},
+		wantErr: "tmux list-windows: exit status 1: no server running",
+	},
+	{
+		test: "screen new window",
+		env:  map[string]string{"STY": "1234.pts-0.elsinore"},
+		output: map[string]string{
+			"screen -S 1234.pts-0.elsinore -Q windows": "0 bash  1*$ HamNet #hamlets",
+		},
+		want: []string{
+			"screen -S 1234.pts-0.elsinore -Q windows",
+			"screen -S 1234.pts-0.elsinore -X screen -t HamNet #hamlet /usr/bin/discoirc -config /home/yorick/discoirc -network HamNet -channel #hamlet",
+		},
+	},
+	{
+		test: "screen existing window",
+		env:  map[string]string{"STY": "1234.pts-0.elsinore"},
+		output: map[string]string{
+			"screen -S 1234.pts-0.elsinore -Q windows": "0 bash  1*$ HamNet #hamlet (3)  2-$ HamNet #battlements",
+		},
+		want: []string{
+			"screen -S 1234.pts-0.elsinore -Q windows",
+			"screen -S 1234.pts-0.elsinore -X select HamNet #hamlet (3)",
+		},
+	},
+}
+
+func TestLaunch(t *testing.T) {
+	for _, tt := range launchTests {
+		tt := tt
+		t.Run(tt.test, func(t *testing.T) {
+			t.Parallel()
+			r := &fakeRunner{output: tt.output, fail: tt.fail}
+			l := wm.Detect(func(k string) string { return tt.env[k] }, command, r.run)
+			if l == nil {
+				t.Fatalf("no launcher detected for %v", tt.env)
+			}
+
+			err := l.Open("HamNet", "#hamlet")
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+				t.Errorf("unexpected error: got: %v want: %s", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(r.ran, tt.want); diff != "" {
+				t.Errorf("unexpected commands: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDetect_None(t *testing.T) {
+	t.Parallel()
+	if l := wm.Detect(func(string) string { return "" }, command, wm.Exec); l != nil {
+		t.Errorf("unexpected launcher: got: %T want: nil", l)
+	}
+}
+
+func TestTmuxAlert(t *testing.T) {
+	for _, tt := range []struct {
+		test    string
+		windows string
+		want    []string
+	}{
+		{
+			test:    "channel window",
+			windows: "@0 bash\n@3 HamNet #hamlet (3)\n",
+			want: []string{
+				"tmux list-windows -F #{window_id} #{window_name}",
+				`tmux run-shell -t @3 printf '\a' > '#{pane_tty}'`,
+			},
+		},
+		{
+			test:    "current window",
+			windows: "@0 bash\n",
+			want: []string{
+				"tmux list-windows -F #{window_id} #{window_name}",
+				`tmux run-shell printf '\a' > '#{pane_tty}'`,
+			},
+		},
+	} {
+		tt := tt
+		t.Run(tt.test, func(t *testing.T) {
+			t.Parallel()
+			r := &fakeRunner{output: map[string]string{"tmux list-windows": tt.windows}}
+			tmux := &wm.Tmux{Command: command, Run: r.run}
+			if err := tmux.Alert("HamNet", "#hamlet"); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(r.ran, tt.want); diff != "" {
+				t.Errorf("unexpected commands: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}